@@ -2,80 +2,70 @@ package main
 
 import (
 	"context"
-	"database/sql"
-	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
-	"strings"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
-	"github.com/pressly/goose/v3"
-
-	_ "github.com/lib/pq"
-
+	"github.com/sshlykov/shortener/internal/bootstrap/migrator"
 	"github.com/sshlykov/shortener/internal/config"
-	_ "github.com/sshlykov/shortener/migrations"
-	"github.com/sshlykov/shortener/pkg/backoff"
 )
 
 const (
-	timeout = 30 * time.Second
-)
-
-var (
-	ErrCantStartApplication = errors.New("application not started")
-	ErrTimeoutExceeded      = errors.New("error time exceeded")
-	ErrCantPingDatabase     = errors.New("database not available")
+	defaultTimeout = 30 * time.Second
 )
 
 func main() {
-	data, err := os.ReadFile("./MIGRATION")
-	if err != nil {
-		log.Fatalf("Error reading MIGRATION file: %v\n", err)
+	enabled := flag.Bool("enabled", envBool("MIGRATION_ENABLED", true),
+		"run migrations, false exits without touching the database (env MIGRATION_ENABLED)")
+	timeout := flag.Duration("timeout", envDuration("MIGRATION_TIMEOUT", defaultTimeout),
+		"how long to wait for the database (env MIGRATION_TIMEOUT)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] <command> [args]\n\n%s\n\nflags:\n",
+			os.Args[0], migrator.Usage)
+		flag.PrintDefaults()
 	}
-	if strings.TrimSpace(string(data)) != "true" {
-		log.Printf("MIGRATION file does not contain true; content: %s\n", string(data))
+	flag.Parse()
+
+	if !*enabled {
+		log.Println("migrations disabled, skipping")
 		return
 	}
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
 	dbDSN, err := config.GetDSN()
 	if err != nil {
 		log.Fatalf("Error getting database DSN: %v\n", err)
 	}
 
-	fmt.Println(dbDSN)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	var db *sql.DB
-	startTime := time.Now()
-	h := func() error {
-		if time.Since(startTime) > timeout {
-			return backoff.Permanent(ErrTimeoutExceeded)
-		}
-		db, err = sql.Open("postgres", dbDSN)
-		if err != nil {
-			fmt.Println(err)
-			return ErrCantStartApplication
-		}
-		if perr := db.Ping(); perr != nil {
-			return ErrCantPingDatabase
-		}
-		return backoff.Permanent(nil)
-	}
-	if err = backoff.Retry(h, backoff.NewExponentialBackOff()); err != nil {
-		log.Fatalf("failed to open db connection: %v\n", err)
+	if err = migrator.Run(ctx, dbDSN, *timeout, flag.Args(), os.Stdout); err != nil {
+		stop()
+		log.Fatalf("migrator: %v\n", err)
 	}
+}
 
-	defer func() {
-		if err := db.Close(); err != nil {
-			log.Fatalf("failed to close db connection: %v\n", err)
-		}
-	}()
-	if len(os.Args) < 2 {
-		log.Fatalf("usage: use goose command like 'go run ... command' \n")
+func envBool(key string, def bool) bool {
+	v, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return def
 	}
+	return v
+}
 
-	cmd := os.Args[1]
-	if err = goose.RunContext(context.Background(), cmd, db, "./migrations"); err != nil {
-		log.Fatalf("failed to run goose command: %v\n", err)
+func envDuration(key string, def time.Duration) time.Duration {
+	v, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return def
 	}
+	return v
 }
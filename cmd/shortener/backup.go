@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sshlykov/shortener/internal/bootstrap/app"
+	"github.com/sshlykov/shortener/internal/bootstrap/registry"
+	"github.com/sshlykov/shortener/internal/config"
+	backupsrv "github.com/sshlykov/shortener/internal/pkg/backup/service"
+)
+
+// backup пишет архив ссылок и подписок на вебхуки: shortener backup [-o file]
+func backup(ctx context.Context, cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	out := fs.String("o", "", "archive path, shortener-<timestamp>.tar.gz by default")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s backup [flags]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		fs.Usage()
+		return ErrBackup
+	}
+	path := *out
+	if path == "" {
+		path = fmt.Sprintf("shortener-%s.tar.gz", time.Now().UTC().Format("20060102T150405Z"))
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	application, err := app.New(ctx, cfg)
+	if err != nil {
+		log.Printf("failed to create app: %s\n", err.Error())
+		return ErrCreateApp
+	}
+
+	var manifest *backupsrv.Manifest
+	err = application.Exec(func(ctx context.Context, services *registry.Services) error {
+		manifest, err = services.Backup.Backup(ctx, path)
+		return err
+	})
+	if err != nil {
+		log.Printf("backup: %s\n", err.Error())
+		return ErrBackup
+	}
+
+	printJSON(manifest)
+	log.Printf("backup written to %s\n", path)
+
+	return OkCode
+}
+
+// restore восстанавливает архив: shortener restore [-apply] [-on-conflict policy] file.
+// Без -apply выполняется пробный прогон. В пустой базе схема должна быть создана заранее: shortener migrate up
+func restore(ctx context.Context, cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	apply := fs.Bool("apply", false, "commit the restore, without it the archive is checked by a dry run")
+	onConflict := fs.String("on-conflict", string(backupsrv.ConflictFail),
+		"what to do with a link whose key is taken by another link: fail, skip, generate or suffix")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s restore [flags] <file>\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		fs.Usage()
+		return ErrRestore
+	}
+
+	policy, err := backupsrv.ParseConflictPolicy(*onConflict)
+	if err != nil {
+		log.Printf("restore: %s\n", err.Error())
+		return ErrRestore
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	application, err := app.New(ctx, cfg)
+	if err != nil {
+		log.Printf("failed to create app: %s\n", err.Error())
+		return ErrCreateApp
+	}
+
+	var report *backupsrv.RestoreReport
+	err = application.Exec(func(ctx context.Context, services *registry.Services) error {
+		report, err = services.Backup.Restore(ctx, fs.Arg(0), backupsrv.RestoreOptions{Apply: *apply, OnConflict: policy})
+		return err
+	})
+	if report != nil {
+		printJSON(report)
+	}
+	if err != nil {
+		log.Printf("restore: %s\n", err.Error())
+		return ErrRestore
+	}
+
+	return OkCode
+}
+
+func printJSON(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/sshlykov/shortener/internal/bootstrap/app"
+	"github.com/sshlykov/shortener/internal/bootstrap/migrator"
 	"github.com/sshlykov/shortener/internal/config"
 )
 
@@ -16,11 +19,25 @@ const (
 	ErrConfigLoad
 	ErrCreateApp
 	ErrRunApp
+	ErrMigrate
+	ErrSeed
+	ErrBackup
+	ErrRestore
+	ErrSnapshot
+	ErrUsage
 )
 
 func main() {
 	var configPath string
 	flag.StringVar(&configPath, "config", "./config", "path to configuration file")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"usage: %s [flags] [migrate <command> [args] | seed [flags] <file> | backup [flags] | restore [flags] <file> | snapshot [flags]]\n\n"+
+				"migrate %s\n\nflags:\n",
+			os.Args[0], migrator.Usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
 
 	ctx := context.Background()
 
@@ -30,6 +47,26 @@ func main() {
 		os.Exit(ErrConfigLoad)
 	}
 
+	switch flag.Arg(0) {
+	case "migrate":
+		os.Exit(migrate(ctx, cfg, flag.Args()[1:]))
+	case "seed":
+		os.Exit(seed(ctx, cfg, flag.Args()[1:]))
+	case "backup":
+		os.Exit(backup(ctx, cfg, flag.Args()[1:]))
+	case "restore":
+		os.Exit(restore(ctx, cfg, flag.Args()[1:]))
+	case "snapshot":
+		os.Exit(snapshot(ctx, cfg, flag.Args()[1:]))
+	default:
+		// опечатка в команде не должна запускать сервер
+		if flag.NArg() > 0 {
+			fmt.Fprintf(flag.CommandLine.Output(), "unknown command %q\n\n", flag.Arg(0))
+			flag.Usage()
+			os.Exit(ErrUsage)
+		}
+	}
+
 	application, err := app.New(ctx, cfg)
 	if err != nil {
 		fmt.Printf("failed to create app: %s\n", err.Error())
@@ -43,3 +80,22 @@ func main() {
 
 	os.Exit(OkCode)
 }
+
+// migrate выполняет команду мигратора теми же вшитыми миграциями, что и cmd/migrator
+func migrate(ctx context.Context, cfg *config.Config, args []string) int {
+	dsn, err := config.GetDSN()
+	if err != nil {
+		log.Printf("failed to get database DSN: %s\n", err.Error())
+		return ErrMigrate
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err = migrator.Run(ctx, dsn, cfg.DB.RefreshTimeout, args, os.Stdout); err != nil {
+		log.Printf("migrate: %s\n", err.Error())
+		return ErrMigrate
+	}
+
+	return OkCode
+}
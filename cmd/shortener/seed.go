@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/sshlykov/shortener/internal/bootstrap/app"
+	"github.com/sshlykov/shortener/internal/bootstrap/registry"
+	"github.com/sshlykov/shortener/internal/config"
+	seedsrv "github.com/sshlykov/shortener/internal/pkg/seed/service"
+)
+
+// seed загружает ссылки из CSV или JSONL: shortener seed [-format csv|jsonl] [-map url=column,...] file
+func seed(ctx context.Context, cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	format := fs.String("format", "", "csv or jsonl, by default taken from the file extension")
+	mapping := fs.String("map", "", "field=column pairs for key, url, owner and expires_at, e.g. url=long_url,key=slug")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s seed [flags] <file>\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		fs.Usage()
+		return ErrSeed
+	}
+	path := fs.Arg(0)
+
+	f, err := seedsrv.ParseFormat(*format, path)
+	if err != nil {
+		log.Printf("seed: %s\n", err.Error())
+		return ErrSeed
+	}
+	m, err := seedsrv.ParseMapping(*mapping)
+	if err != nil {
+		log.Printf("seed: %s\n", err.Error())
+		return ErrSeed
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Printf("seed: %s\n", err.Error())
+		return ErrSeed
+	}
+	defer file.Close()
+
+	rows, err := seedsrv.NewReader(file, f, m)
+	if err != nil {
+		log.Printf("seed: %s\n", err.Error())
+		return ErrSeed
+	}
+
+	application, err := app.New(ctx, cfg)
+	if err != nil {
+		log.Printf("failed to create app: %s\n", err.Error())
+		return ErrCreateApp
+	}
+
+	var report *seedsrv.Report
+	err = application.Exec(func(ctx context.Context, services *registry.Services) error {
+		report, err = seedsrv.New(services.Links).Load(ctx, rows)
+		return err
+	})
+	if report != nil {
+		printJSON(report)
+	}
+	if err != nil {
+		log.Printf("seed: %s\n", err.Error())
+		return ErrSeed
+	}
+	if report.Failed > 0 {
+		return ErrSeed
+	}
+
+	return OkCode
+}
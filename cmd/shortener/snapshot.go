@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/sshlykov/shortener/internal/bootstrap/app"
+	"github.com/sshlykov/shortener/internal/bootstrap/registry"
+	"github.com/sshlykov/shortener/internal/config"
+	snapsrv "github.com/sshlykov/shortener/internal/pkg/snapshot/service"
+)
+
+// snapshot выгружает действующие ссылки в файлы для nginx, Caddy и fallback: shortener snapshot [-dir dir]
+func snapshot(ctx context.Context, cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("snapshot", flag.ContinueOnError)
+	dir := fs.String("dir", cfg.Snapshot.Dir, "output directory")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s snapshot [flags]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		fs.Usage()
+		return ErrSnapshot
+	}
+	cfg.Snapshot.Dir = *dir
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	application, err := app.New(ctx, cfg)
+	if err != nil {
+		log.Printf("failed to create app: %s\n", err.Error())
+		return ErrCreateApp
+	}
+
+	var result *snapsrv.Result
+	err = application.Exec(func(ctx context.Context, services *registry.Services) error {
+		result, err = services.Snapshot.Generate(ctx)
+		return err
+	})
+	if err != nil {
+		log.Printf("snapshot: %s\n", err.Error())
+		return ErrSnapshot
+	}
+	printJSON(result)
+
+	return OkCode
+}
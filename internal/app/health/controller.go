@@ -1,6 +1,7 @@
 package health
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
@@ -8,40 +9,78 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/trace"
+
+	"github.com/sshlykov/shortener/internal/domain"
 )
 
+// Probes - состояние приложения для startup, liveness и readiness проб
+type Probes interface {
+	State() domain.LifecycleState
+	Liveness() domain.Liveness
+	Readiness() domain.Readiness
+}
+
 type Controller struct {
-	prom             *prometheus.Registry
-	tracer           trace.Tracer
-	readinessHandler func() bool
+	prom          *prometheus.Registry
+	tracer        trace.Tracer
+	probes        Probes
+	statusHandler func(ctx context.Context) map[string]any
 }
 
-func New(prom *prometheus.Registry, readinessHandler func() bool) *Controller {
+func New(prom *prometheus.Registry, probes Probes, statusHandler func(ctx context.Context) map[string]any) *Controller {
 	return &Controller{
-		prom:             prom,
-		readinessHandler: readinessHandler,
-		tracer:           otel.GetTracerProvider().Tracer("health_controller"),
+		prom:          prom,
+		probes:        probes,
+		statusHandler: statusHandler,
+		tracer:        otel.GetTracerProvider().Tracer("health_controller"),
 	}
 }
 
 func (c *Controller) RegisterRoutes(router *echo.Group) {
 	router.GET("/health", c.Health)
+	router.GET("/startup", c.Startup)
 	router.GET("/readiness", c.Readiness)
+	router.GET("/status", c.Status)
 	router.GET("/metrics", c.PrometheusHandler())
 
 }
 
-// Health - endpoint for health
-// //nolint:revive
+// Health - liveness: 503, если главный цикл приложения перестал отмечаться
 func (c *Controller) Health(ectx echo.Context) error {
-	return ectx.JSON(http.StatusOK, echo.Map{"status": "healthy"})
+	res := c.probes.Liveness()
+	if res.Error != "" {
+		return ectx.JSON(http.StatusServiceUnavailable, res)
+	}
+
+	return ectx.JSON(http.StatusOK, res)
+}
+
+// Startup - запуск завершен, после него проверяются liveness и readiness
+func (c *Controller) Startup(ectx echo.Context) error {
+	state := c.probes.State()
+	if state == domain.LifecycleStarting {
+		return ectx.JSON(http.StatusServiceUnavailable, echo.Map{"state": state})
+	}
+
+	return ectx.JSON(http.StatusOK, echo.Map{"state": state})
 }
+
+// Readiness - готовность принимать трафик и состояние каждой проверки. В degraded режиме под остается
+// в балансировке: редиректы он отдает, поэтому ответ 200, а причина - в поле error
 func (c *Controller) Readiness(ectx echo.Context) error {
-	if c.readinessHandler() {
-		return ectx.JSON(http.StatusOK, echo.Map{"status": "ready"})
+	res := c.probes.Readiness()
+	if res.Status != domain.ReadinessReady && res.Status != domain.ReadinessDegraded {
+		return ectx.JSON(http.StatusServiceUnavailable, res)
 	}
-	return ectx.JSON(http.StatusServiceUnavailable, echo.Map{"status": "not ready"})
+
+	return ectx.JSON(http.StatusOK, res)
 }
+
+// Status - состояние фоновых сервисов (партиции и т.п.)
+func (c *Controller) Status(ectx echo.Context) error {
+	return ectx.JSON(http.StatusOK, c.statusHandler(ectx.Request().Context()))
+}
+
 func (c *Controller) PrometheusHandler() echo.HandlerFunc {
 	if c.prom != nil {
 		h := promhttp.HandlerFor(c.prom, promhttp.HandlerOpts{
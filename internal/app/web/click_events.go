@@ -0,0 +1,103 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sshlykov/shortener/internal/app/web/dto"
+	"github.com/sshlykov/shortener/internal/pkg/clicks/stream"
+	"github.com/sshlykov/shortener/pkg/logger"
+)
+
+func (c *Controller) LinkEvents(ectx echo.Context) error {
+	link, err := c.svc.Get(ectx.Request().Context(), ectx.Param("key"))
+	if err != nil {
+		return linkError(ectx, err)
+	}
+
+	sub, backlog, err := c.svc.SubscribeLink(link.ID, dto.EjectLastEventID(ectx))
+	if err != nil {
+		return ectx.JSON(http.StatusServiceUnavailable, echo.Map{"error": "click stream is closed"})
+	}
+
+	return c.streamClicks(ectx, sub, backlog)
+}
+
+func (c *Controller) OwnerEvents(ectx echo.Context) error {
+	sub, backlog, err := c.svc.SubscribeOwner(ectx.Param("owner"), dto.EjectLastEventID(ectx))
+	if err != nil {
+		return ectx.JSON(http.StatusServiceUnavailable, echo.Map{"error": "click stream is closed"})
+	}
+
+	return c.streamClicks(ectx, sub, backlog)
+}
+
+// streamClicks пишет события в ответ как Server-Sent Events, пока клиент не отключится,
+// подписка не переполнится или приложение не начнет останавливаться
+func (c *Controller) streamClicks(ectx echo.Context, sub *stream.Subscription, backlog []stream.Event) error {
+	defer c.svc.Unsubscribe(sub)
+
+	ctx := ectx.Request().Context()
+	res := ectx.Response()
+	rc := http.NewResponseController(res)
+
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+
+	// WriteTimeout сервера рассчитан на обычные запросы, для потока продлеваем дедлайн на каждую запись
+	write := func(payload string) error {
+		if err := rc.SetWriteDeadline(time.Now().Add(2 * c.stream.Heartbeat)); err != nil {
+			return err
+		}
+		if _, err := res.Write([]byte(payload)); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+	writeEvent := func(event stream.Event) error {
+		data, err := json.Marshal(dto.NewClickEvent(event.Click))
+		if err != nil {
+			return err
+		}
+		return write(fmt.Sprintf("id: %d\nevent: click\ndata: %s\n\n", event.ID, data))
+	}
+
+	for _, event := range backlog {
+		if err := writeEvent(event); err != nil {
+			return nil
+		}
+	}
+
+	heartbeat := time.NewTicker(c.stream.Heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-sub.C:
+			if !ok {
+				if errors.Is(sub.Err(), stream.ErrSlowConsumer) {
+					logger.Warn(ctx, "click stream subscriber is too slow", logger.Err(sub.Err()))
+				}
+				return nil
+			}
+			err = writeEvent(event)
+		case <-heartbeat.C:
+			err = write(": heartbeat\n\n")
+		}
+		if err != nil {
+			// клиент отключился, ответ уже начат, поэтому ошибку наружу не отдаем
+			return nil
+		}
+	}
+}
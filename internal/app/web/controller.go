@@ -3,18 +3,54 @@ package health
 import (
 	"context"
 	"time"
+
+	"github.com/sshlykov/shortener/internal/config"
+	"github.com/sshlykov/shortener/internal/domain"
+	"github.com/sshlykov/shortener/internal/pkg/clicks/stream"
+	importsrv "github.com/sshlykov/shortener/internal/pkg/imports/service"
+	linksrv "github.com/sshlykov/shortener/internal/pkg/links/service"
 )
 
 type Service interface {
 	SelectNow(ctx context.Context) (*time.Time, error)
+
+	Resolve(ctx context.Context, key string) (*domain.Link, error)
+	Get(ctx context.Context, key string) (*domain.Link, error)
+	Create(ctx context.Context, link domain.Link) (*domain.Link, error)
+	CreateBatch(ctx context.Context, links []domain.Link) []linksrv.BatchResult
+	Update(ctx context.Context, key string, url *string, expiry linksrv.Expiry) (*domain.Link, error)
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context, filter linksrv.Filter) ([]*domain.Link, error)
+	Export(ctx context.Context, filter linksrv.ExportFilter, fn func(link *linksrv.ExportedLink) error) error
+
+	Record(ctx context.Context, click domain.Click)
+	SubscribeLink(linkID int32, lastEventID uint64) (*stream.Subscription, []stream.Event, error)
+	SubscribeOwner(owner string, lastEventID uint64) (*stream.Subscription, []stream.Event, error)
+	Unsubscribe(sub *stream.Subscription)
+
+	CreateSubscription(ctx context.Context, sub domain.WebhookSubscription) (*domain.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context, owner string) ([]*domain.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id int64) error
+	Deliveries(ctx context.Context, subscriptionID int64, status domain.DeliveryStatus,
+		limit, offset int) ([]*domain.WebhookDelivery, error)
+	Replay(ctx context.Context, deliveryID int64) (*domain.WebhookDelivery, error)
+
+	CreateImport(ctx context.Context, source importsrv.Source, owner string, data []byte) (*domain.Import, error)
+	GetImport(ctx context.Context, id int64) (*domain.Import, error)
 }
 
 type Controller struct {
-	svc Service
+	svc     Service
+	links   config.Links
+	stream  config.Stream
+	imports config.Imports
 }
 
-func New(svc Service) *Controller {
+func New(svc Service, linksCfg config.Links, streamCfg config.Stream, importsCfg config.Imports) *Controller {
 	return &Controller{
-		svc: svc,
+		svc:     svc,
+		links:   linksCfg,
+		stream:  streamCfg,
+		imports: importsCfg,
 	}
 }
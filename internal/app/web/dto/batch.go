@@ -0,0 +1,49 @@
+package dto
+
+import (
+	"errors"
+	"mime"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sshlykov/shortener/internal/domain"
+	seedsrv "github.com/sshlykov/shortener/internal/pkg/seed/service"
+)
+
+var ErrUnsupportedBatchFormat = errors.New("content type should be application/json, application/x-ndjson or text/csv")
+
+// EjectBatchFormat определяет формат пакета по Content-Type
+func EjectBatchFormat(ectx echo.Context) (seedsrv.Format, error) {
+	mediaType, _, err := mime.ParseMediaType(ectx.Request().Header.Get(echo.HeaderContentType))
+	if err != nil {
+		return "", ErrUnsupportedBatchFormat
+	}
+
+	switch mediaType {
+	case echo.MIMEApplicationJSON:
+		return seedsrv.FormatJSON, nil
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return seedsrv.FormatJSONL, nil
+	case "text/csv":
+		return seedsrv.FormatCSV, nil
+	default:
+		return "", ErrUnsupportedBatchFormat
+	}
+}
+
+// BatchLinkResult - строка NDJSON-ответа пакетного создания, index - номер записи во входных данных с нуля
+type BatchLinkResult struct {
+	Index    int    `json:"index"`
+	Key      string `json:"key,omitempty"`
+	ShortURL string `json:"short_url,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+func NewBatchLinkResult(index int, link *domain.Link, err error, baseURL string) BatchLinkResult {
+	if err != nil {
+		return BatchLinkResult{Index: index, Error: err.Error()}
+	}
+
+	res := NewLinkResponse(link, baseURL)
+	return BatchLinkResult{Index: index, Key: res.Key, ShortURL: res.ShortURL}
+}
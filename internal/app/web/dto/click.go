@@ -0,0 +1,46 @@
+package dto
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sshlykov/shortener/internal/domain"
+)
+
+const HeaderLastEventID = "Last-Event-ID"
+
+type ClickEvent struct {
+	Key       string    `json:"key"`
+	Owner     string    `json:"owner,omitempty"`
+	ClickedAt time.Time `json:"clicked_at"`
+	Referer   string    `json:"referer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+}
+
+func NewClickEvent(click domain.Click) ClickEvent {
+	return ClickEvent{
+		Key:       click.Key,
+		Owner:     click.Owner,
+		ClickedAt: click.ClickedAt,
+		Referer:   click.Referer,
+		UserAgent: click.UserAgent,
+	}
+}
+
+// EjectLastEventID достает id последнего полученного события из заголовка Last-Event-ID
+// или из query параметра last_event_id (EventSource не умеет выставлять заголовки при первом подключении)
+func EjectLastEventID(ectx echo.Context) uint64 {
+	raw := ectx.Request().Header.Get(HeaderLastEventID)
+	if raw == "" {
+		raw = ectx.QueryParam("last_event_id")
+	}
+
+	id, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return id
+}
@@ -0,0 +1,86 @@
+package dto
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	linksrv "github.com/sshlykov/shortener/internal/pkg/links/service"
+)
+
+var ErrUnknownExportFormat = errors.New("format should be csv or ndjson")
+
+type ExportFormat string
+
+const (
+	ExportCSV    ExportFormat = "csv"
+	ExportNDJSON ExportFormat = "ndjson"
+)
+
+type ExportRequest struct {
+	Format ExportFormat
+	Filter linksrv.ExportFilter
+}
+
+// EjectExport читает format, owner и clicks из query, по умолчанию выгрузка в NDJSON без кликов
+func EjectExport(ectx echo.Context) (*ExportRequest, error) {
+	req := &ExportRequest{Format: ExportNDJSON, Filter: linksrv.ExportFilter{Owner: ectx.QueryParam("owner")}}
+
+	switch format := ExportFormat(ectx.QueryParam("format")); format {
+	case "":
+	case ExportCSV, ExportNDJSON:
+		req.Format = format
+	default:
+		return nil, ErrUnknownExportFormat
+	}
+
+	if raw := ectx.QueryParam("clicks"); raw != "" {
+		withClicks, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, errors.New("clicks should be a boolean")
+		}
+		req.Filter.WithClicks = withClicks
+	}
+
+	return req, nil
+}
+
+type ExportLinkResponse struct {
+	LinkResponse
+	Clicks *int64 `json:"clicks,omitempty"`
+}
+
+func NewExportLinkResponse(link *linksrv.ExportedLink, baseURL string) ExportLinkResponse {
+	return ExportLinkResponse{LinkResponse: NewLinkResponse(&link.Link, baseURL), Clicks: link.Clicks}
+}
+
+// ExportCSVHeader - колонки CSV-выгрузки, clicks - только при выгрузке с кликами
+func ExportCSVHeader(withClicks bool) []string {
+	header := []string{"key", "short_url", "url", "owner", "created_at", "updated_at", "expires_at"}
+	if withClicks {
+		header = append(header, "clicks")
+	}
+
+	return header
+}
+
+func (r *ExportLinkResponse) CSVRecord(withClicks bool) []string {
+	expiresAt := ""
+	if r.ExpiresAt != nil {
+		expiresAt = r.ExpiresAt.Format(time.RFC3339)
+	}
+
+	record := []string{r.Key, r.ShortURL, r.URL, r.Owner,
+		r.CreatedAt.Format(time.RFC3339), r.UpdatedAt.Format(time.RFC3339), expiresAt}
+	if withClicks {
+		clicks := "0"
+		if r.Clicks != nil {
+			clicks = strconv.FormatInt(*r.Clicks, 10)
+		}
+		record = append(record, clicks)
+	}
+
+	return record
+}
@@ -0,0 +1,66 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sshlykov/shortener/internal/domain"
+	importsrv "github.com/sshlykov/shortener/internal/pkg/imports/service"
+)
+
+type CreateImportRequest struct {
+	Source importsrv.Source
+	Owner  string
+}
+
+// EjectCreateImport читает параметры импорта из query: source - bitly, tinyurl или yourls, owner - владелец ссылок
+func EjectCreateImport(ectx echo.Context) (*CreateImportRequest, error) {
+	source, err := importsrv.ParseSource(ectx.QueryParam("source"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreateImportRequest{Source: source, Owner: ectx.QueryParam("owner")}, nil
+}
+
+type ImportResponse struct {
+	ID         int64                `json:"id"`
+	Source     string               `json:"source"`
+	Owner      string               `json:"owner,omitempty"`
+	Status     domain.ImportStatus  `json:"status"`
+	Total      int                  `json:"total"`
+	Processed  int                  `json:"processed"`
+	Created    int                  `json:"created"`
+	Skipped    int                  `json:"skipped"`
+	Failed     int                  `json:"failed"`
+	Issues     []domain.ImportIssue `json:"issues"`
+	LastError  *string              `json:"last_error,omitempty"`
+	CreatedAt  time.Time            `json:"created_at"`
+	UpdatedAt  time.Time            `json:"updated_at"`
+	FinishedAt *time.Time           `json:"finished_at,omitempty"`
+}
+
+func NewImportResponse(imp *domain.Import) ImportResponse {
+	issues := imp.Issues
+	if issues == nil {
+		issues = []domain.ImportIssue{}
+	}
+
+	return ImportResponse{
+		ID:         imp.ID,
+		Source:     imp.Source,
+		Owner:      imp.Owner,
+		Status:     imp.Status,
+		Total:      imp.Total,
+		Processed:  imp.Processed,
+		Created:    imp.Created,
+		Skipped:    imp.Skipped,
+		Failed:     imp.Failed,
+		Issues:     issues,
+		LastError:  imp.LastError,
+		CreatedAt:  imp.CreatedAt,
+		UpdatedAt:  imp.UpdatedAt,
+		FinishedAt: imp.FinishedAt,
+	}
+}
@@ -0,0 +1,125 @@
+package dto
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sshlykov/shortener/internal/domain"
+	linksrv "github.com/sshlykov/shortener/internal/pkg/links/service"
+	shorten "github.com/sshlykov/shortener/internal/pkg/shorten/service"
+)
+
+var (
+	ErrURLEmpty        = errors.New("url is empty")
+	ErrNothingToUpdate = errors.New("nothing to update")
+)
+
+type CreateLinkRequest struct {
+	URL       string     `json:"url"`
+	Key       string     `json:"key"`
+	Owner     string     `json:"owner"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (r *CreateLinkRequest) Validate() error {
+	if r.URL == "" {
+		return ErrURLEmpty
+	}
+
+	return nil
+}
+
+func (r *CreateLinkRequest) ToDomain() domain.Link {
+	return domain.Link{
+		URL:       r.URL,
+		Key:       r.Key,
+		Owner:     r.Owner,
+		ExpiresAt: r.ExpiresAt,
+	}
+}
+
+func EjectCreateLink(ectx echo.Context) (*CreateLinkRequest, error) {
+	var req CreateLinkRequest
+	if err := ectx.Bind(&req); err != nil {
+		return nil, err
+	}
+
+	return &req, nil
+}
+
+// UpdateLinkRequest - поля, которых нет в запросе, не меняются. "expires_at": null снимает срок жизни
+type UpdateLinkRequest struct {
+	URL       *string      `json:"url"`
+	ExpiresAt OptionalTime `json:"expires_at"`
+}
+
+func (r *UpdateLinkRequest) Validate() error {
+	if r.URL == nil && !r.ExpiresAt.Set {
+		return ErrNothingToUpdate
+	}
+
+	return nil
+}
+
+func (r *UpdateLinkRequest) Expiry() linksrv.Expiry {
+	return linksrv.Expiry{Set: r.ExpiresAt.Set, At: r.ExpiresAt.Value}
+}
+
+// OptionalTime отличает отсутствующее поле (Set = false) от null (Set = true, Value = nil)
+type OptionalTime struct {
+	Set   bool
+	Value *time.Time
+}
+
+func (o *OptionalTime) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	return json.Unmarshal(data, &o.Value)
+}
+
+func EjectUpdateLink(ectx echo.Context) (*UpdateLinkRequest, error) {
+	var req UpdateLinkRequest
+	if err := ectx.Bind(&req); err != nil {
+		return nil, err
+	}
+
+	return &req, nil
+}
+
+type LinkResponse struct {
+	Key       string     `json:"key"`
+	ShortURL  string     `json:"short_url"`
+	URL       string     `json:"url"`
+	Owner     string     `json:"owner,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func NewLinkResponse(link *domain.Link, baseURL string) LinkResponse {
+	shortURL, err := shorten.PrependBaseURL(baseURL, link.Key)
+	if err != nil {
+		shortURL = link.Key
+	}
+
+	return LinkResponse{
+		Key:       link.Key,
+		ShortURL:  shortURL,
+		URL:       link.URL,
+		Owner:     link.Owner,
+		CreatedAt: link.CreatedAt,
+		UpdatedAt: link.UpdatedAt,
+		ExpiresAt: link.ExpiresAt,
+	}
+}
+
+func NewLinksResponse(links []*domain.Link, baseURL string) []LinkResponse {
+	res := make([]LinkResponse, 0, len(links))
+	for _, link := range links {
+		res = append(res, NewLinkResponse(link, baseURL))
+	}
+
+	return res
+}
@@ -0,0 +1,42 @@
+package dto
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+const defaultPageSize = 100
+
+var (
+	ErrInvalidPage = errors.New("limit and offset should be non-negative integers")
+)
+
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+// EjectPage читает limit/offset из query, limit ограничивается maxSize
+func EjectPage(ectx echo.Context, maxSize int) (Page, error) {
+	page := Page{Limit: min(defaultPageSize, maxSize)}
+
+	if raw := ectx.QueryParam("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 0 {
+			return Page{}, ErrInvalidPage
+		}
+		page.Limit = min(limit, maxSize)
+	}
+
+	if raw := ectx.QueryParam("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return Page{}, ErrInvalidPage
+		}
+		page.Offset = offset
+	}
+
+	return page, nil
+}
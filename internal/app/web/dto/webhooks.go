@@ -0,0 +1,114 @@
+package dto
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sshlykov/shortener/internal/domain"
+)
+
+var (
+	ErrInvalidID = errors.New("id should be a positive integer")
+)
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Owner  string   `json:"owner"`
+	Secret string   `json:"secret"`
+}
+
+func (r *CreateWebhookRequest) ToDomain() domain.WebhookSubscription {
+	events := make([]domain.EventType, 0, len(r.Events))
+	for _, event := range r.Events {
+		events = append(events, domain.EventType(event))
+	}
+
+	return domain.WebhookSubscription{
+		URL:    r.URL,
+		Events: events,
+		Owner:  r.Owner,
+		Secret: r.Secret,
+	}
+}
+
+func EjectCreateWebhook(ectx echo.Context) (*CreateWebhookRequest, error) {
+	var req CreateWebhookRequest
+	if err := ectx.Bind(&req); err != nil {
+		return nil, err
+	}
+
+	return &req, nil
+}
+
+func EjectID(ectx echo.Context, name string) (int64, error) {
+	id, err := strconv.ParseInt(ectx.Param(name), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidID
+	}
+
+	return id, nil
+}
+
+type WebhookResponse struct {
+	ID        int64              `json:"id"`
+	URL       string             `json:"url"`
+	Events    []domain.EventType `json:"events"`
+	Owner     string             `json:"owner,omitempty"`
+	Active    bool               `json:"active"`
+	CreatedAt time.Time          `json:"created_at"`
+	// Secret отдается только при создании подписки
+	Secret string `json:"secret,omitempty"`
+}
+
+func NewWebhookResponse(sub *domain.WebhookSubscription, withSecret bool) WebhookResponse {
+	res := WebhookResponse{
+		ID:        sub.ID,
+		URL:       sub.URL,
+		Events:    sub.Events,
+		Owner:     sub.Owner,
+		Active:    sub.Active,
+		CreatedAt: sub.CreatedAt,
+	}
+	if withSecret {
+		res.Secret = sub.Secret
+	}
+
+	return res
+}
+
+type DeliveryResponse struct {
+	ID             int64                 `json:"id"`
+	SubscriptionID int64                 `json:"subscription_id"`
+	EventID        string                `json:"event_id"`
+	Event          domain.EventType      `json:"event"`
+	Payload        json.RawMessage       `json:"payload"`
+	Status         domain.DeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	NextAttemptAt  time.Time             `json:"next_attempt_at"`
+	LastStatusCode *int                  `json:"last_status_code,omitempty"`
+	LastError      *string               `json:"last_error,omitempty"`
+	CreatedAt      time.Time             `json:"created_at"`
+	DeliveredAt    *time.Time            `json:"delivered_at,omitempty"`
+}
+
+func NewDeliveryResponse(d *domain.WebhookDelivery) DeliveryResponse {
+	return DeliveryResponse{
+		ID:             d.ID,
+		SubscriptionID: d.SubscriptionID,
+		EventID:        d.EventID,
+		Event:          d.Event,
+		Payload:        d.Payload,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt,
+		DeliveredAt:    d.DeliveredAt,
+	}
+}
@@ -0,0 +1,65 @@
+package health
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sshlykov/shortener/internal/app/web/dto"
+	importsrv "github.com/sshlykov/shortener/internal/pkg/imports/service"
+)
+
+// CreateImport принимает CSV-выгрузку другого сокращателя телом запроса и ставит ее импорт фоновой задачей.
+// Ход импорта отдает GetImport
+func (c *Controller) CreateImport(ectx echo.Context) error {
+	req, err := dto.EjectCreateImport(ectx)
+	if err != nil {
+		return ectx.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(ectx.Response(), ectx.Request().Body, c.imports.MaxBodyBytes))
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return ectx.JSON(http.StatusRequestEntityTooLarge,
+			echo.Map{"error": fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit)})
+	case err != nil:
+		return ectx.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+
+	imp, err := c.svc.CreateImport(ectx.Request().Context(), req.Source, req.Owner, data)
+	if err != nil {
+		return importError(ectx, err)
+	}
+
+	ectx.Response().Header().Set(echo.HeaderLocation, fmt.Sprintf("/api/v1/imports/%d", imp.ID))
+	return ectx.JSON(http.StatusAccepted, dto.NewImportResponse(imp))
+}
+
+func (c *Controller) GetImport(ectx echo.Context) error {
+	id, err := dto.EjectID(ectx, "id")
+	if err != nil {
+		return ectx.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+
+	imp, err := c.svc.GetImport(ectx.Request().Context(), id)
+	if err != nil {
+		return importError(ectx, err)
+	}
+
+	return ectx.JSON(http.StatusOK, dto.NewImportResponse(imp))
+}
+
+func importError(ectx echo.Context, err error) error {
+	switch {
+	case errors.Is(err, importsrv.ErrUnknownSource), errors.Is(err, importsrv.ErrUnrecognizedFile):
+		return ectx.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	case errors.Is(err, importsrv.ErrImportNotFound):
+		return ectx.JSON(http.StatusNotFound, echo.Map{"error": err.Error()})
+	default:
+		return ectx.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+}
@@ -0,0 +1,97 @@
+package health
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sshlykov/shortener/internal/app/web/dto"
+	linksrv "github.com/sshlykov/shortener/internal/pkg/links/service"
+)
+
+func (c *Controller) CreateLink(ectx echo.Context) error {
+	req, err := dto.EjectCreateLink(ectx)
+	if err != nil {
+		return ectx.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request body"})
+	}
+	if err = req.Validate(); err != nil {
+		return ectx.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+
+	link, err := c.svc.Create(ectx.Request().Context(), req.ToDomain())
+	if err != nil {
+		return linkError(ectx, err)
+	}
+
+	return ectx.JSON(http.StatusCreated, dto.NewLinkResponse(link, c.links.BaseURL))
+}
+
+func (c *Controller) ListLinks(ectx echo.Context) error {
+	page, err := dto.EjectPage(ectx, c.links.MaxPageSize)
+	if err != nil {
+		return ectx.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+
+	links, err := c.svc.List(ectx.Request().Context(), linksrv.Filter{
+		Owner:  ectx.QueryParam("owner"),
+		Limit:  page.Limit,
+		Offset: page.Offset,
+	})
+	if err != nil {
+		return linkError(ectx, err)
+	}
+
+	return ectx.JSON(http.StatusOK, dto.NewLinksResponse(links, c.links.BaseURL))
+}
+
+func (c *Controller) GetLink(ectx echo.Context) error {
+	link, err := c.svc.Get(ectx.Request().Context(), ectx.Param("key"))
+	if err != nil {
+		return linkError(ectx, err)
+	}
+
+	return ectx.JSON(http.StatusOK, dto.NewLinkResponse(link, c.links.BaseURL))
+}
+
+func (c *Controller) UpdateLink(ectx echo.Context) error {
+	req, err := dto.EjectUpdateLink(ectx)
+	if err != nil {
+		return ectx.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request body"})
+	}
+	if err = req.Validate(); err != nil {
+		return ectx.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+
+	link, err := c.svc.Update(ectx.Request().Context(), ectx.Param("key"), req.URL, req.Expiry())
+	if err != nil {
+		return linkError(ectx, err)
+	}
+
+	return ectx.JSON(http.StatusOK, dto.NewLinkResponse(link, c.links.BaseURL))
+}
+
+func (c *Controller) DeleteLink(ectx echo.Context) error {
+	if err := c.svc.Delete(ectx.Request().Context(), ectx.Param("key")); err != nil {
+		return linkError(ectx, err)
+	}
+
+	return ectx.NoContent(http.StatusNoContent)
+}
+
+func linkError(ectx echo.Context, err error) error {
+	switch {
+	case errors.Is(err, linksrv.ErrInvalidURL), errors.Is(err, linksrv.ErrInvalidKey):
+		return ectx.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	case errors.Is(err, linksrv.ErrLinkNotFound):
+		return ectx.JSON(http.StatusNotFound, echo.Map{"error": "link not found"})
+	case errors.Is(err, linksrv.ErrLinkExpired):
+		return ectx.JSON(http.StatusGone, echo.Map{"error": "link expired"})
+	case errors.Is(err, linksrv.ErrKeyTaken):
+		return ectx.JSON(http.StatusConflict, echo.Map{"error": "key is already taken"})
+	case errors.Is(err, linksrv.ErrTooManyExports):
+		return ectx.JSON(http.StatusTooManyRequests, echo.Map{"error": err.Error()})
+	default:
+		return ectx.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+}
@@ -0,0 +1,157 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sshlykov/shortener/internal/app/web/dto"
+	"github.com/sshlykov/shortener/internal/domain"
+	seedsrv "github.com/sshlykov/shortener/internal/pkg/seed/service"
+)
+
+// CreateLinks создает ссылки пакетом из JSON-массива, NDJSON или CSV с колонками url, key, owner, expires_at.
+// Тело читается потоком: каждые ChunkSize записей создаются одной транзакцией, и их результаты пишутся
+// в ответ NDJSON до чтения следующей части. Ошибка до первой части возвращается статусом, после нее -
+// последней строкой ответа с index первой необработанной записи
+func (c *Controller) CreateLinks(ectx echo.Context) error {
+	format, err := dto.EjectBatchFormat(ectx)
+	if err != nil {
+		return ectx.JSON(http.StatusUnsupportedMediaType, echo.Map{"error": err.Error()})
+	}
+
+	ctx := ectx.Request().Context()
+	res := ectx.Response()
+	rc := http.NewResponseController(res)
+
+	// HTTP/1.x по умолчанию не дает читать тело после начала ответа
+	if err = rc.EnableFullDuplex(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	body := http.MaxBytesReader(res, ectx.Request().Body, c.links.Batch.MaxBodyBytes)
+	mapping, _ := seedsrv.ParseMapping("")
+	reader, err := seedsrv.NewReader(body, format, mapping)
+	if err != nil {
+		status, msg := c.batchError(unwrapMaxBytes(err))
+		return ectx.JSON(status, echo.Map{"error": msg})
+	}
+
+	enc := json.NewEncoder(res)
+	started := false
+	for offset := 0; ctx.Err() == nil; {
+		chunk, done, readErr := c.readChunk(reader, offset)
+		if readErr != nil && !started {
+			status, msg := c.batchError(readErr)
+			return ectx.JSON(status, echo.Map{"error": msg})
+		}
+		if !started {
+			started = true
+			res.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+			res.Header().Set("X-Accel-Buffering", "no")
+			res.WriteHeader(http.StatusOK)
+		}
+
+		err = rc.SetWriteDeadline(time.Now().Add(c.links.Batch.ChunkTimeout))
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return nil
+		}
+		results := c.createChunk(ectx, offset, chunk)
+		offset += len(chunk)
+		if readErr != nil {
+			_, msg := c.batchError(readErr)
+			results = append(results, dto.BatchLinkResult{Index: offset, Error: msg})
+		}
+		for _, result := range results {
+			if err = enc.Encode(result); err != nil {
+				return nil
+			}
+		}
+		if err = rc.Flush(); err != nil || done || readErr != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+var errTooManyRows = errors.New("too many rows")
+
+// readChunk читает до ChunkSize записей, read - сколько записей уже прочитано, для лимита MaxRows.
+// done - тело прочитано до конца
+func (c *Controller) readChunk(reader seedsrv.Reader, read int) (rows []seedsrv.Row, done bool, err error) {
+	size := max(c.links.Batch.ChunkSize, 1)
+	rows = make([]seedsrv.Row, 0, size)
+	for len(rows) < size {
+		row, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return rows, true, nil
+		}
+		if err != nil {
+			return rows, false, unwrapMaxBytes(err)
+		}
+		if read+len(rows) == c.links.Batch.MaxRows {
+			return rows, false, errTooManyRows
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, false, nil
+}
+
+// batchError - статус и текст ошибки чтения пакета
+func (c *Controller) batchError(err error) (int, string) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit)
+	case errors.Is(err, errTooManyRows):
+		return http.StatusRequestEntityTooLarge, fmt.Sprintf("batch exceeds %d rows", c.links.Batch.MaxRows)
+	default:
+		return http.StatusBadRequest, err.Error()
+	}
+}
+
+// unwrapMaxBytes - csv и json оборачивают ошибку чтения тела без %w
+func unwrapMaxBytes(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return tooLarge
+	}
+
+	return err
+}
+
+// createChunk создает строки без ошибок разбора одной транзакцией и собирает результаты по индексам
+func (c *Controller) createChunk(ectx echo.Context, offset int, chunk []seedsrv.Row) []dto.BatchLinkResult {
+	results := make([]dto.BatchLinkResult, len(chunk))
+	if len(chunk) == 0 {
+		return results
+	}
+
+	links := make([]domain.Link, 0, len(chunk))
+	positions := make([]int, 0, len(chunk))
+	for i, row := range chunk {
+		if row.Err == nil && row.Link.URL == "" {
+			row.Err = dto.ErrURLEmpty
+		}
+		if row.Err != nil {
+			results[i] = dto.NewBatchLinkResult(offset+i, nil, row.Err, c.links.BaseURL)
+			continue
+		}
+		links = append(links, row.Link)
+		positions = append(positions, i)
+	}
+
+	for j, created := range c.svc.CreateBatch(ectx.Request().Context(), links) {
+		i := positions[j]
+		results[i] = dto.NewBatchLinkResult(offset+i, created.Link, created.Err, c.links.BaseURL)
+	}
+
+	return results
+}
@@ -0,0 +1,120 @@
+package health
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sshlykov/shortener/internal/app/web/dto"
+	linksrv "github.com/sshlykov/shortener/internal/pkg/links/service"
+	"github.com/sshlykov/shortener/pkg/logger"
+)
+
+// ExportLinks выгружает все ссылки по фильтру списка в CSV или NDJSON. Строки пишутся в ответ по мере
+// чтения из курсора и сбрасываются клиенту каждые cfg.Export.FetchSize строк
+func (c *Controller) ExportLinks(ectx echo.Context) error {
+	req, err := dto.EjectExport(ectx)
+	if err != nil {
+		return ectx.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+
+	ctx := ectx.Request().Context()
+	res := ectx.Response()
+	rc := http.NewResponseController(res)
+
+	var (
+		header  func() error
+		write   func(link *dto.ExportLinkResponse) error
+		flush   func() error
+		started bool
+		written int
+	)
+	switch req.Format {
+	case dto.ExportCSV:
+		w := csv.NewWriter(res)
+		header = func() error {
+			return w.Write(dto.ExportCSVHeader(req.Filter.WithClicks))
+		}
+		write = func(link *dto.ExportLinkResponse) error {
+			return w.Write(link.CSVRecord(req.Filter.WithClicks))
+		}
+		flush = func() error {
+			w.Flush()
+			return w.Error()
+		}
+	default:
+		enc := json.NewEncoder(res)
+		header = func() error { return nil }
+		write = func(link *dto.ExportLinkResponse) error {
+			return enc.Encode(link)
+		}
+		flush = func() error { return nil }
+	}
+
+	// заголовки отправляются с первой строкой, чтобы ошибку до начала выгрузки можно было вернуть статусом
+	start := func() error {
+		started = true
+		c.exportHeaders(res, req)
+		res.WriteHeader(http.StatusOK)
+		return header()
+	}
+	sync := func() error {
+		if err := flush(); err != nil {
+			return err
+		}
+		err := rc.SetWriteDeadline(time.Now().Add(c.links.Export.ChunkTimeout))
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	err = c.svc.Export(ctx, req.Filter, func(link *linksrv.ExportedLink) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+
+		row := dto.NewExportLinkResponse(link, c.links.BaseURL)
+		if err := write(&row); err != nil {
+			return err
+		}
+		if written++; written%max(c.links.Export.FetchSize, 1) == 0 {
+			return sync()
+		}
+		return nil
+	})
+	if err != nil && !started {
+		return linkError(ectx, err)
+	}
+	if err != nil {
+		// статус уже отправлен, клиент увидит оборванный ответ
+		logger.Warn(ctx, "links export interrupted", logger.Err(err), logger.Any("rows", written))
+		return nil
+	}
+
+	if !started {
+		if err = start(); err != nil {
+			return nil
+		}
+	}
+	_ = sync()
+
+	return nil
+}
+
+func (c *Controller) exportHeaders(res *echo.Response, req *dto.ExportRequest) {
+	contentType, filename := "application/x-ndjson", "links.ndjson"
+	if req.Format == dto.ExportCSV {
+		contentType, filename = "text/csv; charset=utf-8", "links.csv"
+	}
+
+	res.Header().Set(echo.HeaderContentType, contentType)
+	res.Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	res.Header().Set("X-Accel-Buffering", "no")
+}
@@ -0,0 +1,36 @@
+package health
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sshlykov/shortener/internal/domain"
+)
+
+func (c *Controller) Redirect(ectx echo.Context) error {
+	ctx := ectx.Request().Context()
+
+	link, err := c.svc.Resolve(ctx, ectx.Param("key"))
+	if err != nil {
+		return linkError(ectx, err)
+	}
+
+	// у ссылки из снапшота нет id, клик не к чему привязать
+	if link.ID == 0 {
+		return ectx.Redirect(http.StatusFound, link.URL)
+	}
+
+	c.svc.Record(ctx, domain.Click{
+		LinkID:    link.ID,
+		Key:       link.Key,
+		Owner:     link.Owner,
+		ClickedAt: time.Now(),
+		Referer:   ectx.Request().Referer(),
+		UserAgent: ectx.Request().UserAgent(),
+		IP:        ectx.RealIP(),
+	})
+
+	return ectx.Redirect(http.StatusFound, link.URL)
+}
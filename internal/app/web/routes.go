@@ -6,4 +6,27 @@ import (
 
 func (c *Controller) RegisterRoutes(router *echo.Group) {
 	router.POST("/now", c.TestNow)
+	router.GET("/:key", c.Redirect)
+
+	api := router.Group("/api/v1")
+
+	api.POST("/links", c.CreateLink)
+	api.POST("/links\\:batch", c.CreateLinks)
+	api.GET("/links", c.ListLinks)
+	api.GET("/links\\:export", c.ExportLinks)
+	api.GET("/links/:key", c.GetLink)
+	api.PATCH("/links/:key", c.UpdateLink)
+	api.DELETE("/links/:key", c.DeleteLink)
+
+	api.GET("/links/:key/events", c.LinkEvents)
+	api.GET("/owners/:owner/events", c.OwnerEvents)
+
+	api.POST("/webhooks", c.CreateWebhook)
+	api.GET("/webhooks", c.ListWebhooks)
+	api.DELETE("/webhooks/:id", c.DeleteWebhook)
+	api.GET("/webhooks/:id/deliveries", c.ListDeliveries)
+	api.POST("/webhooks/deliveries/:id/replay", c.ReplayDelivery)
+
+	api.POST("/imports", c.CreateImport)
+	api.GET("/imports/:id", c.GetImport)
 }
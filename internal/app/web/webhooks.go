@@ -0,0 +1,102 @@
+package health
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sshlykov/shortener/internal/app/web/dto"
+	"github.com/sshlykov/shortener/internal/domain"
+	webhooksrv "github.com/sshlykov/shortener/internal/pkg/webhooks/service"
+)
+
+func (c *Controller) CreateWebhook(ectx echo.Context) error {
+	req, err := dto.EjectCreateWebhook(ectx)
+	if err != nil {
+		return ectx.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request body"})
+	}
+
+	sub, err := c.svc.CreateSubscription(ectx.Request().Context(), req.ToDomain())
+	if err != nil {
+		return webhookError(ectx, err)
+	}
+
+	return ectx.JSON(http.StatusCreated, dto.NewWebhookResponse(sub, true))
+}
+
+func (c *Controller) ListWebhooks(ectx echo.Context) error {
+	subs, err := c.svc.ListSubscriptions(ectx.Request().Context(), ectx.QueryParam("owner"))
+	if err != nil {
+		return webhookError(ectx, err)
+	}
+
+	res := make([]dto.WebhookResponse, 0, len(subs))
+	for _, sub := range subs {
+		res = append(res, dto.NewWebhookResponse(sub, false))
+	}
+
+	return ectx.JSON(http.StatusOK, res)
+}
+
+func (c *Controller) DeleteWebhook(ectx echo.Context) error {
+	id, err := dto.EjectID(ectx, "id")
+	if err != nil {
+		return ectx.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+
+	if err = c.svc.DeleteSubscription(ectx.Request().Context(), id); err != nil {
+		return webhookError(ectx, err)
+	}
+
+	return ectx.NoContent(http.StatusNoContent)
+}
+
+func (c *Controller) ListDeliveries(ectx echo.Context) error {
+	id, err := dto.EjectID(ectx, "id")
+	if err != nil {
+		return ectx.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+	page, err := dto.EjectPage(ectx, c.links.MaxPageSize)
+	if err != nil {
+		return ectx.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+
+	status := domain.DeliveryStatus(ectx.QueryParam("status"))
+	deliveries, err := c.svc.Deliveries(ectx.Request().Context(), id, status, page.Limit, page.Offset)
+	if err != nil {
+		return webhookError(ectx, err)
+	}
+
+	res := make([]dto.DeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		res = append(res, dto.NewDeliveryResponse(delivery))
+	}
+
+	return ectx.JSON(http.StatusOK, res)
+}
+
+func (c *Controller) ReplayDelivery(ectx echo.Context) error {
+	id, err := dto.EjectID(ectx, "id")
+	if err != nil {
+		return ectx.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+
+	delivery, err := c.svc.Replay(ectx.Request().Context(), id)
+	if err != nil {
+		return webhookError(ectx, err)
+	}
+
+	return ectx.JSON(http.StatusAccepted, dto.NewDeliveryResponse(delivery))
+}
+
+func webhookError(ectx echo.Context, err error) error {
+	switch {
+	case errors.Is(err, webhooksrv.ErrInvalidURL), errors.Is(err, webhooksrv.ErrInvalidEvents):
+		return ectx.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	case errors.Is(err, webhooksrv.ErrSubscriptionNotFound), errors.Is(err, webhooksrv.ErrDeliveryNotFound):
+		return ectx.JSON(http.StatusNotFound, echo.Map{"error": err.Error()})
+	default:
+		return ectx.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+	}
+}
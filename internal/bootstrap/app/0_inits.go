@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
-	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
@@ -18,7 +17,6 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/sshlykov/shortener/internal/config"
-	"github.com/sshlykov/shortener/pkg/backoff"
 	"github.com/sshlykov/shortener/pkg/logger"
 	"github.com/sshlykov/shortener/pkg/postgres"
 )
@@ -97,35 +95,48 @@ func (app *App) initOtel() error {
 }
 
 func (app *App) initDB() error {
-	// Сделано так, чтобы дать запуститься всему остальному, если база не стартанула, то приложение завершится
+	// Пул подключается лениво: недоступная при старте база не мешает запуску,
+	// приложение работает в degraded режиме, пока runDBWatcher не дождется базы
 	dsn, err := config.GetDSN()
 	if err != nil {
 		return fmt.Errorf("unable to connect to database: %w", err)
 	}
-	db, err := postgres.NewClient(app.ctx, dsn)
+	argsPolicy, err := postgres.ParseArgsPolicy(app.cfg.DB.SlowQuery.Args)
+	if err != nil {
+		return err
+	}
+	db, err := postgres.NewClient(app.ctx, dsn, app.poolConfig(),
+		postgres.WithMetrics(postgres.NewMetrics(app.prom)),
+		postgres.WithTracerProvider(app.traceProvider),
+		postgres.WithSlowQueryLog(postgres.SlowQueryLog{
+			Threshold: app.cfg.DB.SlowQuery.Threshold,
+			Args:      argsPolicy,
+			MaxArgLen: app.cfg.DB.SlowQuery.MaxArgLength,
+		}),
+	)
 	if err != nil {
 		return fmt.Errorf("failed to init pg client: %w", err)
 	}
 	app.db = db
 
-	checkupdb := func() {
-		startTime := time.Now()
-		h := func() error {
-			if time.Since(startTime) > app.cfg.DB.RefreshTimeout {
-				return backoff.Permanent(ErrTimeoutExceeded)
-			}
-			err = db.DB().Ping(app.ctx)
-			if err == nil {
-				return backoff.Permanent(nil)
-			}
-			return ErrCantStart
-		}
-		if err = backoff.Retry(h, backoff.NewExponentialBackOff()); err != nil {
-			logger.Error(app.ctx, "error during db creation", logger.Err(err))
-			app.cancel()
-		}
+	return nil
+}
+
+func (app *App) poolConfig() postgres.PoolConfig {
+	cfg := app.cfg.DB
+	appName := cfg.ApplicationName
+	if appName == "" {
+		appName = app.cfg.App.Name
 	}
-	go checkupdb()
 
-	return nil
+	return postgres.PoolConfig{
+		MaxConns:          cfg.MaxConns,
+		MinConns:          cfg.MinConns,
+		MaxConnLifetime:   cfg.MaxConnLifetime,
+		MaxConnIdleTime:   cfg.MaxConnIdleTime,
+		HealthCheckPeriod: cfg.HealthCheckPeriod,
+		StatementTimeout:  cfg.StatementTimeout,
+		ApplicationName:   appName,
+		Replicas:          cfg.Replicas,
+	}
 }
@@ -2,35 +2,225 @@ package app
 
 import (
 	"context"
-	"sync/atomic"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
 
-	"github.com/sshlykov/shortener/pkg/logger"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/sshlykov/shortener/internal/bootstrap/checkers"
+	"github.com/sshlykov/shortener/internal/bootstrap/migrator"
+	"github.com/sshlykov/shortener/internal/domain"
+	"github.com/sshlykov/shortener/pkg/postgres"
 )
 
-func (app *App) appCheckers() []DependencyChecker {
-	return []DependencyChecker{
-		//	checkers.NewDBChecker(app.db),
-		//	checkers.NewAPIChecker(fmt.Sprintf("http://localhost:%d/health", app.cfg.Health.Port)),
+func (app *App) appCheckers() []*checkers.Probe {
+	probes := []*checkers.Probe{
+		app.probe(checkers.NewPostgres(app.db.DB()), true, 0),
+		app.probe(app.schema, true, 0),
+		app.probe(replicaChecker{db: app.db.DB()}, false, 0),
+	}
+	if app.cfg.Snapshot.Fallback {
+		probes = append(probes, app.probe(app.services.Snapshot, false, 0))
 	}
+
+	client := &http.Client{}
+	for _, dep := range app.cfg.Readiness.HTTP {
+		probes = append(probes, app.probe(checkers.NewHTTP(dep.Name, dep.URL, client), dep.Critical, dep.Timeout))
+	}
+
+	return probes
 }
 
-func (app *App) RegisterChecker(checker DependencyChecker) {
-	app.checkers = append(app.checkers, checker)
+// probe оборачивает проверку настройками из readiness, timeout 0 - readiness.timeout
+func (app *App) probe(checker DependencyChecker, critical bool, timeout time.Duration) *checkers.Probe {
+	cfg := app.cfg.Readiness
+	if timeout <= 0 {
+		timeout = cfg.Timeout
+	}
+
+	return checkers.NewProbe(checker, checkers.Options{
+		Critical:         critical,
+		Timeout:          timeout,
+		FailureThreshold: cfg.FailureThreshold,
+		SuccessThreshold: cfg.SuccessThreshold,
+	})
 }
 
+func (app *App) appReporters() []StatusReporter {
+	return []StatusReporter{
+		app.services.Partitions,
+		app.services.Scheduler,
+		app.services.Snapshot,
+		replicaChecker{db: app.db.DB()},
+		app.schema,
+	}
+}
+
+// replicaChecker выводит недоступные реплики из ротации, на готовность не влияет: чтения уходят в primary
+type replicaChecker struct {
+	db postgres.DB
+}
+
+func (c replicaChecker) Check(ctx context.Context) error {
+	c.db.CheckReplicas(ctx)
+	return nil
+}
+
+func (c replicaChecker) Name() string {
+	return "replicas"
+}
+
+func (c replicaChecker) Status(context.Context) any {
+	return c.db.Replicas()
+}
+
+// schemaChecker сверяет версию схемы в таблице goose с диапазоном, вшитым в бинарник,
+// чтобы старые поды не работали со схемой, которую не понимают
+type schemaChecker struct {
+	db       postgres.DB
+	compiled migrator.SchemaRange
+
+	mu      sync.RWMutex
+	version *int64
+	err     error
+}
+
+func newSchemaChecker(db postgres.DB) *schemaChecker {
+	return &schemaChecker{db: db, compiled: migrator.Compiled()}
+}
+
+// SchemaStatus - версия схемы в базе и совместимый с бинарником диапазон
+type SchemaStatus struct {
+	Database   *int64               `json:"database"`
+	Compiled   migrator.SchemaRange `json:"compiled"`
+	Compatible bool                 `json:"compatible"`
+	Error      string               `json:"error,omitempty"`
+}
+
+// версию читаем из primary: реплика может отставать от только что примененной миграции
+const getSchemaVersion = `SELECT COALESCE(max(version_id), 0) FROM goose_db_version`
+
+func (c *schemaChecker) Check(ctx context.Context) error {
+	version, err := c.read(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err != nil {
+		c.version, c.err = nil, err
+		return err
+	}
+	c.version, c.err = &version, c.compiled.Check(version)
+
+	return c.err
+}
+
+func (c *schemaChecker) read(ctx context.Context) (int64, error) {
+	var version int64
+	q := postgres.Query{Name: "schema.get_version", Raw: getSchemaVersion}
+	err := c.db.QueryRowContext(ctx, q).Scan(&version)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "42P01" {
+		// миграции еще ни разу не применялись
+		return 0, nil
+	}
+
+	return version, err
+}
+
+func (c *schemaChecker) Name() string {
+	return "schema"
+}
+
+func (c *schemaChecker) Status(context.Context) any {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	status := SchemaStatus{Database: c.version, Compiled: c.compiled, Compatible: c.version != nil && c.err == nil}
+	if c.err != nil {
+		status.Error = c.err.Error()
+	}
+
+	return status
+}
+
+func (app *App) RegisterChecker(probe *checkers.Probe) {
+	app.checkers = append(app.checkers, probe)
+}
+
+// CheckReadiness запускает проверки параллельно и собирает итог: упавшая критичная проверка - down,
+// недоступная база - degraded, даже если проверки еще не успели упасть, иначе ready.
+// Пока наблюдатель базы не сделал первый ping, приложение остается в starting: иначе под попал бы
+// в ротацию в degraded режиме и отклонял запись, хотя база доступна. Так же starting держится, пока применяются миграции
 func (app *App) CheckReadiness(ctx context.Context) bool {
-	for _, checker := range app.checkers {
-		if err := checker.Check(ctx); err != nil {
-			logger.Error(ctx, "Readiness check failed", logger.Err(err))
-			atomic.StoreInt32(&app.ready, 0)
-			return false
+	app.checkMu.Lock()
+	defer app.checkMu.Unlock()
+
+	checkers.Run(ctx, app.checkers)
+
+	report := &domain.Readiness{Status: domain.ReadinessReady, Checks: make([]domain.CheckResult, 0, len(app.checkers))}
+	for _, probe := range app.checkers {
+		result := probe.Result()
+		report.Checks = append(report.Checks, result)
+		if result.Critical && result.Status != domain.CheckPass && report.Status == domain.ReadinessReady {
+			report.Status, report.Error = domain.ReadinessDown, result.Name+": "+result.LastError
 		}
 	}
+	if app.Degraded() {
+		report.Status, report.Error = domain.ReadinessDegraded, ErrDatabaseUnavailable.Error()
+	}
 
-	atomic.StoreInt32(&app.ready, 1)
-	return true
+	if !app.dbChecked() || app.migrating.Load() {
+		report.Status, report.Error = domain.ReadinessStarting, ErrNotReady.Error()
+		app.readiness.Store(report)
+		return false
+	}
+
+	app.readiness.Store(report)
+	if report.Status == domain.ReadinessDegraded {
+		app.setState(ctx, domain.LifecycleDegraded)
+	} else {
+		app.setState(ctx, domain.LifecycleReady)
+	}
+
+	return report.Status == domain.ReadinessReady
 }
 
 func (app *App) IsReady() bool {
-	return atomic.LoadInt32(&app.ready) == 1
+	return app.Readiness().Status == domain.ReadinessReady
+}
+
+// Readiness возвращает результат последней проверки готовности с учетом этапа жизненного цикла:
+// до первой проверки - starting, после сигнала остановки - draining
+func (app *App) Readiness() domain.Readiness {
+	res := domain.Readiness{Status: domain.ReadinessStarting, Error: ErrNotReady.Error(), Checks: []domain.CheckResult{}}
+	if r := app.readiness.Load(); r != nil {
+		res = *r
+	}
+
+	res.State = app.State()
+	switch res.State {
+	case domain.LifecycleStarting:
+		res.Status = domain.ReadinessStarting
+	case domain.LifecycleDraining, domain.LifecycleStopped:
+		res.Status, res.Error = domain.ReadinessDraining, ErrDraining.Error()
+	}
+
+	return res
+}
+
+func (app *App) RegisterReporter(reporter StatusReporter) {
+	app.reporters = append(app.reporters, reporter)
+}
+
+func (app *App) Statuses(ctx context.Context) map[string]any {
+	statuses := make(map[string]any, len(app.reporters))
+	for _, reporter := range app.reporters {
+		statuses[reporter.Name()] = reporter.Status(ctx)
+	}
+
+	return statuses
 }
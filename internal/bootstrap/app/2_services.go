@@ -9,20 +9,32 @@ import (
 	"github.com/sshlykov/shortener/pkg/logger"
 )
 
-func (app *App) appServices() []func(ctx context.Context, stop context.CancelFunc, wg *sync.WaitGroup) {
+// probeServices отвечают на пробы и следят за базой, они работают и во время миграций
+func (app *App) probeServices() []func(ctx context.Context, stop context.CancelFunc, wg *sync.WaitGroup) {
 	return []func(ctx context.Context, stop context.CancelFunc, wg *sync.WaitGroup){
-		app.runWebApp,
+		app.runDBWatcher,
 		app.runHealthApp,
 		app.runReadinessChecker,
 	}
 }
 
+func (app *App) appServices() []func(ctx context.Context, stop context.CancelFunc, wg *sync.WaitGroup) {
+	return []func(ctx context.Context, stop context.CancelFunc, wg *sync.WaitGroup){
+		app.runWebApp,
+		app.runScheduler,
+		app.runClickPipeline,
+		app.runWebhookDispatcher,
+		app.runOutboxRelay,
+		app.runJobWorkers,
+	}
+}
+
 func (app *App) runWebApp(ctx context.Context, stop context.CancelFunc, wg *sync.WaitGroup) {
 	defer wg.Done()
 	defer stop()
 	defer logger.Info(ctx, "web app stopped")
 
-	if err := registry.RunWebServer(app.ctx, app.prom, app.cfg.Web, app.services); err != nil {
+	if err := registry.RunWebServer(app.ctx, app.prom, app.cfg, app.services, app.Degraded); err != nil {
 		logger.Error(ctx, "web app error", err)
 	}
 }
@@ -32,7 +44,7 @@ func (app *App) runHealthApp(ctx context.Context, stop context.CancelFunc, wg *s
 	defer stop()
 	defer logger.Info(ctx, "health app stopped")
 
-	if err := registry.RunHealthServer(app.ctx, app.prom, app.cfg.Health, app.IsReady); err != nil {
+	if err := registry.RunHealthServer(app.ctx, app.prom, app.cfg.Health, app, app.Statuses); err != nil {
 		logger.Error(ctx, "health app error", err)
 	}
 }
@@ -42,15 +54,59 @@ func (app *App) runReadinessChecker(ctx context.Context, stop context.CancelFunc
 	defer stop()
 	defer logger.Info(ctx, "Readiness checker stopped")
 
+	// это главный цикл для watchdog: если проверка зависла, отметки прекращаются и /health падает
 	ticker := time.NewTicker(app.cfg.App.ReadinessCheckPeriod)
 	defer ticker.Stop()
+	app.beat()
 	app.CheckReadiness(ctx)
+	app.beat()
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
 			app.CheckReadiness(ctx)
+			app.beat()
 		}
 	}
 }
+
+func (app *App) runScheduler(ctx context.Context, stop context.CancelFunc, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer stop()
+	defer logger.Info(ctx, "scheduler stopped")
+
+	app.services.Scheduler.Run(ctx)
+}
+
+func (app *App) runClickPipeline(ctx context.Context, stop context.CancelFunc, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer stop()
+	defer logger.Info(ctx, "click pipeline stopped")
+
+	app.services.Clicks.Run(ctx)
+}
+
+func (app *App) runWebhookDispatcher(ctx context.Context, stop context.CancelFunc, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer stop()
+	defer logger.Info(ctx, "webhook dispatcher stopped")
+
+	app.services.Webhooks.Run(ctx)
+}
+
+func (app *App) runOutboxRelay(ctx context.Context, stop context.CancelFunc, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer stop()
+	defer logger.Info(ctx, "outbox relay stopped")
+
+	app.services.Outbox.Run(ctx)
+}
+
+func (app *App) runJobWorkers(ctx context.Context, stop context.CancelFunc, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer stop()
+	defer logger.Info(ctx, "job workers stopped")
+
+	app.services.Jobs.Run(ctx)
+}
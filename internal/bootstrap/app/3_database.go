@@ -0,0 +1,189 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sshlykov/shortener/internal/bootstrap/migrator"
+	"github.com/sshlykov/shortener/internal/config"
+	"github.com/sshlykov/shortener/pkg/backoff"
+	"github.com/sshlykov/shortener/pkg/logger"
+)
+
+// runDBWatcher следит за доступностью базы. Пока база недоступна, приложение работает в degraded режиме,
+// а подключение повторяется с экспоненциальной задержкой; доступная база проверяется раз в ReadinessCheckPeriod
+func (app *App) runDBWatcher(ctx context.Context, stop context.CancelFunc, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer stop()
+	defer logger.Info(ctx, "database watcher stopped")
+
+	for {
+		if err := app.reconnectDB(ctx); err != nil {
+			return
+		}
+		app.setDBAvailable(ctx, true)
+		app.CheckReadiness(ctx)
+
+		app.watchDB(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		app.setDBAvailable(ctx, false)
+		app.CheckReadiness(ctx)
+	}
+}
+
+func (app *App) reconnectDB(ctx context.Context) error {
+	cfg := app.cfg.Degraded
+	b := backoff.NewExponentialBackOff(
+		backoff.WithInitialInterval(cfg.ReconnectInitialInterval),
+		backoff.WithMaxInterval(cfg.ReconnectMaxInterval),
+		backoff.WithMaxElapsedTime(0),
+	)
+
+	return backoff.RetryNotify(
+		func() error {
+			return app.pingDB(ctx)
+		},
+		backoff.WithContext(b, ctx),
+		func(err error, next time.Duration) {
+			app.setDBAvailable(ctx, false)
+			logger.Warn(ctx, "database is unavailable", logger.Err(err), logger.Any("next", next.String()))
+		},
+	)
+}
+
+// watchDB возвращается, когда база перестала отвечать или отменен ctx
+func (app *App) watchDB(ctx context.Context) {
+	ticker := time.NewTicker(app.cfg.App.ReadinessCheckPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := app.pingDB(ctx); err != nil {
+				logger.Error(ctx, "database ping failed", logger.Err(err))
+				return
+			}
+		}
+	}
+}
+
+func (app *App) pingDB(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, app.cfg.Degraded.PingTimeout)
+	defer cancel()
+
+	return app.db.DB().Ping(ctx)
+}
+
+// состояние базы по последнему ping, до первого ping оно неизвестно
+const (
+	dbUnknown int32 = iota
+	dbUp
+	dbDown
+)
+
+func (app *App) setDBAvailable(ctx context.Context, available bool) {
+	state := dbDown
+	if available {
+		state = dbUp
+	}
+
+	prev := app.dbState.Swap(state)
+	switch {
+	case prev == state:
+	case available && prev == dbUnknown:
+		logger.Info(ctx, "database is available")
+	case available:
+		logger.Info(ctx, "database is available, leaving degraded mode")
+	default:
+		logger.Error(ctx, "database is unavailable, entering degraded mode")
+	}
+}
+
+// dbChecked - база уже ответила или не ответила на ping хотя бы раз
+func (app *App) dbChecked() bool {
+	return app.dbState.Load() != dbUnknown
+}
+
+// Degraded - база недоступна или еще не проверена: редиректы отдаются из кэша и снапшота, запись отклоняется
+func (app *App) Degraded() bool {
+	return app.dbState.Load() != dbUp
+}
+
+// runMigrations применяет миграции при db.auto_migrate. Выполняется в Run после запуска проб:
+// пока база недоступна или миграции ждут блокировку другого деплоя, /startup и /health отвечают,
+// а готовность остается starting. Попытки повторяются, пока не будет отменен ctx,
+// кроме схемы новее бинарника - с ней приложение не запускается
+func (app *App) runMigrations(ctx context.Context) error {
+	if !app.cfg.DB.AutoMigrate {
+		return nil
+	}
+	defer app.migrating.Store(false)
+
+	cfg := app.cfg.Degraded
+	b := backoff.NewExponentialBackOff(
+		backoff.WithInitialInterval(cfg.ReconnectInitialInterval),
+		backoff.WithMaxInterval(cfg.ReconnectMaxInterval),
+		backoff.WithMaxElapsedTime(0),
+	)
+
+	return backoff.RetryNotify(
+		func() error {
+			err := app.migrate(ctx)
+			if errors.Is(err, migrator.ErrSchemaAhead) {
+				return backoff.Permanent(err)
+			}
+			return err
+		},
+		backoff.WithContext(b, ctx),
+		func(err error, next time.Duration) {
+			logger.Warn(ctx, "migrations not applied", logger.Err(err), logger.Any("next", next.String()))
+		},
+	)
+}
+
+// migrate применяет миграции одной попыткой
+func (app *App) migrate(ctx context.Context) error {
+	dsn, err := config.GetDSN()
+	if err != nil {
+		return fmt.Errorf("unable to connect to database: %w", err)
+	}
+	db, err := migrator.Open(ctx, dsn, app.cfg.DB.RefreshTimeout)
+	if err != nil {
+		return fmt.Errorf("auto migrate: %w", err)
+	}
+	m, err := migrator.New(db, migrationLog{ctx: ctx})
+	if err != nil {
+		_ = db.Close()
+		return fmt.Errorf("auto migrate: %w", err)
+	}
+	defer m.Close()
+
+	logger.Info(ctx, "applying migrations", logger.Any("latest", m.Latest()))
+	if err = m.Up(ctx); err != nil {
+		return fmt.Errorf("auto migrate: %w", err)
+	}
+
+	return nil
+}
+
+// migrationLog пишет вывод мигратора в лог построчно
+type migrationLog struct {
+	//nolint:containedctx
+	ctx context.Context
+}
+
+func (l migrationLog) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimSpace(string(p)), "\n") {
+		logger.Info(l.ctx, "migration", logger.Any("result", line))
+	}
+
+	return len(p), nil
+}
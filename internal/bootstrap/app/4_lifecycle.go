@@ -0,0 +1,92 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/sshlykov/shortener/internal/domain"
+	"github.com/sshlykov/shortener/pkg/logger"
+)
+
+// State - текущий этап жизненного цикла приложения
+func (app *App) State() domain.LifecycleState {
+	app.stateMu.RLock()
+	defer app.stateMu.RUnlock()
+
+	return app.state
+}
+
+// stages - порядок этапов: состояние не возвращается на более ранний этап,
+// поэтому поздняя проверка готовности не отменит draining
+var stages = map[domain.LifecycleState]int{
+	domain.LifecycleStarting: 0,
+	domain.LifecycleReady:    1,
+	domain.LifecycleDegraded: 1,
+	domain.LifecycleDraining: 2,
+	domain.LifecycleStopped:  3,
+}
+
+func (app *App) setState(ctx context.Context, state domain.LifecycleState) {
+	app.stateMu.Lock()
+	prev := app.state
+	if stages[state] >= stages[prev] {
+		app.state = state
+	}
+	app.stateMu.Unlock()
+
+	if stages[state] >= stages[prev] && prev != state {
+		logger.Info(ctx, "app state changed", logger.Any("from", prev), logger.Any("to", state))
+	}
+}
+
+// drain ждет сигнала остановки и переводит приложение в draining. Сервисы и серверы останавливаются
+// только через app.drain_delay, чтобы балансировщик успел увидеть draining в /readiness.
+// Повторный сигнал завершает процесс сразу
+func (app *App) drain(signalCtx context.Context, stopSignals, stopServices context.CancelFunc) {
+	<-signalCtx.Done()
+	stopSignals()
+	app.setState(app.ctx, domain.LifecycleDraining)
+
+	// приложение остановилось само, ждать балансировщик незачем
+	if app.ctx.Err() == nil && app.cfg.App.DrainDelay > 0 {
+		logger.Info(app.ctx, "draining before shutdown", logger.Any("delay", app.cfg.App.DrainDelay.String()))
+
+		timer := time.NewTimer(app.cfg.App.DrainDelay)
+		select {
+		case <-timer.C:
+		case <-app.ctx.Done():
+			timer.Stop()
+		}
+	}
+
+	stopServices()
+}
+
+// beat отмечает, что главный цикл жив
+func (app *App) beat() {
+	app.heartbeat.Store(time.Now().UnixNano())
+}
+
+// Liveness - жив ли главный цикл проверки готовности. Во время остановки цикл завершается
+// штатно, поэтому отметка не проверяется
+func (app *App) Liveness() domain.Liveness {
+	state := app.State()
+	res := domain.Liveness{Status: "healthy", State: state}
+
+	beatAt := app.heartbeat.Load()
+	if beatAt == 0 {
+		return res
+	}
+	last := time.Unix(0, beatAt)
+	res.LastHeartbeat = &last
+
+	timeout := app.cfg.App.WatchdogTimeout
+	if timeout <= 0 || state == domain.LifecycleDraining || state == domain.LifecycleStopped {
+		return res
+	}
+	if time.Since(last) > timeout {
+		res.Status, res.Error = "unhealthy", ErrWatchdog.Error()
+	}
+
+	return res
+}
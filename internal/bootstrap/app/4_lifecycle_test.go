@@ -0,0 +1,197 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sshlykov/shortener/internal/config"
+	"github.com/sshlykov/shortener/internal/domain"
+)
+
+func newTestApp(cfg config.App) *App {
+	app := &App{state: domain.LifecycleStarting, cfg: &config.Config{App: cfg}}
+	app.ctx, app.cancel = context.WithCancel(context.Background())
+
+	return app
+}
+
+func TestSetState(t *testing.T) {
+	tests := []struct {
+		name   string
+		states []domain.LifecycleState
+		want   domain.LifecycleState
+	}{
+		{name: "ready", states: []domain.LifecycleState{domain.LifecycleReady}, want: domain.LifecycleReady},
+		{name: "degraded and back", states: []domain.LifecycleState{domain.LifecycleReady, domain.LifecycleDegraded, domain.LifecycleReady}, want: domain.LifecycleReady},
+		{name: "ready never returns to starting", states: []domain.LifecycleState{domain.LifecycleReady, domain.LifecycleStarting}, want: domain.LifecycleReady},
+		{name: "late check after draining", states: []domain.LifecycleState{domain.LifecycleReady, domain.LifecycleDraining, domain.LifecycleReady}, want: domain.LifecycleDraining},
+		{name: "degraded after draining", states: []domain.LifecycleState{domain.LifecycleDraining, domain.LifecycleDegraded}, want: domain.LifecycleDraining},
+		{name: "stopped is final", states: []domain.LifecycleState{domain.LifecycleStopped, domain.LifecycleDraining, domain.LifecycleReady}, want: domain.LifecycleStopped},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(config.App{})
+			for _, state := range tt.states {
+				app.setState(context.Background(), state)
+			}
+			if got := app.State(); got != tt.want {
+				t.Errorf("State() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLiveness(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		beatAgo time.Duration
+		state   domain.LifecycleState
+		want    string
+	}{
+		{name: "no heartbeat yet", timeout: time.Second, state: domain.LifecycleStarting, want: "healthy"},
+		{name: "fresh heartbeat", timeout: time.Minute, beatAgo: time.Second, state: domain.LifecycleReady, want: "healthy"},
+		{name: "stale heartbeat", timeout: time.Minute, beatAgo: 2 * time.Minute, state: domain.LifecycleReady, want: "unhealthy"},
+		{name: "stale heartbeat while degraded", timeout: time.Minute, beatAgo: 2 * time.Minute, state: domain.LifecycleDegraded, want: "unhealthy"},
+		{name: "watchdog disabled", beatAgo: time.Hour, state: domain.LifecycleReady, want: "healthy"},
+		{name: "stale heartbeat while draining", timeout: time.Minute, beatAgo: time.Hour, state: domain.LifecycleDraining, want: "healthy"},
+		{name: "stale heartbeat when stopped", timeout: time.Minute, beatAgo: time.Hour, state: domain.LifecycleStopped, want: "healthy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(config.App{WatchdogTimeout: tt.timeout})
+			app.state = tt.state
+			if tt.beatAgo > 0 {
+				app.heartbeat.Store(time.Now().Add(-tt.beatAgo).UnixNano())
+			}
+
+			res := app.Liveness()
+			if res.Status != tt.want || res.State != tt.state {
+				t.Errorf("Liveness() = %+v, want %s in %s", res, tt.want, tt.state)
+			}
+			if (res.Error != "") != (tt.want == "unhealthy") {
+				t.Errorf("Liveness() error = %q", res.Error)
+			}
+		})
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	tests := []struct {
+		name   string
+		report *domain.Readiness
+		state  domain.LifecycleState
+		want   domain.ReadinessStatus
+	}{
+		{name: "not checked", state: domain.LifecycleStarting, want: domain.ReadinessStarting},
+		{name: "checked while starting", report: &domain.Readiness{Status: domain.ReadinessReady}, state: domain.LifecycleStarting, want: domain.ReadinessStarting},
+		{name: "ready", report: &domain.Readiness{Status: domain.ReadinessReady}, state: domain.LifecycleReady, want: domain.ReadinessReady},
+		{name: "down", report: &domain.Readiness{Status: domain.ReadinessDown}, state: domain.LifecycleReady, want: domain.ReadinessDown},
+		{name: "degraded", report: &domain.Readiness{Status: domain.ReadinessDegraded}, state: domain.LifecycleDegraded, want: domain.ReadinessDegraded},
+		{name: "ready while draining", report: &domain.Readiness{Status: domain.ReadinessReady}, state: domain.LifecycleDraining, want: domain.ReadinessDraining},
+		{name: "degraded while draining", report: &domain.Readiness{Status: domain.ReadinessDegraded}, state: domain.LifecycleDraining, want: domain.ReadinessDraining},
+		{name: "stopped", report: &domain.Readiness{Status: domain.ReadinessReady}, state: domain.LifecycleStopped, want: domain.ReadinessDraining},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(config.App{})
+			app.state = tt.state
+			if tt.report != nil {
+				app.readiness.Store(tt.report)
+			}
+
+			res := app.Readiness()
+			if res.Status != tt.want || res.State != tt.state {
+				t.Errorf("Readiness() = %+v, want %s in %s", res, tt.want, tt.state)
+			}
+			if app.IsReady() != (tt.want == domain.ReadinessReady) {
+				t.Errorf("IsReady() = %v with status %s", app.IsReady(), res.Status)
+			}
+		})
+	}
+}
+
+func TestCheckReadinessWaitsForDatabase(t *testing.T) {
+	app := newTestApp(config.App{})
+
+	if app.CheckReadiness(context.Background()) || app.State() != domain.LifecycleStarting {
+		t.Fatalf("before first ping state = %s, want starting", app.State())
+	}
+
+	app.setDBAvailable(context.Background(), false)
+	if app.CheckReadiness(context.Background()) || app.State() != domain.LifecycleDegraded {
+		t.Errorf("database down state = %s, want degraded", app.State())
+	}
+
+	app.setDBAvailable(context.Background(), true)
+	if !app.CheckReadiness(context.Background()) || app.State() != domain.LifecycleReady {
+		t.Errorf("database up state = %s, want ready", app.State())
+	}
+}
+
+func TestCheckReadinessWaitsForMigrations(t *testing.T) {
+	app := newTestApp(config.App{})
+	app.setDBAvailable(context.Background(), true)
+	app.migrating.Store(true)
+
+	if app.CheckReadiness(context.Background()) || app.State() != domain.LifecycleStarting {
+		t.Fatalf("state while migrating = %s, want starting", app.State())
+	}
+	if res := app.Readiness(); res.Status != domain.ReadinessStarting {
+		t.Errorf("Readiness() while migrating = %s, want starting", res.Status)
+	}
+
+	app.migrating.Store(false)
+	if !app.CheckReadiness(context.Background()) || app.State() != domain.LifecycleReady {
+		t.Errorf("state after migrations = %s, want ready", app.State())
+	}
+}
+
+func TestDrain(t *testing.T) {
+	tests := []struct {
+		name      string
+		delay     time.Duration
+		cancelApp bool
+		minWait   time.Duration
+	}{
+		{name: "waits for the delay", delay: 50 * time.Millisecond, minWait: 50 * time.Millisecond},
+		{name: "no delay", delay: 0},
+		{name: "app already stopped", delay: time.Hour, cancelApp: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(config.App{DrainDelay: tt.delay})
+			app.setState(context.Background(), domain.LifecycleReady)
+			if tt.cancelApp {
+				app.cancel()
+			}
+
+			signalCtx, sendSignal := context.WithCancel(context.Background())
+			sendSignal()
+			var signalsStopped, servicesStopped bool
+
+			started := time.Now()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				app.drain(signalCtx, func() { signalsStopped = true }, func() { servicesStopped = true })
+			}()
+
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("drain did not return")
+			}
+			if elapsed := time.Since(started); elapsed < tt.minWait {
+				t.Errorf("drain returned after %s, want at least %s", elapsed, tt.minWait)
+			}
+			if !signalsStopped || !servicesStopped {
+				t.Errorf("signals stopped %v, services stopped %v", signalsStopped, servicesStopped)
+			}
+			if app.State() != domain.LifecycleDraining {
+				t.Errorf("State() = %s, want draining", app.State())
+			}
+		})
+	}
+}
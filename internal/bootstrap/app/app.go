@@ -4,13 +4,16 @@ import (
 	"context"
 	"os/signal"
 	"sync"
+	"sync/atomic"
 	"syscall"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"go.opentelemetry.io/otel/sdk/trace"
 
+	"github.com/sshlykov/shortener/internal/bootstrap/checkers"
 	"github.com/sshlykov/shortener/internal/bootstrap/registry"
 	"github.com/sshlykov/shortener/internal/config"
+	"github.com/sshlykov/shortener/internal/domain"
 	"github.com/sshlykov/shortener/pkg/logger"
 	"github.com/sshlykov/shortener/pkg/postgres"
 )
@@ -27,14 +30,23 @@ type App struct {
 
 	traceProvider *trace.TracerProvider
 
-	ready    int32
-	checkers []DependencyChecker
+	stateMu   sync.RWMutex
+	state     domain.LifecycleState
+	heartbeat atomic.Int64
+
+	readiness atomic.Pointer[domain.Readiness]
+	checkMu   sync.Mutex
+	dbState   atomic.Int32
+	migrating atomic.Bool
+	checkers  []*checkers.Probe
+	reporters []StatusReporter
 
 	services *registry.Services
+	schema   *schemaChecker
 }
 
 func New(ctx context.Context, cfg *config.Config) (*App, error) {
-	app := &App{}
+	app := &App{state: domain.LifecycleStarting}
 	app.ctx, app.cancel = context.WithCancel(ctx)
 	app.cfg = cfg
 
@@ -48,30 +60,83 @@ func New(ctx context.Context, cfg *config.Config) (*App, error) {
 }
 
 func (app *App) Run() (err error) {
-	ctx, stop := signal.NotifyContext(app.ctx, syscall.SIGINT, syscall.SIGTERM)
-	defer stop()
+	signalCtx, stopSignals := signal.NotifyContext(app.ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	// сервисы останавливаются не по сигналу, а после draining
+	ctx, stopServices := context.WithCancel(app.ctx)
+	defer stopServices()
+	go app.drain(signalCtx, stopSignals, stopServices)
 
 	var wg sync.WaitGroup
 
 	logger.Info(ctx, "starting app")
 	logger.Debug(ctx, "debug messages started")
 
-	app.services = registry.NewServices(app.db, app.cfg)
+	app.services = registry.NewServices(app.db, app.cfg, app.prom)
+	app.services.Links.SetDegraded(app.Degraded)
+	app.schema = newSchemaChecker(app.db.DB())
 
 	for _, checker := range app.appCheckers() {
 		app.RegisterChecker(checker)
 	}
 
-	for _, service := range app.appServices() {
+	for _, reporter := range app.appReporters() {
+		app.RegisterReporter(reporter)
+	}
+
+	// пробы запускаются до миграций, остальные сервисы - после, чтобы не работать со старой схемой
+	app.migrating.Store(app.cfg.DB.AutoMigrate)
+	for _, service := range app.probeServices() {
 		wg.Add(1)
 		go service(ctx, app.cancel, &wg)
 	}
 
+	// ошибка после отмены ctx - это остановка во время миграций, а не их сбой
+	migrateErr := app.runMigrations(ctx)
+	if migrateErr != nil && ctx.Err() == nil {
+		logger.Error(ctx, "migrations not applied", logger.Err(migrateErr))
+		app.cancel()
+	} else {
+		migrateErr = nil
+	}
+
+	if ctx.Err() == nil {
+		for _, service := range app.appServices() {
+			wg.Add(1)
+			go service(ctx, app.cancel, &wg)
+		}
+		app.CheckReadiness(ctx)
+	}
+
 	stoppedChan := make(chan struct{})
 	go func() {
 		wg.Wait()
 		stoppedChan <- struct{}{}
 	}()
 
-	return app.closer(ctx, stoppedChan)
+	defer app.setState(app.ctx, domain.LifecycleStopped)
+
+	if err = app.closer(ctx, stoppedChan); err != nil {
+		return err
+	}
+
+	return migrateErr
+}
+
+// Exec выполняет разовую команду на сервисах приложения, без серверов и фоновых воркеров
+func (app *App) Exec(cmd func(ctx context.Context, services *registry.Services) error) error {
+	ctx, stop := signal.NotifyContext(app.ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	defer app.db.Close()
+
+	if app.cfg.DB.AutoMigrate {
+		if err := app.migrate(ctx); err != nil {
+			return err
+		}
+	}
+
+	app.services = registry.NewServices(app.db, app.cfg, app.prom)
+
+	return cmd(ctx, app.services)
 }
@@ -3,6 +3,8 @@ package app
 import "errors"
 
 var (
-	ErrCantStart       = errors.New("can't start app")
-	ErrTimeoutExceeded = errors.New("timeout exceeded")
+	ErrNotReady            = errors.New("readiness not checked yet")
+	ErrDatabaseUnavailable = errors.New("database is unavailable")
+	ErrDraining            = errors.New("app is shutting down")
+	ErrWatchdog            = errors.New("main loop heartbeat is stale")
 )
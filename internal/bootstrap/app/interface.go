@@ -3,5 +3,11 @@ package app
 import "context"
 
 type DependencyChecker interface {
+	Name() string
 	Check(ctx context.Context) error
 }
+
+type StatusReporter interface {
+	Name() string
+	Status(ctx context.Context) any
+}
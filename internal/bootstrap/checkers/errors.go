@@ -0,0 +1,5 @@
+package checkers
+
+import "errors"
+
+var ErrUnexpectedStatus = errors.New("unexpected response status")
@@ -0,0 +1,43 @@
+package checkers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// HTTP проверяет внешнюю зависимость GET запросом, ответ 4xx и 5xx считается ошибкой
+type HTTP struct {
+	name   string
+	url    string
+	client *http.Client
+}
+
+func NewHTTP(name, url string, client *http.Client) HTTP {
+	return HTTP{name: name, url: url, client: client}
+}
+
+func (c HTTP) Name() string {
+	return c.name
+}
+
+func (c HTTP) Check(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("%w: %s", ErrUnexpectedStatus, resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package checkers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPCheck(t *testing.T) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	checker := NewHTTP("geoip", srv.URL, srv.Client())
+	if err := checker.Check(context.Background()); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	status = http.StatusServiceUnavailable
+	if err := checker.Check(context.Background()); !errors.Is(err, ErrUnexpectedStatus) {
+		t.Errorf("Check() error = %v, want ErrUnexpectedStatus", err)
+	}
+}
@@ -0,0 +1,24 @@
+package checkers
+
+import (
+	"context"
+
+	"github.com/sshlykov/shortener/pkg/postgres"
+)
+
+// Postgres проверяет, что primary отвечает на ping
+type Postgres struct {
+	db postgres.PingRunner
+}
+
+func NewPostgres(db postgres.PingRunner) Postgres {
+	return Postgres{db: db}
+}
+
+func (c Postgres) Name() string {
+	return "postgres"
+}
+
+func (c Postgres) Check(ctx context.Context) error {
+	return c.db.Ping(ctx)
+}
@@ -0,0 +1,137 @@
+package checkers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sshlykov/shortener/internal/domain"
+	"github.com/sshlykov/shortener/pkg/logger"
+)
+
+// Checker - проверка одной зависимости. Check должен соблюдать ctx: по нему истекает таймаут проверки
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+type Options struct {
+	Critical bool
+	// Timeout - таймаут одной проверки, 0 - без таймаута
+	Timeout time.Duration
+	// FailureThreshold и SuccessThreshold - сколько результатов подряд нужно, чтобы сменить состояние проверки
+	FailureThreshold int
+	SuccessThreshold int
+}
+
+// Probe хранит состояние проверки между запусками. Первый результат применяется сразу,
+// дальше состояние меняется только после серии одинаковых результатов, чтобы одиночный сбой
+// не выводил под из балансировки, а одиночный успех не возвращал его раньше времени
+type Probe struct {
+	checker Checker
+	opts    Options
+
+	mu        sync.Mutex
+	checked   bool
+	passing   bool
+	failures  int
+	successes int
+	latency   time.Duration
+	lastErr   error
+	lastErrAt time.Time
+	since     time.Time
+}
+
+func NewProbe(checker Checker, opts Options) *Probe {
+	opts.FailureThreshold = max(opts.FailureThreshold, 1)
+	opts.SuccessThreshold = max(opts.SuccessThreshold, 1)
+
+	return &Probe{checker: checker, opts: opts}
+}
+
+// Run выполняет проверки параллельно и ждет завершения всех
+func Run(ctx context.Context, probes []*Probe) {
+	var wg sync.WaitGroup
+	for _, probe := range probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			probe.run(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (p *Probe) run(ctx context.Context) {
+	checkCtx := ctx
+	if p.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		checkCtx, cancel = context.WithTimeout(ctx, p.opts.Timeout)
+		defer cancel()
+	}
+
+	started := time.Now()
+	err := p.checker.Check(checkCtx)
+
+	changed, passing := p.record(err, time.Since(started), time.Now())
+	switch {
+	case changed && passing:
+		logger.Info(ctx, "readiness check passed", logger.Any("check", p.checker.Name()))
+	case changed:
+		logger.Error(ctx, "readiness check failed", logger.Any("check", p.checker.Name()), logger.Err(err))
+	}
+}
+
+// record применяет результат проверки и сообщает, сменилось ли состояние
+func (p *Probe) record(err error, latency time.Duration, now time.Time) (changed, passing bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.latency = latency
+	if err != nil {
+		p.failures, p.successes = p.failures+1, 0
+		p.lastErr, p.lastErrAt = err, now
+	} else {
+		p.failures, p.successes = 0, p.successes+1
+	}
+
+	switch {
+	case !p.checked:
+		p.checked, p.passing, changed = true, err == nil, true
+	case p.passing && p.failures >= p.opts.FailureThreshold:
+		p.passing, changed = false, true
+	case !p.passing && p.successes >= p.opts.SuccessThreshold:
+		p.passing, changed = true, true
+	}
+	if changed {
+		p.since = now
+	}
+
+	return changed, p.passing
+}
+
+// Result - состояние проверки, до первого запуска проверка считается упавшей
+func (p *Probe) Result() domain.CheckResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result := domain.CheckResult{
+		Name:      p.checker.Name(),
+		Status:    domain.CheckFail,
+		Critical:  p.opts.Critical,
+		LatencyMS: p.latency.Milliseconds(),
+	}
+	if p.passing {
+		result.Status = domain.CheckPass
+	}
+	if p.lastErr != nil {
+		lastErrAt := p.lastErrAt
+		result.LastError, result.LastErrorAt = p.lastErr.Error(), &lastErrAt
+	}
+	if p.checked {
+		since := p.since
+		result.Since = &since
+	}
+
+	return result
+}
@@ -0,0 +1,96 @@
+package checkers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sshlykov/shortener/internal/domain"
+)
+
+type fakeChecker struct {
+	name string
+	errs []error
+}
+
+func (c *fakeChecker) Name() string {
+	return c.name
+}
+
+func (c *fakeChecker) Check(context.Context) error {
+	err := c.errs[0]
+	c.errs = c.errs[1:]
+	return err
+}
+
+func TestProbeDamping(t *testing.T) {
+	fail := errors.New("connection refused")
+	checker := &fakeChecker{name: "db", errs: []error{nil, fail, fail, nil, fail, fail, fail, nil, nil}}
+	probe := NewProbe(checker, Options{Critical: true, FailureThreshold: 3, SuccessThreshold: 2})
+
+	// первый результат применяется сразу, дальше нужна серия одинаковых
+	want := []domain.CheckStatus{
+		domain.CheckPass, domain.CheckPass, domain.CheckPass, domain.CheckPass,
+		domain.CheckPass, domain.CheckPass, domain.CheckFail, domain.CheckFail, domain.CheckPass,
+	}
+	for i, status := range want {
+		probe.run(context.Background())
+		if got := probe.Result().Status; got != status {
+			t.Fatalf("run %d: status = %s, want %s", i, got, status)
+		}
+	}
+
+	result := probe.Result()
+	if !result.Critical || result.Name != "db" {
+		t.Errorf("result = %+v", result)
+	}
+	if result.LastError != fail.Error() || result.LastErrorAt == nil {
+		t.Errorf("last error = %q at %v, want kept after recovery", result.LastError, result.LastErrorAt)
+	}
+}
+
+func TestProbeNotChecked(t *testing.T) {
+	result := NewProbe(&fakeChecker{name: "db"}, Options{}).Result()
+	if result.Status != domain.CheckFail || result.Since != nil {
+		t.Errorf("result = %+v, want fail without since", result)
+	}
+}
+
+type slowChecker struct{}
+
+func (slowChecker) Name() string {
+	return "slow"
+}
+
+func (slowChecker) Check(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Second):
+		return nil
+	}
+}
+
+func TestRunParallelWithTimeout(t *testing.T) {
+	probes := []*Probe{
+		NewProbe(slowChecker{}, Options{Timeout: 50 * time.Millisecond}),
+		NewProbe(slowChecker{}, Options{Timeout: 50 * time.Millisecond}),
+		NewProbe(&fakeChecker{name: "fast", errs: []error{nil}}, Options{}),
+	}
+
+	started := time.Now()
+	Run(context.Background(), probes)
+	if elapsed := time.Since(started); elapsed > 500*time.Millisecond {
+		t.Errorf("Run took %s, want checks in parallel with timeouts", elapsed)
+	}
+
+	for _, probe := range probes[:2] {
+		if result := probe.Result(); result.Status != domain.CheckFail || result.LastError != context.DeadlineExceeded.Error() {
+			t.Errorf("slow result = %+v, want deadline exceeded", result)
+		}
+	}
+	if result := probes[2].Result(); result.Status != domain.CheckPass {
+		t.Errorf("fast result = %+v", result)
+	}
+}
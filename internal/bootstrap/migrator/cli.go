@@ -0,0 +1,117 @@
+package migrator
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Usage - справка по командам Run
+const Usage = `commands:
+  up                apply all pending migrations
+  up-to <version>   apply pending migrations up to version
+  down              roll back the latest migration
+  down-to <version> roll back migrations newer than version, 0 rolls back all
+  redo              roll back and re-apply the latest migration
+  status [-json]    print applied and pending migrations
+  plan              print SQL of pending migrations without applying it
+  version           print the current schema version
+  wait              wait until the database accepts connections`
+
+// Run выполняет команду мигратора. Изменяющие схему команды сериализуются advisory-блокировкой,
+// поэтому одновременные деплои не применяют миграции параллельно
+func Run(ctx context.Context, dsn string, timeout time.Duration, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: command is required", ErrUnknownCommand)
+	}
+
+	cmd, err := parse(args[0], args[1:])
+	if err != nil {
+		return err
+	}
+
+	db, err := Open(ctx, dsn, timeout)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if cmd.name == "wait" {
+		fmt.Fprintln(out, "database is ready")
+		return nil
+	}
+
+	m, err := New(db, out)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	switch cmd.name {
+	case "up":
+		return m.Up(ctx)
+	case "up-to":
+		return m.UpTo(ctx, cmd.version)
+	case "down":
+		return m.Down(ctx)
+	case "down-to":
+		return m.DownTo(ctx, cmd.version)
+	case "redo":
+		return m.Redo(ctx)
+	case "status":
+		return m.Status(ctx, cmd.json)
+	case "plan":
+		return m.Plan(ctx)
+	default:
+		version, err := m.Version(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, version)
+		return nil
+	}
+}
+
+type command struct {
+	name    string
+	version int64
+	json    bool
+}
+
+// parse проверяет аргументы до подключения к базе
+func parse(name string, args []string) (command, error) {
+	cmd := command{name: name}
+
+	switch name {
+	case "up", "down", "redo", "plan", "version", "wait":
+		if len(args) != 0 {
+			return cmd, fmt.Errorf("%w: %s takes no arguments", ErrUnknownCommand, name)
+		}
+	case "up-to", "down-to":
+		if len(args) != 1 {
+			return cmd, fmt.Errorf("%w: %s requires a version", ErrInvalidVersion, name)
+		}
+		v, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || v < 0 {
+			return cmd, fmt.Errorf("%w: %q", ErrInvalidVersion, args[0])
+		}
+		cmd.version = v
+	case "status":
+		fs := flag.NewFlagSet("status", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		fs.BoolVar(&cmd.json, "json", false, "print status as JSON")
+		if err := fs.Parse(args); err != nil {
+			return cmd, fmt.Errorf("%w: %w", ErrUnknownCommand, err)
+		}
+		if fs.NArg() != 0 {
+			return cmd, fmt.Errorf("%w: status takes no arguments", ErrUnknownCommand)
+		}
+	default:
+		return cmd, fmt.Errorf("%w: %q", ErrUnknownCommand, name)
+	}
+
+	return cmd, nil
+}
@@ -0,0 +1,37 @@
+package migrator
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		args []string
+		want command
+		err  error
+	}{
+		{args: []string{"up"}, want: command{name: "up"}},
+		{args: []string{"up-to", "20241201120000"}, want: command{name: "up-to", version: 20241201120000}},
+		{args: []string{"down-to", "0"}, want: command{name: "down-to"}},
+		{args: []string{"status", "-json"}, want: command{name: "status", json: true}},
+		{args: []string{"status"}, want: command{name: "status"}},
+		{args: []string{"up-to"}, err: ErrInvalidVersion},
+		{args: []string{"down-to", "latest"}, err: ErrInvalidVersion},
+		{args: []string{"down-to", "-1"}, err: ErrInvalidVersion},
+		{args: []string{"redo", "1"}, err: ErrUnknownCommand},
+		{args: []string{"status", "-yaml"}, err: ErrUnknownCommand},
+		{args: []string{"fix"}, err: ErrUnknownCommand},
+	}
+
+	for _, tt := range tests {
+		got, err := parse(tt.args[0], tt.args[1:])
+		if !errors.Is(err, tt.err) {
+			t.Errorf("parse(%v) error = %v, want %v", tt.args, err, tt.err)
+			continue
+		}
+		if tt.err == nil && got != tt.want {
+			t.Errorf("parse(%v) = %+v, want %+v", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"github.com/sshlykov/shortener/pkg/backoff"
+)
+
+// Open открывает соединение и ждет, пока база начнет отвечать, но не дольше timeout
+func Open(ctx context.Context, dsn string, timeout time.Duration) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	h := func() error {
+		if time.Since(startTime) > timeout {
+			return backoff.Permanent(ErrTimeoutExceeded)
+		}
+		if perr := db.PingContext(ctx); perr != nil {
+			return fmt.Errorf("%w: %w", ErrCantPingDatabase, perr)
+		}
+		return nil
+	}
+	if err = backoff.Retry(h, backoff.WithContext(backoff.NewExponentialBackOff(), ctx)); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
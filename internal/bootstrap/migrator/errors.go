@@ -0,0 +1,13 @@
+package migrator
+
+import "errors"
+
+var (
+	ErrTimeoutExceeded  = errors.New("error time exceeded")
+	ErrCantPingDatabase = errors.New("database not available")
+	ErrUnknownCommand   = errors.New("unknown command")
+	ErrInvalidVersion   = errors.New("invalid migration version")
+	ErrNothingToRedo    = errors.New("no applied migrations to redo")
+	ErrSchemaAhead      = errors.New("database schema is newer than the binary")
+	ErrSchemaBehind     = errors.New("database schema is older than the binary")
+)
@@ -0,0 +1,203 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pressly/goose/v3"
+
+	"github.com/sshlykov/shortener/migrations"
+)
+
+// lockID - ключ advisory-блокировки, сериализующей миграции между одновременными деплоями
+const lockID int64 = 0x73686f72746e72 // "shortnr"
+
+// Migrator применяет вшитые в бинарник миграции
+type Migrator struct {
+	db       *sql.DB
+	provider *goose.Provider
+	out      io.Writer
+}
+
+func New(db *sql.DB, out io.Writer) (*Migrator, error) {
+	provider, err := migrations.NewProvider(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, provider: provider, out: out}, nil
+}
+
+// MigrationStatus - состояние миграции для вывода status
+type MigrationStatus struct {
+	Version   int64      `json:"version"`
+	Source    string     `json:"source"`
+	Type      string     `json:"type"`
+	State     string     `json:"state"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+func (m *Migrator) Statuses(ctx context.Context) ([]MigrationStatus, error) {
+	list, err := m.provider.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(list))
+	for _, s := range list {
+		status := MigrationStatus{
+			Version: s.Source.Version,
+			Source:  filepath.Base(s.Source.Path),
+			Type:    string(s.Source.Type),
+			State:   string(s.State),
+		}
+		if s.State == goose.StateApplied {
+			appliedAt := s.AppliedAt
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// Status печатает состояние миграций таблицей либо JSON
+func (m *Migrator) Status(ctx context.Context, asJSON bool) error {
+	statuses, err := m.Statuses(ctx)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(m.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(statuses)
+	}
+
+	w := tabwriter.NewWriter(m.out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "Applied At\tMigration")
+	for _, s := range statuses {
+		appliedAt := "Pending"
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.UTC().Format(time.DateTime)
+		}
+		fmt.Fprintf(w, "%s\t%s\n", appliedAt, s.Source)
+	}
+
+	return w.Flush()
+}
+
+// Version возвращает текущую версию схемы в базе
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	return m.provider.GetDBVersion(ctx)
+}
+
+// Latest возвращает версию последней миграции, вшитой в бинарник
+func (m *Migrator) Latest() int64 {
+	return migrations.LatestVersion()
+}
+
+// Up применяет все ожидающие миграции. Если схема в базе новее бинарника, возвращает ErrSchemaAhead:
+// старая версия не должна работать со схемой, которую она не знает
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.locked(ctx, func(ctx context.Context) error {
+		current, err := m.provider.GetDBVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if err = (SchemaRange{Max: m.Latest()}).Check(current); err != nil {
+			return err
+		}
+
+		return m.report(m.provider.Up(ctx))
+	})
+}
+
+func (m *Migrator) UpTo(ctx context.Context, version int64) error {
+	return m.locked(ctx, func(ctx context.Context) error {
+		return m.report(m.provider.UpTo(ctx, version))
+	})
+}
+
+// Down откатывает последнюю примененную миграцию
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.locked(ctx, func(ctx context.Context) error {
+		res, err := m.provider.Down(ctx)
+		return m.report(single(res), err)
+	})
+}
+
+// DownTo откатывает миграции новее version, 0 - откатить все
+func (m *Migrator) DownTo(ctx context.Context, version int64) error {
+	return m.locked(ctx, func(ctx context.Context) error {
+		return m.report(m.provider.DownTo(ctx, version))
+	})
+}
+
+// Redo откатывает и заново применяет последнюю миграцию под одной блокировкой
+func (m *Migrator) Redo(ctx context.Context) error {
+	return m.locked(ctx, func(ctx context.Context) error {
+		current, err := m.provider.GetDBVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if current == 0 {
+			return ErrNothingToRedo
+		}
+
+		res, err := m.provider.Down(ctx)
+		if err = m.report(single(res), err); err != nil {
+			return err
+		}
+		res, err = m.provider.ApplyVersion(ctx, current, true)
+		return m.report(single(res), err)
+	})
+}
+
+// locked выполняет fn под сессионной advisory-блокировкой на отдельном соединении.
+// Конкурирующий деплой ждет ее освобождения, а затем увидит уже примененные миграции
+func (m *Migrator) locked(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		// блокировка снимается и без запроса при закрытии соединения
+		_, _ = conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", lockID)
+	}()
+
+	return fn(ctx)
+}
+
+func (m *Migrator) report(results []*goose.MigrationResult, err error) error {
+	for _, res := range results {
+		fmt.Fprintln(m.out, res.String())
+	}
+	if err == nil && len(results) == 0 {
+		fmt.Fprintln(m.out, "no migrations to run")
+	}
+
+	return err
+}
+
+func single(res *goose.MigrationResult) []*goose.MigrationResult {
+	if res == nil {
+		return nil
+	}
+	return []*goose.MigrationResult{res}
+}
+
+func (m *Migrator) Close() error {
+	return m.provider.Close()
+}
@@ -0,0 +1,76 @@
+package migrator
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/pressly/goose/v3"
+
+	"github.com/sshlykov/shortener/migrations"
+)
+
+// Plan печатает SQL ожидающих миграций, ничего не применяя
+func (m *Migrator) Plan(ctx context.Context) error {
+	list, err := m.provider.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, s := range list {
+		if s.State != goose.StatePending {
+			continue
+		}
+		pending++
+
+		fmt.Fprintf(m.out, "-- %d %s\n", s.Source.Version, filepath.Base(s.Source.Path))
+		if s.Source.Type != goose.TypeSQL {
+			fmt.Fprint(m.out, "-- Go migration, SQL is not available\n\n")
+			continue
+		}
+
+		raw, err := fs.ReadFile(migrations.FS, s.Source.Path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(m.out, "%s\n\n", upSQL(string(raw)))
+	}
+	if pending == 0 {
+		fmt.Fprintln(m.out, "-- no pending migrations")
+	}
+
+	return nil
+}
+
+// upSQL вырезает из файла миграции секцию Up без служебных аннотаций goose
+func upSQL(raw string) string {
+	var (
+		b    strings.Builder
+		inUp bool
+	)
+
+	scanner := bufio.NewScanner(strings.NewReader(raw))
+	for scanner.Scan() {
+		line := scanner.Text()
+		annotation, ok := strings.CutPrefix(strings.TrimSpace(line), "-- +goose ")
+		if ok {
+			switch strings.TrimSpace(annotation) {
+			case "Up":
+				inUp = true
+			case "Down":
+				inUp = false
+			}
+			continue
+		}
+		if inUp {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
+
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,35 @@
+package migrator
+
+import "testing"
+
+func TestUpSQL(t *testing.T) {
+	raw := `-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE t
+(
+    id bigint
+);
+-- +goose StatementEnd
+CREATE INDEX t_id ON t (id);
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE t;
+-- +goose StatementEnd
+`
+	want := `CREATE TABLE t
+(
+    id bigint
+);
+CREATE INDEX t_id ON t (id);`
+
+	if got := upSQL(raw); got != want {
+		t.Errorf("upSQL() = %q, want %q", got, want)
+	}
+}
+
+func TestUpSQLWithoutUp(t *testing.T) {
+	if got := upSQL("-- +goose Down\nDROP TABLE t;\n"); got != "" {
+		t.Errorf("upSQL() = %q, want empty", got)
+	}
+}
@@ -0,0 +1,32 @@
+package migrator
+
+import (
+	"fmt"
+
+	"github.com/sshlykov/shortener/migrations"
+)
+
+// SchemaRange - версии схемы, с которыми совместим бинарник
+type SchemaRange struct {
+	Min int64 `json:"min"`
+	Max int64 `json:"max"`
+}
+
+// Compiled возвращает диапазон, вшитый в бинарник
+func Compiled() SchemaRange {
+	return SchemaRange{Min: migrations.MinVersion, Max: migrations.LatestVersion()}
+}
+
+// Check сравнивает версию схемы в базе с диапазоном бинарника
+func (r SchemaRange) Check(version int64) error {
+	switch {
+	case version < r.Min:
+		return fmt.Errorf("%w: database at %d, binary needs at least %d, apply migrations",
+			ErrSchemaBehind, version, r.Min)
+	case version > r.Max:
+		return fmt.Errorf("%w: database at %d, binary knows up to %d, deploy a newer version",
+			ErrSchemaAhead, version, r.Max)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,59 @@
+package migrator
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/sshlykov/shortener/migrations"
+)
+
+func TestSchemaRangeCheck(t *testing.T) {
+	r := SchemaRange{Min: 20241201120000, Max: 20241208120000}
+
+	tests := []struct {
+		version int64
+		want    error
+	}{
+		{version: 0, want: ErrSchemaBehind},
+		{version: 20241124120000, want: ErrSchemaBehind},
+		{version: 20241201120000},
+		{version: 20241208120000},
+		{version: 20241215120000, want: ErrSchemaAhead},
+	}
+
+	for _, tt := range tests {
+		if err := r.Check(tt.version); !errors.Is(err, tt.want) {
+			t.Errorf("Check(%d) = %v, want %v", tt.version, err, tt.want)
+		}
+	}
+}
+
+func TestCompiled(t *testing.T) {
+	r := Compiled()
+	if r.Max < r.Min {
+		t.Fatalf("latest migration %d is older than migrations.MinVersion %d", r.Max, r.Min)
+	}
+	if r.Max != migrations.LatestVersion() {
+		t.Errorf("Max = %d, want %d", r.Max, migrations.LatestVersion())
+	}
+}
+
+func TestLatestMatchesProvider(t *testing.T) {
+	db, err := sql.Open("postgres", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := New(db, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	sources := m.provider.ListSources()
+	if want := sources[len(sources)-1].Version; m.Latest() != want || migrations.LatestVersion() != want {
+		t.Errorf("Latest() = %d, LatestVersion() = %d, want the last provider source %d",
+			m.Latest(), migrations.LatestVersion(), want)
+	}
+}
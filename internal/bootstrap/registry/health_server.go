@@ -17,7 +17,7 @@ import (
 )
 
 func RunHealthServer(ctx context.Context, prom *prometheus.Registry, cfg config.Health,
-	readinessHandler func() bool) error {
+	probes healthcntrl.Probes, statusHandler func(ctx context.Context) map[string]any) error {
 
 	handler := echo.New()
 	handler.Use(middleware.Recover())
@@ -25,7 +25,7 @@ func RunHealthServer(ctx context.Context, prom *prometheus.Registry, cfg config.
 	loggermw := mw.New(*logger.FromContext(ctx))
 	handler.Use(loggermw)
 
-	healthcntrl.New(prom, readinessHandler).RegisterRoutes(handler.Group(""))
+	healthcntrl.New(prom, probes, statusHandler).RegisterRoutes(handler.Group(""))
 
 	server := &http.Server{
 		Addr:              fmt.Sprintf(":%d", cfg.Port),
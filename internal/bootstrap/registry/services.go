@@ -4,23 +4,123 @@ import (
 	"context"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+
 	"github.com/sshlykov/shortener/internal/config"
+	"github.com/sshlykov/shortener/internal/domain"
+	backupsrvpkg "github.com/sshlykov/shortener/internal/pkg/backup/service"
+	clicksrvpkg "github.com/sshlykov/shortener/internal/pkg/clicks/service"
+	"github.com/sshlykov/shortener/internal/pkg/clicks/stream"
+	importsrvpkg "github.com/sshlykov/shortener/internal/pkg/imports/service"
+	jobsrvpkg "github.com/sshlykov/shortener/internal/pkg/jobs/service"
+	linksrvpkg "github.com/sshlykov/shortener/internal/pkg/links/service"
+	outboxsrvpkg "github.com/sshlykov/shortener/internal/pkg/outbox/service"
+	partsrvpkg "github.com/sshlykov/shortener/internal/pkg/partitions/service"
+	schedsrvpkg "github.com/sshlykov/shortener/internal/pkg/scheduler/service"
+	snapsrvpkg "github.com/sshlykov/shortener/internal/pkg/snapshot/service"
 	testsrvpkg "github.com/sshlykov/shortener/internal/pkg/test_feat/service"
+	webhooksrvpkg "github.com/sshlykov/shortener/internal/pkg/webhooks/service"
 	"github.com/sshlykov/shortener/pkg/postgres"
 )
 
 type Services struct {
 	TestService
+	LinkService
+	ClickService
+	WebhookService
+	ImportService
+
+	Partitions *partsrvpkg.Service
+	Links      *linksrvpkg.Service
+	Clicks     *clicksrvpkg.Service
+	Webhooks   *webhooksrvpkg.Service
+	Imports    *importsrvpkg.Service
+	Backup     *backupsrvpkg.Service
+	Snapshot   *snapsrvpkg.Service
+	Outbox     *outboxsrvpkg.Service
+	Jobs       *jobsrvpkg.Service
+	Scheduler  *schedsrvpkg.Service
 }
 
 type TestService interface {
 	SelectNow(ctx context.Context) (*time.Time, error)
 }
 
-func NewServices(db postgres.Client, _ *config.Config) *Services {
+type LinkService interface {
+	Resolve(ctx context.Context, key string) (*domain.Link, error)
+	Get(ctx context.Context, key string) (*domain.Link, error)
+	Create(ctx context.Context, link domain.Link) (*domain.Link, error)
+	CreateBatch(ctx context.Context, links []domain.Link) []linksrvpkg.BatchResult
+	Update(ctx context.Context, key string, url *string, expiry linksrvpkg.Expiry) (*domain.Link, error)
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context, filter linksrvpkg.Filter) ([]*domain.Link, error)
+	Export(ctx context.Context, filter linksrvpkg.ExportFilter, fn func(link *linksrvpkg.ExportedLink) error) error
+}
+
+type ClickService interface {
+	Record(ctx context.Context, click domain.Click)
+	SubscribeLink(linkID int32, lastEventID uint64) (*stream.Subscription, []stream.Event, error)
+	SubscribeOwner(owner string, lastEventID uint64) (*stream.Subscription, []stream.Event, error)
+	Unsubscribe(sub *stream.Subscription)
+}
+
+type WebhookService interface {
+	CreateSubscription(ctx context.Context, sub domain.WebhookSubscription) (*domain.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context, owner string) ([]*domain.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id int64) error
+	Deliveries(ctx context.Context, subscriptionID int64, status domain.DeliveryStatus,
+		limit, offset int) ([]*domain.WebhookDelivery, error)
+	Replay(ctx context.Context, deliveryID int64) (*domain.WebhookDelivery, error)
+}
+
+type ImportService interface {
+	CreateImport(ctx context.Context, source importsrvpkg.Source, owner string, data []byte) (*domain.Import, error)
+	GetImport(ctx context.Context, id int64) (*domain.Import, error)
+}
+
+func NewServices(db postgres.Client, cfg *config.Config, prom *prometheus.Registry) *Services {
+	tx := postgres.NewTxManager(db.DB())
+
 	testsrv := testsrvpkg.New(db)
+	outboxsrv := outboxsrvpkg.New(db, tx, cfg.Outbox)
+	webhooksrv := webhooksrvpkg.New(db, cfg.Webhooks)
+	linksrv := linksrvpkg.New(db, tx, cfg.Links, outboxsrv)
+	clicksrv := clicksrvpkg.New(db, tx, cfg.Clicks, outboxsrv, webhooksrv)
+	jobsrv := jobsrvpkg.New(db, cfg.Jobs, jobsrvpkg.NewMetrics(prom))
+	partsrv := partsrvpkg.New(db, tx, cfg.Partitions)
+	schedsrv := schedsrvpkg.New(db, tx, cfg.Scheduler)
+	importsrv := importsrvpkg.New(db, tx, cfg.Imports, jobsrv, linksrv)
+	backupsrv := backupsrvpkg.New(db, tx)
+	snapsrv := snapsrvpkg.New(db, tx, cfg.Snapshot)
+
+	outboxsrv.Register(webhooksrv.Publish)
+
+	schedsrv.Register("partitions.maintain", partsrv.Maintain, schedsrvpkg.OnStart())
+	schedsrv.Register("links.expire", linksrv.ExpireDue)
+	schedsrv.Register("jobs.purge", jobsrv.Purge)
+	schedsrv.Register("snapshot.generate", snapsrv.Refresh)
+
+	if cfg.Snapshot.Fallback {
+		linksrv.UseFallback(snapsrv.Fallback())
+	}
+
+	jobsrv.Register(importsrvpkg.JobKind, importsrv.Handle)
 
 	return &Services{
-		TestService: testsrv,
+		TestService:    testsrv,
+		LinkService:    linksrv,
+		ClickService:   clicksrv,
+		WebhookService: webhooksrv,
+		ImportService:  importsrv,
+		Partitions:     partsrv,
+		Links:          linksrv,
+		Clicks:         clicksrv,
+		Webhooks:       webhooksrv,
+		Imports:        importsrv,
+		Backup:         backupsrv,
+		Snapshot:       snapsrv,
+		Outbox:         outboxsrv,
+		Jobs:           jobsrv,
+		Scheduler:      schedsrv,
 	}
 }
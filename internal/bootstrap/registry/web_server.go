@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -14,9 +17,13 @@ import (
 	"github.com/sshlykov/shortener/internal/config"
 	"github.com/sshlykov/shortener/pkg/logger"
 	mw "github.com/sshlykov/shortener/pkg/logger/echomw"
+	"github.com/sshlykov/shortener/pkg/postgres"
 )
 
-func RunWebServer(ctx context.Context, prom *prometheus.Registry, cfg config.Web, service *Services) error {
+func RunWebServer(ctx context.Context, prom *prometheus.Registry, appCfg *config.Config, service *Services,
+	degraded func() bool) error {
+	cfg := appCfg.Web
+
 	handler := echo.New()
 	handler.Use(middleware.Recover())
 
@@ -24,8 +31,10 @@ func RunWebServer(ctx context.Context, prom *prometheus.Registry, cfg config.Web
 	handler.Use(loggermw)
 
 	handler.Use(NewPrometheusMiddleware(prom).Middleware())
+	handler.Use(readYourWrites)
+	handler.Use(rejectWritesWhenDegraded(degraded, appCfg.Degraded.RetryAfter))
 
-	webcntrl.New(service).RegisterRoutes(handler.Group(""))
+	webcntrl.New(service, appCfg.Links, appCfg.Clicks.Stream, appCfg.Imports).RegisterRoutes(handler.Group(""))
 
 	server := &http.Server{
 		Addr:              fmt.Sprintf(":%d", cfg.Port),
@@ -44,3 +53,33 @@ func RunWebServer(ctx context.Context, prom *prometheus.Registry, cfg config.Web
 	}()
 	return handleHTTPClose(ctx, server, cfg.ShutdownTimeout)
 }
+
+// readYourWrites - после записи в рамках запроса чтения этого запроса идут в primary
+func readYourWrites(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		c.SetRequest(req.WithContext(postgres.WithReadYourWrites(req.Context())))
+		return next(c)
+	}
+}
+
+// rejectWritesWhenDegraded отвечает 503 с Retry-After на запросы, меняющие данные, пока база недоступна.
+// Чтения проходят: редиректы отдаются из кэша и снапшота
+func rejectWritesWhenDegraded(degraded func() bool, retryAfter time.Duration) echo.MiddlewareFunc {
+	seconds := strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			switch c.Request().Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				return next(c)
+			}
+			if !degraded() {
+				return next(c)
+			}
+
+			c.Response().Header().Set("Retry-After", seconds)
+			return c.JSON(http.StatusServiceUnavailable, echo.Map{"error": "database is unavailable, writes are disabled"})
+		}
+	}
+}
@@ -11,6 +11,19 @@ type Config struct {
 	Web    Web    `yaml:"web"`
 	Logger Logger `yaml:"logger"`
 	DB     DB     `yaml:"db"`
+
+	Degraded  Degraded  `yaml:"degraded"`
+	Readiness Readiness `yaml:"readiness"`
+
+	Partitions Partitions `yaml:"partitions"`
+	Clicks     Clicks     `yaml:"clicks"`
+	Links      Links      `yaml:"links"`
+	Webhooks   Webhooks   `yaml:"webhooks"`
+	Outbox     Outbox     `yaml:"outbox"`
+	Jobs       Jobs       `yaml:"jobs"`
+	Imports    Imports    `yaml:"imports"`
+	Snapshot   Snapshot   `yaml:"snapshot"`
+	Scheduler  Scheduler  `yaml:"scheduler"`
 }
 
 type App struct {
@@ -20,10 +33,194 @@ type App struct {
 	TerminateTimeout     time.Duration `yaml:"terminate_timeout"`
 	OtelAgent            string        `yaml:"otel_agent"`
 	ReadinessCheckPeriod time.Duration `yaml:"readiness_check_period"`
+	// DrainDelay - сколько после SIGTERM отдавать draining в /readiness и принимать запросы,
+	// чтобы балансировщик успел вывести под, прежде чем серверы остановятся
+	DrainDelay time.Duration `yaml:"drain_delay"`
+	// WatchdogTimeout - /health падает, если цикл проверки готовности не отмечался дольше, 0 - не следить
+	WatchdogTimeout time.Duration `yaml:"watchdog_timeout"`
+}
+
+// Degraded - работа без базы: редиректы из кэша и снапшота, запись отклоняется
+type Degraded struct {
+	// RetryAfter - значение Retry-After в ответах 503 на запись
+	RetryAfter time.Duration `yaml:"retry_after"`
+	// ReconnectInitialInterval и ReconnectMaxInterval - границы экспоненциальной задержки между попытками подключения
+	ReconnectInitialInterval time.Duration `yaml:"reconnect_initial_interval"`
+	ReconnectMaxInterval     time.Duration `yaml:"reconnect_max_interval"`
+	// PingTimeout - таймаут проверки доступной базы, проверка идет раз в app.readiness_check_period
+	PingTimeout time.Duration `yaml:"ping_timeout"`
+}
+
+// Readiness - проверки зависимостей для /readiness, выполняются параллельно раз в app.readiness_check_period
+type Readiness struct {
+	// Timeout - таймаут одной проверки
+	Timeout time.Duration `yaml:"timeout"`
+	// FailureThreshold и SuccessThreshold - сколько результатов подряд меняют состояние проверки, гасят флаппинг
+	FailureThreshold int `yaml:"failure_threshold"`
+	SuccessThreshold int `yaml:"success_threshold"`
+	// HTTP - внешние HTTP зависимости, проверяются GET запросом
+	HTTP []HTTPDependency `yaml:"http"`
+}
+
+type HTTPDependency struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	// Critical - недоступная зависимость переводит под в down, иначе только отображается в /readiness
+	Critical bool `yaml:"critical"`
+	// Timeout - 0 - readiness.timeout
+	Timeout time.Duration `yaml:"timeout"`
 }
 
 type DB struct {
 	RefreshTimeout time.Duration `yaml:"refresh_timeout"`
+
+	MaxConns          int32         `yaml:"max_conns"`
+	MinConns          int32         `yaml:"min_conns"`
+	MaxConnLifetime   time.Duration `yaml:"max_conn_lifetime"`
+	MaxConnIdleTime   time.Duration `yaml:"max_conn_idle_time"`
+	HealthCheckPeriod time.Duration `yaml:"health_check_period"`
+	StatementTimeout  time.Duration `yaml:"statement_timeout"`
+	// ApplicationName - имя в pg_stat_activity, по умолчанию app.name
+	ApplicationName string `yaml:"application_name"`
+
+	SlowQuery SlowQuery `yaml:"slow_query"`
+	// Replicas - host или host:port реплик, учетные данные берутся из DSN primary
+	Replicas []string `yaml:"replicas"`
+	// AutoMigrate - применять вшитые миграции при старте, до того как сервис станет готов
+	AutoMigrate bool `yaml:"auto_migrate"`
+}
+
+type SlowQuery struct {
+	// Threshold - запросы дольше пишутся в лог на уровне warn, 0 выключает лог
+	Threshold time.Duration `yaml:"threshold"`
+	// Args - redact, truncate или omit
+	Args         string `yaml:"args"`
+	MaxArgLength int    `yaml:"max_arg_length"`
+}
+
+type Partitions struct {
+	// Premake - на сколько месяцев вперед создавать партиции
+	Premake int `yaml:"premake"`
+	// Retention - сколько месяцев хранить партиции, 0 - хранить всегда
+	Retention       int    `yaml:"retention"`
+	RetentionPolicy string `yaml:"retention_policy"`
+}
+
+type Links struct {
+	// BaseURL - адрес сервиса, к которому добавляется ключ короткой ссылки
+	BaseURL         string `yaml:"base_url"`
+	ExpireBatchSize int    `yaml:"expire_batch_size"`
+	// MaxPageSize - ограничение на размер страницы при получении списка ссылок
+	MaxPageSize int         `yaml:"max_page_size"`
+	Batch       LinksBatch  `yaml:"batch"`
+	Export      LinksExport `yaml:"export"`
+	// FallbackCacheSize - сколько последних отданных редиректов держать в памяти,
+	// чтобы отдавать их, пока база недоступна. 0 - без кэша
+	FallbackCacheSize int `yaml:"fallback_cache_size"`
+}
+
+// LinksExport - выгрузка ссылок курсором
+type LinksExport struct {
+	// FetchSize - сколько строк читается из курсора за раз, определяет расход памяти
+	FetchSize int `yaml:"fetch_size"`
+	// ChunkTimeout - на сколько продлевается дедлайн ответа после каждой порции строк
+	ChunkTimeout time.Duration `yaml:"chunk_timeout"`
+	// MaxConcurrent - сколько выгрузок может идти одновременно, каждая держит соединение на все время скачивания.
+	// 0 - без ограничения
+	MaxConcurrent int `yaml:"max_concurrent"`
+}
+
+// LinksBatch - ограничения пакетного создания ссылок
+type LinksBatch struct {
+	MaxRows      int   `yaml:"max_rows"`
+	MaxBodyBytes int64 `yaml:"max_body_bytes"`
+	// ChunkSize - сколько строк вставляется одной транзакцией
+	ChunkSize int `yaml:"chunk_size"`
+	// ChunkTimeout - на сколько продлевается дедлайн запроса на каждую часть, общий WriteTimeout сервера
+	// рассчитан на обычные запросы
+	ChunkTimeout time.Duration `yaml:"chunk_timeout"`
+}
+
+type Webhooks struct {
+	PollInterval time.Duration `yaml:"poll_interval"`
+	BatchSize    int           `yaml:"batch_size"`
+	Timeout      time.Duration `yaml:"timeout"`
+	// MaxAttempts - после стольких неудачных попыток доставка переходит в статус dead
+	MaxAttempts     int           `yaml:"max_attempts"`
+	InitialInterval time.Duration `yaml:"initial_interval"`
+	MaxInterval     time.Duration `yaml:"max_interval"`
+	// SubscribedTTL - сколько помнить, есть ли подписчики на событие, 0 - проверять каждый раз.
+	// Новая подписка на другой реплике начинает получать события не позже чем через этот интервал
+	SubscribedTTL time.Duration `yaml:"subscribed_ttl"`
+}
+
+type Outbox struct {
+	PollInterval time.Duration `yaml:"poll_interval"`
+	BatchSize    int           `yaml:"batch_size"`
+	// Lease - на сколько забранная пачка скрывается от других реплик, за это время обработчики
+	// должны закончить, иначе событие будет доставлено повторно
+	Lease           time.Duration `yaml:"lease"`
+	InitialInterval time.Duration `yaml:"initial_interval"`
+	MaxInterval     time.Duration `yaml:"max_interval"`
+}
+
+type Scheduler struct {
+	// Jitter - случайная задержка срабатывания, размазывает нагрузку от реплик
+	Jitter time.Duration `yaml:"jitter"`
+	// RunTimeout - предельная длительность запуска. Пока запуск не завершен и не старше RunTimeout,
+	// следующие срабатывания задачи пропускаются на всех репликах
+	RunTimeout time.Duration `yaml:"run_timeout"`
+	// Tasks - cron-выражение по имени задачи, задача без расписания не запускается
+	Tasks map[string]string `yaml:"tasks"`
+}
+
+type Jobs struct {
+	Workers      int           `yaml:"workers"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// VisibilityTimeout - lease задачи, воркер продлевает его каждые VisibilityTimeout/2
+	VisibilityTimeout time.Duration `yaml:"visibility_timeout"`
+	// MaxAttempts - значение по умолчанию, после стольких неудачных попыток задача переходит в статус dead
+	MaxAttempts     int           `yaml:"max_attempts"`
+	InitialInterval time.Duration `yaml:"initial_interval"`
+	MaxInterval     time.Duration `yaml:"max_interval"`
+	// ShutdownTimeout - сколько ждать выполняющиеся задачи при остановке
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// Retention - сколько хранить завершенные задачи, 0 - не удалять. Чистка - задача планировщика jobs.purge
+	Retention time.Duration `yaml:"retention"`
+}
+
+type Imports struct {
+	MaxBodyBytes int64 `yaml:"max_body_bytes"`
+	// ProgressEvery - через сколько строк сохранять прогресс, с него импорт продолжается после повтора задачи
+	ProgressEvery int `yaml:"progress_every"`
+	// MaxIssues - сколько проблемных строк сохранять в отчете, счетчики считают все
+	MaxIssues int `yaml:"max_issues"`
+}
+
+type Snapshot struct {
+	// Dir - каталог файлов снапшота. Задача планировщика выполняется на одной реплике,
+	// поэтому для fallback на всех репликах это должен быть общий том
+	Dir string `yaml:"dir"`
+	// Fallback - отдавать редиректы из снапшота, когда база недоступна
+	Fallback bool `yaml:"fallback"`
+}
+
+type Clicks struct {
+	// QueueSize - размер очереди кликов между редиректом и записью в базу
+	QueueSize int `yaml:"queue_size"`
+	// ShutdownTimeout - сколько при остановке дописывать в базу уже принятые клики
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// WriteTimeout - сколько ждать записи одного клика, пока база недоступна
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	Stream       Stream        `yaml:"stream"`
+}
+
+type Stream struct {
+	Heartbeat time.Duration `yaml:"heartbeat"`
+	// BufferSize - сколько событий может накопить одно SSE соединение, после чего оно закрывается
+	BufferSize int `yaml:"buffer_size"`
+	// RingSize - сколько последних событий хранится для возобновления по Last-Event-ID
+	RingSize int `yaml:"ring_size"`
 }
 
 type Web struct {
@@ -1,6 +1,27 @@
 package domain
 
+import "time"
+
 type Link struct {
-	ShortURL string
-	Original string
+	ID        int32      `json:"-"`
+	Key       string     `json:"key"`
+	URL       string     `json:"url"`
+	Owner     string     `json:"owner,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func (l *Link) Expired(now time.Time) bool {
+	return l.ExpiresAt != nil && !l.ExpiresAt.After(now)
+}
+
+type Click struct {
+	LinkID    int32     `json:"-"`
+	Key       string    `json:"key"`
+	Owner     string    `json:"owner,omitempty"`
+	ClickedAt time.Time `json:"clicked_at"`
+	Referer   string    `json:"referer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	IP        string    `json:"-"`
 }
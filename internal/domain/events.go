@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventLinkCreated EventType = "link.created"
+	EventLinkUpdated EventType = "link.updated"
+	EventLinkDeleted EventType = "link.deleted"
+	EventLinkExpired EventType = "link.expired"
+	EventLinkClicked EventType = "link.clicked"
+)
+
+var EventTypes = []EventType{
+	EventLinkCreated,
+	EventLinkUpdated,
+	EventLinkDeleted,
+	EventLinkExpired,
+	EventLinkClicked,
+}
+
+func (t EventType) Valid() bool {
+	for _, et := range EventTypes {
+		if et == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Event - доменное событие, Data уже сериализована, чтобы событие можно было сохранить и переслать как есть
+type Event struct {
+	ID         string          `json:"id"`
+	Type       EventType       `json:"type"`
+	Owner      string          `json:"-"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+func NewEvent(eventType EventType, owner string, data any) (Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+
+	id := make([]byte, 16)
+	if _, err = rand.Read(id); err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		ID:         hex.EncodeToString(id),
+		Type:       eventType,
+		Owner:      owner,
+		OccurredAt: time.Now().UTC(),
+		Data:       raw,
+	}, nil
+}
@@ -0,0 +1,48 @@
+package domain
+
+import "time"
+
+type ImportStatus string
+
+const (
+	ImportPending ImportStatus = "pending"
+	ImportRunning ImportStatus = "running"
+	ImportDone    ImportStatus = "done"
+	ImportFailed  ImportStatus = "failed"
+)
+
+type ImportIssueKind string
+
+const (
+	// ImportIssueInvalid - строка не импортирована: нет url, url или число кликов некорректны
+	ImportIssueInvalid ImportIssueKind = "invalid"
+	// ImportIssueConflict - ключ уже занят ссылкой на другой url, строка не импортирована
+	ImportIssueConflict ImportIssueKind = "conflict"
+	// ImportIssueKeyReplaced - ключ не подходит под правила, ссылка создана с новым ключом
+	ImportIssueKeyReplaced ImportIssueKind = "key_replaced"
+)
+
+type ImportIssue struct {
+	Line    int             `json:"line"`
+	Key     string          `json:"key,omitempty"`
+	Kind    ImportIssueKind `json:"kind"`
+	Message string          `json:"message"`
+}
+
+// Import - импорт ссылок из выгрузки другого сокращателя
+type Import struct {
+	ID         int64
+	Source     string
+	Owner      string
+	Status     ImportStatus
+	Total      int
+	Processed  int
+	Created    int
+	Skipped    int
+	Failed     int
+	Issues     []ImportIssue
+	LastError  *string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	FinishedAt *time.Time
+}
@@ -0,0 +1,73 @@
+package domain
+
+import "time"
+
+// ReadinessStatus - готовность приложения принимать трафик
+type ReadinessStatus string
+
+const (
+	// ReadinessReady - все зависимости доступны
+	ReadinessReady ReadinessStatus = "ready"
+	// ReadinessDegraded - база недоступна, редиректы отдаются из кэша и снапшота, запись отклоняется
+	ReadinessDegraded ReadinessStatus = "degraded"
+	// ReadinessDown - приложение не может обслуживать запросы
+	ReadinessDown ReadinessStatus = "down"
+	// ReadinessStarting - первая проверка готовности еще не завершена
+	ReadinessStarting ReadinessStatus = "starting"
+	// ReadinessDraining - получен сигнал остановки, под выводится из балансировки до остановки серверов
+	ReadinessDraining ReadinessStatus = "draining"
+)
+
+// LifecycleState - этап жизненного цикла приложения
+type LifecycleState string
+
+const (
+	// LifecycleStarting - сервисы запускаются, первая проверка готовности не завершена
+	LifecycleStarting LifecycleState = "starting"
+	// LifecycleReady - приложение запущено и база доступна. Упавшая критичная проверка
+	// отражается в готовности, а не в состоянии
+	LifecycleReady LifecycleState = "ready"
+	// LifecycleDegraded - приложение запущено, база недоступна
+	LifecycleDegraded LifecycleState = "degraded"
+	// LifecycleDraining - получен сигнал остановки, серверы еще принимают запросы
+	LifecycleDraining LifecycleState = "draining"
+	// LifecycleStopped - сервисы и серверы остановлены
+	LifecycleStopped LifecycleState = "stopped"
+)
+
+// Liveness - жив ли главный цикл приложения
+type Liveness struct {
+	Status        string         `json:"status"`
+	State         LifecycleState `json:"state"`
+	LastHeartbeat *time.Time     `json:"last_heartbeat,omitempty"`
+	Error         string         `json:"error,omitempty"`
+}
+
+// CheckStatus - состояние проверки зависимости после подавления флаппинга
+type CheckStatus string
+
+const (
+	CheckPass CheckStatus = "pass"
+	CheckFail CheckStatus = "fail"
+)
+
+// CheckResult - состояние одной проверки готовности
+type CheckResult struct {
+	Name   string      `json:"name"`
+	Status CheckStatus `json:"status"`
+	// Critical - упавшая проверка переводит приложение в down, некритичная только отображается
+	Critical    bool       `json:"critical"`
+	LatencyMS   int64      `json:"latency_ms"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+	// Since - с какого момента проверка в текущем состоянии
+	Since *time.Time `json:"since,omitempty"`
+}
+
+// Readiness - итог проверки готовности и состояние отдельных проверок
+type Readiness struct {
+	Status ReadinessStatus `json:"status"`
+	State  LifecycleState  `json:"state"`
+	Error  string          `json:"error,omitempty"`
+	Checks []CheckResult   `json:"checks"`
+}
@@ -0,0 +1,180 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sshlykov/shortener/pkg/postgres"
+)
+
+// Link - ссылка в том виде, в каком она попадает в архив. Clicks - перенесенные клики вместе с записанными
+type Link struct {
+	LinkID    int32      `db:"link_id"`
+	Key       string     `db:"key"`
+	URL       string     `db:"url"`
+	Owner     string     `db:"owner"`
+	CreatedAt time.Time  `db:"created_at"`
+	ExpiresAt *time.Time `db:"expires_at"`
+	ExpiredAt *time.Time `db:"expired_at"`
+	Clicks    int64      `db:"clicks"`
+}
+
+type Subscription struct {
+	URL       string    `db:"url"`
+	Secret    string    `db:"secret"`
+	Events    []string  `db:"events"`
+	Owner     string    `db:"owner"`
+	Active    bool      `db:"active"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// ExistingLink - ссылка в базе, занимающая ключ из архива
+type ExistingLink struct {
+	Key   string `db:"key"`
+	URL   string `db:"url"`
+	Owner string `db:"owner"`
+}
+
+type Repository struct {
+	db postgres.DB
+}
+
+func New(db postgres.Client) *Repository {
+	return &Repository{db: db.DB()}
+}
+
+const getSchemaVersion = `SELECT COALESCE(max(version_id), 0) FROM goose_db_version`
+
+func (r *Repository) SchemaVersion(ctx context.Context) (int64, error) {
+	var version int64
+	q := postgres.Query{Name: "backup.schema_version", Raw: getSchemaVersion, ReadOnly: true}
+	err := r.db.QueryRowContext(ctx, q).Scan(&version)
+
+	return version, err
+}
+
+const declareLinks = `
+DECLARE links_backup NO SCROLL CURSOR FOR
+SELECT link_id, key, url, owner, created_at, expires_at, expired_at,
+       imported_clicks + (SELECT count(*) FROM clicks c WHERE c.link_id = links.link_id) AS clicks
+FROM links
+ORDER BY link_id`
+
+// DeclareLinks открывает курсор по всем ссылкам, вызывается внутри транзакции
+func (r *Repository) DeclareLinks(ctx context.Context) error {
+	q := postgres.Query{Name: "backup.links_declare", Raw: declareLinks}
+	_, err := r.db.ExecContext(ctx, q)
+
+	return err
+}
+
+// FetchLinks читает следующие n строк курсора
+func (r *Repository) FetchLinks(ctx context.Context, n int) ([]Link, error) {
+	var links []Link
+	q := postgres.Query{Name: "backup.links_fetch", Raw: fmt.Sprintf("FETCH FORWARD %d FROM links_backup", n)}
+	if err := r.db.ScanAllContext(ctx, q, &links); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+const listSubscriptions = `
+SELECT url, secret, events, owner, active, created_at
+FROM webhook_subscriptions
+ORDER BY subscription_id`
+
+func (r *Repository) Subscriptions(ctx context.Context) ([]Subscription, error) {
+	var subs []Subscription
+	q := postgres.Query{Name: "backup.subscriptions", Raw: listSubscriptions}
+	if err := r.db.ScanAllContext(ctx, q, &subs); err != nil {
+		return nil, err
+	}
+
+	return subs, nil
+}
+
+const getLinksByKeys = `
+SELECT key, url, owner
+FROM links
+WHERE key = ANY ($1)`
+
+func (r *Repository) LinksByKeys(ctx context.Context, keys []string) ([]ExistingLink, error) {
+	var links []ExistingLink
+	q := postgres.Query{Name: "backup.links_by_keys", Raw: getLinksByKeys}
+	if err := r.db.ScanAllContext(ctx, q, &links, keys); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+const nextLinkIDs = `SELECT nextval(pg_get_serial_sequence('links', 'link_id'))::int FROM generate_series(1, $1)`
+
+func (r *Repository) NextIDs(ctx context.Context, n int) ([]int32, error) {
+	var ids []int32
+	q := postgres.Query{Name: "backup.next_link_ids", Raw: nextLinkIDs}
+	if err := r.db.ScanAllContext(ctx, q, &ids, n); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+const restoreLink = `
+INSERT INTO links (link_id, key, url, owner, created_at, updated_at, expires_at, expired_at, imported_clicks)
+VALUES ($1, $2, $3, $4, $5, $5, $6, $7, $8)
+ON CONFLICT (key) DO NOTHING
+RETURNING link_id`
+
+// InsertLinks вставляет ссылки одним батчем. Для занятого ключа в результате false,
+// поэтому конфликт одной строки не прерывает транзакцию
+func (r *Repository) InsertLinks(ctx context.Context, links []Link) ([]bool, error) {
+	inserted := make([]bool, len(links))
+	q := postgres.Query{Name: "backup.insert_link", Raw: restoreLink}
+
+	b := postgres.NewBatch("backup.insert_links")
+	for i, link := range links {
+		b.QueueRow(q, func(row pgx.Row) error {
+			var id int32
+			err := row.Scan(&id)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil
+			}
+			inserted[i] = err == nil
+			return err
+		}, link.LinkID, link.Key, link.URL, link.Owner, link.CreatedAt, link.ExpiresAt, link.ExpiredAt, link.Clicks)
+	}
+
+	results, err := r.db.SendBatchContext(ctx, b)
+	if err == nil {
+		err = postgres.BatchErr(results)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return inserted, nil
+}
+
+const restoreSubscription = `
+INSERT INTO webhook_subscriptions (url, secret, events, owner, active, created_at)
+SELECT $1, $2, $3, $4, $5, $6
+WHERE NOT EXISTS (SELECT 1 FROM webhook_subscriptions WHERE owner = $4 AND url = $1)
+RETURNING subscription_id`
+
+// InsertSubscription восстанавливает подписку, если у владельца нет подписки на тот же url
+func (r *Repository) InsertSubscription(ctx context.Context, sub Subscription) (bool, error) {
+	var id int64
+	q := postgres.Query{Name: "backup.insert_subscription", Raw: restoreSubscription}
+	err := r.db.QueryRowContext(ctx, q, sub.URL, sub.Secret, sub.Events, sub.Owner, sub.Active, sub.CreatedAt).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	archiveFormat = "shortener-backup"
+	// ArchiveVersion - версия формата архива. Restore читает архивы версии не выше своей,
+	// новые поля записей добавляются без смены версии, несовместимые изменения ее повышают
+	ArchiveVersion = 1
+
+	manifestName = "manifest.json"
+	linksName    = "links.ndjson"
+	webhooksName = "webhooks.ndjson"
+)
+
+// Manifest описывает архив: версию формата, версию схемы базы на момент выгрузки и файлы с контрольными суммами
+type Manifest struct {
+	Format        string         `json:"format"`
+	Version       int            `json:"version"`
+	CreatedAt     time.Time      `json:"created_at"`
+	SchemaVersion int64          `json:"schema_version"`
+	Files         []ManifestFile `json:"files"`
+}
+
+type ManifestFile struct {
+	Name    string `json:"name"`
+	Records int    `json:"records"`
+	Size    int64  `json:"size"`
+	SHA256  string `json:"sha256"`
+}
+
+func (m *Manifest) file(name string) (ManifestFile, bool) {
+	for _, f := range m.Files {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return ManifestFile{}, false
+}
+
+// linkRecord - строка links.ndjson. Clicks переносится в imported_clicks: сами клики в архив не входят
+type linkRecord struct {
+	Key       string     `json:"key"`
+	URL       string     `json:"url"`
+	Owner     string     `json:"owner,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	ExpiredAt *time.Time `json:"expired_at,omitempty"`
+	Clicks    int64      `json:"clicks,omitempty"`
+}
+
+// webhookRecord - строка webhooks.ndjson, подписка владельца на события вместе с секретом подписи
+type webhookRecord struct {
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret"`
+	Events    []string  `json:"events"`
+	Owner     string    `json:"owner,omitempty"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// section пишет записи одного файла архива во временный файл, считая размер и контрольную сумму.
+// Размер записи tar нужен заранее, поэтому файлы архива сначала собираются на диске
+type section struct {
+	name    string
+	file    *os.File
+	buf     *bufio.Writer
+	hash    hash.Hash
+	size    int64
+	records int
+}
+
+func newSection(dir, name string) (*section, error) {
+	file, err := os.CreateTemp(dir, ".backup-*-"+name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &section{name: name, file: file, buf: bufio.NewWriter(file), hash: sha256.New()}, nil
+}
+
+func (s *section) add(record any) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	_, _ = s.hash.Write(line)
+	s.size += int64(len(line))
+	s.records++
+	_, err = s.buf.Write(line)
+
+	return err
+}
+
+func (s *section) manifest() ManifestFile {
+	return ManifestFile{Name: s.name, Records: s.records, Size: s.size, SHA256: hex.EncodeToString(s.hash.Sum(nil))}
+}
+
+func (s *section) close() {
+	_ = s.file.Close()
+	_ = os.Remove(s.file.Name())
+}
+
+// writeArchive пишет tar.gz: первым manifest.json, за ним файлы секций
+func writeArchive(w io.Writer, manifest *Manifest, sections []*section) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	header := &tar.Header{Name: manifestName, Mode: 0o600, Size: int64(len(data)), ModTime: manifest.CreatedAt}
+	if err = tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err = tw.Write(data); err != nil {
+		return err
+	}
+
+	for _, s := range sections {
+		if err = s.buf.Flush(); err != nil {
+			return err
+		}
+		if _, err = s.file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		header = &tar.Header{Name: s.name, Mode: 0o600, Size: s.size, ModTime: manifest.CreatedAt}
+		if err = tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err = io.Copy(tw, s.file); err != nil {
+			return err
+		}
+	}
+
+	if err = tw.Close(); err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+// readArchive читает манифест и передает в fn остальные файлы архива в порядке записи
+func readArchive(r io.Reader, fn func(manifest *Manifest, file ManifestFile, body io.Reader) error) (*Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	header, err := tr.Next()
+	if err != nil || header.Name != manifestName {
+		return nil, fmt.Errorf("%w: %s should be the first file", ErrInvalidArchive, manifestName)
+	}
+	var manifest Manifest
+	if err = json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidArchive, manifestName, err)
+	}
+	if manifest.Format != archiveFormat {
+		return nil, fmt.Errorf("%w: format is %q", ErrInvalidArchive, manifest.Format)
+	}
+	if manifest.Version < 1 || manifest.Version > ArchiveVersion {
+		return nil, fmt.Errorf("%w: %d, supported up to %d", ErrUnsupportedFormat, manifest.Version, ArchiveVersion)
+	}
+
+	for {
+		header, err = tr.Next()
+		if errors.Is(err, io.EOF) {
+			return &manifest, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+		}
+
+		file, ok := manifest.file(header.Name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s is not listed in the manifest", ErrInvalidArchive, header.Name)
+		}
+		if err = fn(&manifest, file, tr); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Verify проверяет архив целиком: манифест, наличие файлов, размеры, число записей и контрольные суммы
+func Verify(r io.Reader) (*Manifest, error) {
+	seen := make(map[string]bool)
+	manifest, err := readArchive(r, func(_ *Manifest, file ManifestFile, body io.Reader) error {
+		h := sha256.New()
+		counter := &lineCounter{}
+		size, err := io.Copy(io.MultiWriter(h, counter), body)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidArchive, file.Name, err)
+		}
+		if size != file.Size || counter.lines != file.Records || hex.EncodeToString(h.Sum(nil)) != file.SHA256 {
+			return fmt.Errorf("%w: %s", ErrChecksumMismatch, file.Name)
+		}
+		seen[file.Name] = true
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range manifest.Files {
+		if !seen[file.Name] {
+			return nil, fmt.Errorf("%w: %s is missing", ErrInvalidArchive, file.Name)
+		}
+	}
+
+	return manifest, nil
+}
+
+type lineCounter struct {
+	lines int
+}
+
+func (c *lineCounter) Write(p []byte) (int, error) {
+	c.lines += bytes.Count(p, []byte{'\n'})
+	return len(p), nil
+}
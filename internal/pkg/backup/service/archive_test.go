@@ -0,0 +1,121 @@
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func buildArchive(t *testing.T, manifest *Manifest, records ...linkRecord) []byte {
+	t.Helper()
+
+	links, err := newSection(t.TempDir(), linksName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer links.close()
+	for _, r := range records {
+		if err = links.add(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manifest.Files = []ManifestFile{links.manifest()}
+
+	var buf bytes.Buffer
+	if err = writeArchive(&buf, manifest, []*section{links}); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func newManifest() *Manifest {
+	return &Manifest{Format: archiveFormat, Version: ArchiveVersion, CreatedAt: time.Now().UTC(), SchemaVersion: 1}
+}
+
+func TestVerify(t *testing.T) {
+	data := buildArchive(t, newManifest(),
+		linkRecord{Key: "docs", URL: "https://example.com/docs"},
+		linkRecord{Key: "blog", URL: "https://example.com/blog", Clicks: 3},
+	)
+
+	manifest, err := Verify(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if f, ok := manifest.file(linksName); !ok || f.Records != 2 {
+		t.Errorf("manifest files = %+v", manifest.Files)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	future := newManifest()
+	future.Version = ArchiveVersion + 1
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{name: "not gzip", data: []byte("links"), want: ErrInvalidArchive},
+		{name: "newer version", data: buildArchive(t, future), want: ErrUnsupportedFormat},
+		{name: "tampered", data: tamper(t, buildArchive(t, newManifest(), linkRecord{Key: "docs", URL: "https://a.io"})),
+			want: ErrChecksumMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Verify(bytes.NewReader(tt.data)); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// tamper меняет байт в links.ndjson, не трогая манифест
+func tamper(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+
+	var out bytes.Buffer
+	gw := gzip.NewWriter(&out)
+	tw := tar.NewWriter(gw)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(tr)
+		if header.Name == linksName {
+			body = bytes.Replace(body, []byte("a.io"), []byte("b.io"), 1)
+		}
+		_ = tw.WriteHeader(header)
+		_, _ = tw.Write(body)
+	}
+	_ = tw.Close()
+	_ = gw.Close()
+
+	return out.Bytes()
+}
+
+func TestSuffixKey(t *testing.T) {
+	if got := suffixKey("docs", 2); got != "docs-2" {
+		t.Errorf("suffixKey() = %q", got)
+	}
+	long := "abcdefghijklmnopqrstuvwxyz012345"
+	if got := suffixKey(long, 10); len(got) != len(long) || got[len(got)-3:] != "-10" {
+		t.Errorf("suffixKey() = %q, want trimmed to %d chars", got, len(long))
+	}
+}
@@ -0,0 +1,12 @@
+package service
+
+import "errors"
+
+var (
+	ErrInvalidArchive    = errors.New("invalid backup archive")
+	ErrUnsupportedFormat = errors.New("unsupported backup archive version")
+	ErrChecksumMismatch  = errors.New("backup archive checksum mismatch")
+	ErrUnknownPolicy     = errors.New("unknown conflict policy, expected fail, skip, generate or suffix")
+	ErrKeyConflict       = errors.New("key is taken by a link to another url")
+	ErrNoFreeKey         = errors.New("no free key for the link")
+)
@@ -0,0 +1,364 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	repository "github.com/sshlykov/shortener/internal/pkg/backup/repo"
+	linksrv "github.com/sshlykov/shortener/internal/pkg/links/service"
+	shorten "github.com/sshlykov/shortener/internal/pkg/shorten/service"
+	"github.com/sshlykov/shortener/pkg/logger"
+)
+
+const (
+	restoreChunkSize = 500
+	// remapAttempts ограничивает подбор свободного ключа для одной ссылки
+	remapAttempts = 10
+)
+
+// ConflictPolicy - что делать со ссылкой из архива, ключ которой занят ссылкой на другой url или другого владельца.
+// Ссылка с тем же ключом, url и владельцем считается уже восстановленной и пропускается при любой политике
+type ConflictPolicy string
+
+const (
+	// ConflictFail прерывает восстановление на первом конфликте
+	ConflictFail ConflictPolicy = "fail"
+	// ConflictSkip оставляет ссылку из базы, ссылка из архива не восстанавливается
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictGenerate восстанавливает ссылку с новым сгенерированным ключом
+	ConflictGenerate ConflictPolicy = "generate"
+	// ConflictSuffix восстанавливает ссылку с ключом key-2, key-3 и т.д.
+	ConflictSuffix ConflictPolicy = "suffix"
+)
+
+func ParseConflictPolicy(raw string) (ConflictPolicy, error) {
+	switch policy := ConflictPolicy(strings.ToLower(strings.TrimSpace(raw))); policy {
+	case ConflictFail, ConflictSkip, ConflictGenerate, ConflictSuffix:
+		return policy, nil
+	default:
+		return "", ErrUnknownPolicy
+	}
+}
+
+type RestoreOptions struct {
+	// Apply фиксирует восстановление, без него выполняется пробный прогон
+	Apply      bool
+	OnConflict ConflictPolicy
+}
+
+type RestoreCounts struct {
+	Total    int `json:"total"`
+	Created  int `json:"created"`
+	Existing int `json:"existing"`
+	Remapped int `json:"remapped,omitempty"`
+	Skipped  int `json:"skipped,omitempty"`
+	Invalid  int `json:"invalid,omitempty"`
+}
+
+type KeyRemap struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type RestoreReport struct {
+	DryRun     bool           `json:"dry_run"`
+	OnConflict ConflictPolicy `json:"on_conflict"`
+	Manifest   *Manifest      `json:"manifest"`
+	Links      RestoreCounts  `json:"links"`
+	Webhooks   RestoreCounts  `json:"webhooks"`
+	Remapped   []KeyRemap     `json:"remapped,omitempty"`
+	// Conflicts - ключи ссылок, не восстановленных из-за конфликта
+	Conflicts []string `json:"conflicts,omitempty"`
+}
+
+var errDryRun = errors.New("dry run")
+
+// Restore восстанавливает архив из path одной транзакцией, в пустую или в рабочую базу.
+// Архив проверяется целиком до первой записи в базу. Пробный прогон выполняет те же запросы
+// и откатывает транзакцию, поэтому отчет совпадает с реальным восстановлением, кроме
+// сгенерированных ключей: значения последовательности при откате не возвращаются.
+// Восстановленные ссылки не публикуют событий в вебхуки
+func (s *Service) Restore(ctx context.Context, path string, opts RestoreOptions) (*RestoreReport, error) {
+	if opts.OnConflict == "" {
+		opts.OnConflict = ConflictFail
+	}
+
+	manifest, err := verifyFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var report *RestoreReport
+	err = s.tx.ReadCommitted(ctx, func(ctx context.Context) error {
+		report = &RestoreReport{DryRun: !opts.Apply, OnConflict: opts.OnConflict, Manifest: manifest}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		_, err := readArchive(file, func(_ *Manifest, f ManifestFile, body io.Reader) error {
+			switch f.Name {
+			case linksName:
+				return s.restoreLinks(ctx, body, opts.OnConflict, report)
+			case webhooksName:
+				return s.restoreWebhooks(ctx, body, report)
+			default:
+				// файл из более новой версии того же формата
+				return nil
+			}
+		})
+		if err == nil && !opts.Apply {
+			return errDryRun
+		}
+
+		return err
+	})
+	if errors.Is(err, errDryRun) {
+		return report, nil
+	}
+	if err != nil {
+		logger.Error(ctx, "Restore", logger.Err(err), logger.Any("path", path))
+
+		return report, err
+	}
+
+	return report, nil
+}
+
+func verifyFile(path string) (*Manifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Verify(file)
+}
+
+func (s *Service) restoreLinks(ctx context.Context, body io.Reader, policy ConflictPolicy, report *RestoreReport) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	chunk := make([]linkRecord, 0, restoreChunkSize)
+	for scanner.Scan() {
+		var record linkRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidArchive, linksName, err)
+		}
+		report.Links.Total++
+		if record.Key == "" || record.URL == "" {
+			report.Links.Invalid++
+			continue
+		}
+
+		chunk = append(chunk, record)
+		if len(chunk) == restoreChunkSize {
+			if err := s.restoreChunk(ctx, chunk, policy, report); err != nil {
+				return err
+			}
+			chunk = chunk[:0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrInvalidArchive, linksName, err)
+	}
+
+	return s.restoreChunk(ctx, chunk, policy, report)
+}
+
+// pendingLink - ссылка из архива и ключ, под которым она будет вставлена. Пустой ключ генерируется из id
+type pendingLink struct {
+	record linkRecord
+	key    string
+	suffix int
+}
+
+// restoreChunk вставляет часть ссылок. Занятость ключей проверяется заранее, чтобы применить политику,
+// а вставка идет с ON CONFLICT DO NOTHING: ключ может занять ссылка, восстановленная раньше с новым ключом.
+// Такие ссылки проходят проверку заново
+func (s *Service) restoreChunk(ctx context.Context, chunk []linkRecord, policy ConflictPolicy, report *RestoreReport) error {
+	pending := make([]*pendingLink, len(chunk))
+	for i, record := range chunk {
+		pending[i] = &pendingLink{record: record, key: record.Key}
+	}
+
+	for attempt := 0; attempt < remapAttempts && len(pending) > 0; attempt++ {
+		existing, err := s.existing(ctx, pending)
+		if err != nil {
+			return err
+		}
+
+		var insert, retry []*pendingLink
+		for _, p := range pending {
+			taken, ok := existing[p.key]
+			switch {
+			case p.key == "" || !ok:
+				insert = append(insert, p)
+			case p.key == p.record.Key && taken.URL == p.record.URL && taken.Owner == p.record.Owner:
+				report.Links.Existing++
+			case p.key != p.record.Key:
+				// занят ключ, подобранный вместо исходного
+				p.remap()
+				retry = append(retry, p)
+			case policy == ConflictFail:
+				return fmt.Errorf("%w: %s", ErrKeyConflict, p.record.Key)
+			case policy == ConflictSkip:
+				report.Links.Skipped++
+				report.Conflicts = append(report.Conflicts, p.record.Key)
+			case policy == ConflictGenerate:
+				p.key = ""
+				insert = append(insert, p)
+			default:
+				p.remap()
+				retry = append(retry, p)
+			}
+		}
+
+		lost, err := s.insertLinks(ctx, insert, report)
+		if err != nil {
+			return err
+		}
+		pending = append(retry, lost...)
+	}
+
+	for _, p := range pending {
+		if policy == ConflictFail {
+			return fmt.Errorf("%w: %s", ErrNoFreeKey, p.record.Key)
+		}
+		report.Links.Skipped++
+		report.Conflicts = append(report.Conflicts, p.record.Key)
+	}
+
+	return nil
+}
+
+func (s *Service) existing(ctx context.Context, pending []*pendingLink) (map[string]repository.ExistingLink, error) {
+	keys := make([]string, 0, len(pending))
+	for _, p := range pending {
+		if p.key != "" {
+			keys = append(keys, p.key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	links, err := s.repo.LinksByKeys(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]repository.ExistingLink, len(links))
+	for _, link := range links {
+		existing[link.Key] = link
+	}
+
+	return existing, nil
+}
+
+// insertLinks вставляет ссылки и возвращает те, чей ключ оказался занят
+func (s *Service) insertLinks(ctx context.Context, insert []*pendingLink, report *RestoreReport) ([]*pendingLink, error) {
+	if len(insert) == 0 {
+		return nil, nil
+	}
+
+	ids, err := s.repo.NextIDs(ctx, len(insert))
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]repository.Link, len(insert))
+	for i, p := range insert {
+		r := p.record
+		rows[i] = repository.Link{LinkID: ids[i], Key: p.key, URL: r.URL, Owner: r.Owner, CreatedAt: r.CreatedAt,
+			ExpiresAt: r.ExpiresAt, ExpiredAt: r.ExpiredAt, Clicks: r.Clicks}
+		if rows[i].Key == "" {
+			rows[i].Key = shorten.Shorten(uint32(ids[i]))
+		}
+	}
+
+	inserted, err := s.repo.InsertLinks(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	var lost []*pendingLink
+	for i, p := range insert {
+		if !inserted[i] {
+			// ключ заняла ссылка, восстановленная раньше с новым ключом, или сгенерированный ключ
+			// совпал с пользовательским - строка проходит проверку заново
+			lost = append(lost, p)
+			continue
+		}
+
+		report.Links.Created++
+		if rows[i].Key != p.record.Key {
+			report.Links.Remapped++
+			report.Remapped = append(report.Remapped, KeyRemap{From: p.record.Key, To: rows[i].Key})
+		}
+	}
+
+	return lost, nil
+}
+
+// remap подбирает следующий ключ по политике suffix: key-2, key-3 и т.д. в пределах длины ключа
+func (p *pendingLink) remap() {
+	if p.suffix == 0 {
+		p.suffix = 1
+	}
+	p.suffix++
+	p.key = suffixKey(p.record.Key, p.suffix)
+}
+
+func suffixKey(key string, n int) string {
+	suffix := "-" + strconv.Itoa(n)
+	if len(key)+len(suffix) > linksrv.MaxKeyLen {
+		key = key[:linksrv.MaxKeyLen-len(suffix)]
+	}
+
+	return key + suffix
+}
+
+func (s *Service) restoreWebhooks(ctx context.Context, body io.Reader, report *RestoreReport) error {
+	dec := json.NewDecoder(body)
+	for {
+		var record webhookRecord
+		err := dec.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidArchive, webhooksName, err)
+		}
+		report.Webhooks.Total++
+
+		created, err := s.repo.InsertSubscription(ctx, repository.Subscription{
+			URL:       record.URL,
+			Secret:    record.Secret,
+			Events:    record.Events,
+			Owner:     record.Owner,
+			Active:    record.Active,
+			CreatedAt: record.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		if created {
+			report.Webhooks.Created++
+		} else {
+			report.Webhooks.Existing++
+		}
+	}
+}
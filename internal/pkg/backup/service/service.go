@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	repository "github.com/sshlykov/shortener/internal/pkg/backup/repo"
+	"github.com/sshlykov/shortener/pkg/logger"
+	"github.com/sshlykov/shortener/pkg/postgres"
+)
+
+const fetchSize = 1000
+
+type Repository interface {
+	SchemaVersion(ctx context.Context) (int64, error)
+	DeclareLinks(ctx context.Context) error
+	FetchLinks(ctx context.Context, n int) ([]repository.Link, error)
+	Subscriptions(ctx context.Context) ([]repository.Subscription, error)
+	LinksByKeys(ctx context.Context, keys []string) ([]repository.ExistingLink, error)
+	NextIDs(ctx context.Context, n int) ([]int32, error)
+	InsertLinks(ctx context.Context, links []repository.Link) ([]bool, error)
+	InsertSubscription(ctx context.Context, sub repository.Subscription) (bool, error)
+}
+
+// Service делает переносимую резервную копию ссылок и подписок на вебхуки и восстанавливает ее.
+// Владельцы отдельной сущностью не хранятся и переносятся в полях ссылок и подписок
+type Service struct {
+	repo Repository
+	tx   postgres.TxManager
+}
+
+func New(db postgres.Client, tx postgres.TxManager) *Service {
+	return &Service{
+		repo: repository.New(db),
+		tx:   tx,
+	}
+}
+
+// Backup пишет архив в path. Данные читаются одной транзакцией repeatable read, поэтому ссылки
+// и подписки в архиве согласованы между собой. Архив сначала собирается во временный файл
+// рядом с path и переименовывается только целиком
+func (s *Service) Backup(ctx context.Context, path string) (*Manifest, error) {
+	dir := filepath.Dir(path)
+	links, err := newSection(dir, linksName)
+	if err != nil {
+		return nil, err
+	}
+	defer links.close()
+	webhooks, err := newSection(dir, webhooksName)
+	if err != nil {
+		return nil, err
+	}
+	defer webhooks.close()
+
+	manifest := &Manifest{Format: archiveFormat, Version: ArchiveVersion, CreatedAt: time.Now().UTC()}
+	err = s.tx.RepeatableRead(ctx, func(ctx context.Context) error {
+		var err error
+		if manifest.SchemaVersion, err = s.repo.SchemaVersion(ctx); err != nil {
+			return err
+		}
+		if err = s.backupLinks(ctx, links); err != nil {
+			return err
+		}
+
+		return s.backupWebhooks(ctx, webhooks)
+	})
+	if err != nil {
+		logger.Error(ctx, "Backup", logger.Err(err))
+
+		return nil, err
+	}
+	manifest.Files = []ManifestFile{links.manifest(), webhooks.manifest()}
+
+	if err = writeFile(path, manifest, []*section{links, webhooks}); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+func (s *Service) backupLinks(ctx context.Context, out *section) error {
+	if err := s.repo.DeclareLinks(ctx); err != nil {
+		return err
+	}
+
+	for {
+		rows, err := s.repo.FetchLinks(ctx, fetchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			err = out.add(linkRecord{
+				Key:       row.Key,
+				URL:       row.URL,
+				Owner:     row.Owner,
+				CreatedAt: row.CreatedAt,
+				ExpiresAt: row.ExpiresAt,
+				ExpiredAt: row.ExpiredAt,
+				Clicks:    row.Clicks,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if len(rows) < fetchSize {
+			return nil
+		}
+	}
+}
+
+func (s *Service) backupWebhooks(ctx context.Context, out *section) error {
+	subs, err := s.repo.Subscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range subs {
+		err = out.add(webhookRecord{
+			URL:       sub.URL,
+			Secret:    sub.Secret,
+			Events:    sub.Events,
+			Owner:     sub.Owner,
+			Active:    sub.Active,
+			CreatedAt: sub.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeFile пишет архив во временный файл и переименовывает его в path.
+// Архив содержит секреты подписок, поэтому доступен только владельцу
+func writeFile(path string, manifest *Manifest, sections []*section) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".backup-*.tar.gz")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = tmp.Chmod(0o600); err != nil {
+		return err
+	}
+	if err = writeArchive(tmp, manifest, sections); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,35 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/sshlykov/shortener/pkg/postgres"
+)
+
+type Click struct {
+	LinkID    int32
+	ClickedAt time.Time
+	Referer   string
+	UserAgent string
+	IP        string
+}
+
+type Repository struct {
+	db postgres.DB
+}
+
+func New(db postgres.Client) *Repository {
+	return &Repository{db: db.DB()}
+}
+
+const insertClick = `
+INSERT INTO clicks (link_id, clicked_at, referer, user_agent, ip)
+VALUES ($1, $2, nullif($3, ''), nullif($4, ''), nullif($5, '')::inet)`
+
+func (r *Repository) InsertClick(ctx context.Context, click Click) error {
+	q := postgres.Query{Name: "clicks.insert", Raw: insertClick}
+	_, err := r.db.ExecContext(ctx, q, click.LinkID, click.ClickedAt, click.Referer, click.UserAgent, click.IP)
+
+	return err
+}
@@ -0,0 +1,7 @@
+package service
+
+import "errors"
+
+var (
+	ErrQueueFull = errors.New("click queue is full")
+)
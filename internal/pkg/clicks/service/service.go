@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"net/netip"
+	"time"
+
+	"github.com/sshlykov/shortener/internal/config"
+	"github.com/sshlykov/shortener/internal/domain"
+	repository "github.com/sshlykov/shortener/internal/pkg/clicks/repo"
+	"github.com/sshlykov/shortener/internal/pkg/clicks/stream"
+	"github.com/sshlykov/shortener/pkg/logger"
+	"github.com/sshlykov/shortener/pkg/postgres"
+)
+
+type Repository interface {
+	InsertClick(ctx context.Context, click repository.Click) error
+}
+
+// Publisher пишет событие в outbox в транзакции сохранения клика
+type Publisher interface {
+	Publish(ctx context.Context, event domain.Event) error
+}
+
+// Subscribers сообщает, нужно ли кому-то событие. Клики частые, поэтому link.clicked
+// пишется в outbox, только если на него есть подписка
+type Subscribers interface {
+	Subscribed(ctx context.Context, event domain.EventType) bool
+}
+
+// Service - внутрипроцессный конвейер кликов: редирект кладет клик в очередь,
+// Run публикует его в поток для SSE подписчиков и сохраняет в базу вместе с событием в outbox, если на него подписаны
+type Service struct {
+	repo      Repository
+	tx        postgres.TxManager
+	publisher Publisher
+	// subscribers - nil, если событие пишется всегда
+	subscribers Subscribers
+	hub         *stream.Hub
+	queue       chan domain.Click
+	// shutdownTimeout - сколько дописывать очередь после отмены контекста
+	shutdownTimeout time.Duration
+	// writeTimeout - сколько ждать записи одного клика, 0 - без ограничения
+	writeTimeout time.Duration
+}
+
+func New(db postgres.Client, tx postgres.TxManager, cfg config.Clicks, publisher Publisher,
+	subscribers Subscribers) *Service {
+	return &Service{
+		repo:        repository.New(db),
+		tx:          tx,
+		publisher:   publisher,
+		subscribers: subscribers,
+		hub:         stream.NewHub(cfg.Stream.RingSize, cfg.Stream.BufferSize),
+		queue:       make(chan domain.Click, cfg.QueueSize),
+
+		shutdownTimeout: cfg.ShutdownTimeout,
+		writeTimeout:    cfg.WriteTimeout,
+	}
+}
+
+// Record ставит клик в очередь не блокируя редирект, при переполнении очереди клик теряется.
+// IP приходит из X-Forwarded-For и может быть невалидным, такой IP не сохраняется
+func (s *Service) Record(ctx context.Context, click domain.Click) {
+	if _, err := netip.ParseAddr(click.IP); err != nil {
+		click.IP = ""
+	}
+
+	select {
+	case s.queue <- click:
+	default:
+		logger.Warn(ctx, "click dropped", logger.Err(ErrQueueFull), logger.Any("key", click.Key))
+	}
+}
+
+// Run обрабатывает очередь до отмены контекста, затем дописывает принятые клики не дольше
+// shutdownTimeout и закрывает все подписки
+func (s *Service) Run(ctx context.Context) {
+	defer s.hub.Close()
+
+	// клики пишутся без отмены: принятый клик дописывается, даже если остановка пришла во время записи.
+	// Время записи ограничено writeTimeout, поэтому недоступная база не задерживает остановку дольше
+	workCtx := context.WithoutCancel(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			s.drain(workCtx)
+			return
+		case click := <-s.queue:
+			s.process(workCtx, click)
+		}
+	}
+}
+
+func (s *Service) drain(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, s.shutdownTimeout)
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Error(ctx, "clicks dropped on shutdown", logger.Err(ctx.Err()), logger.Any("count", len(s.queue)))
+			return
+		case click := <-s.queue:
+			s.process(ctx, click)
+		default:
+			return
+		}
+	}
+}
+
+// process сначала отдает клик SSE подписчикам, чтобы они не ждали базу, затем сохраняет его
+func (s *Service) process(ctx context.Context, click domain.Click) {
+	s.hub.Publish(click)
+
+	if s.writeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.writeTimeout)
+		defer cancel()
+	}
+
+	publish := s.subscribers == nil || s.subscribers.Subscribed(ctx, domain.EventLinkClicked)
+	err := s.tx.ReadCommitted(ctx, func(ctx context.Context) error {
+		err := s.repo.InsertClick(ctx, repository.Click{
+			LinkID:    click.LinkID,
+			ClickedAt: click.ClickedAt,
+			Referer:   click.Referer,
+			UserAgent: click.UserAgent,
+			IP:        click.IP,
+		})
+		if err != nil || !publish {
+			return err
+		}
+
+		event, err := domain.NewEvent(domain.EventLinkClicked, click.Owner, click)
+		if err != nil {
+			return err
+		}
+		return s.publisher.Publish(ctx, event)
+	})
+	if err != nil {
+		logger.Error(ctx, "InsertClick", logger.Err(err), logger.Any("key", click.Key))
+	}
+}
+
+func (s *Service) SubscribeLink(linkID int32, lastEventID uint64) (*stream.Subscription, []stream.Event, error) {
+	return s.hub.Subscribe(func(click domain.Click) bool { return click.LinkID == linkID }, lastEventID)
+}
+
+func (s *Service) SubscribeOwner(owner string, lastEventID uint64) (*stream.Subscription, []stream.Event, error) {
+	return s.hub.Subscribe(func(click domain.Click) bool { return click.Owner == owner }, lastEventID)
+}
+
+func (s *Service) Unsubscribe(sub *stream.Subscription) {
+	s.hub.Unsubscribe(sub)
+}
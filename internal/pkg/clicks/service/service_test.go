@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sshlykov/shortener/internal/config"
+	"github.com/sshlykov/shortener/internal/domain"
+	repository "github.com/sshlykov/shortener/internal/pkg/clicks/repo"
+	"github.com/sshlykov/shortener/internal/pkg/clicks/stream"
+	"github.com/sshlykov/shortener/pkg/postgres/pgtest"
+)
+
+type fakeRepo struct {
+	clicks []repository.Click
+	// down - база недоступна, запись висит до отмены ctx
+	down     bool
+	onInsert func()
+}
+
+func (r *fakeRepo) InsertClick(ctx context.Context, click repository.Click) error {
+	if r.onInsert != nil {
+		r.onInsert()
+	}
+	if r.down {
+		<-ctx.Done()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.clicks = append(r.clicks, click)
+	return nil
+}
+
+type nopPublisher struct{}
+
+func (nopPublisher) Publish(context.Context, domain.Event) error { return nil }
+
+type recordPublisher struct {
+	events []domain.EventType
+}
+
+func (p *recordPublisher) Publish(_ context.Context, event domain.Event) error {
+	p.events = append(p.events, event.Type)
+	return nil
+}
+
+type subscribers bool
+
+func (s subscribers) Subscribed(context.Context, domain.EventType) bool { return bool(s) }
+
+func newTestService(repo Repository) *Service {
+	cfg := config.Clicks{QueueSize: 10, ShutdownTimeout: time.Second, WriteTimeout: 20 * time.Millisecond}
+	return &Service{
+		repo:            repo,
+		tx:              pgtest.PassTx{},
+		publisher:       nopPublisher{},
+		hub:             stream.NewHub(10, 10),
+		queue:           make(chan domain.Click, cfg.QueueSize),
+		shutdownTimeout: cfg.ShutdownTimeout,
+		writeTimeout:    cfg.WriteTimeout,
+	}
+}
+
+func TestRunDrainsQueue(t *testing.T) {
+	repo := &fakeRepo{}
+	s := newTestService(repo)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 3; i++ {
+		s.Record(ctx, domain.Click{LinkID: int32(i), IP: "10.0.0.1"})
+	}
+
+	s.Run(ctx)
+
+	if len(repo.clicks) != 3 {
+		t.Errorf("saved %d clicks, want 3 accepted before shutdown", len(repo.clicks))
+	}
+}
+
+func TestRunDatabaseDown(t *testing.T) {
+	repo := &fakeRepo{down: true}
+	s := newTestService(repo)
+	sub, _, err := s.SubscribeLink(1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// SSE подписчик получает клик до записи в базу
+	var published, inserts int
+	repo.onInsert = func() {
+		inserts++
+		select {
+		case <-sub.C:
+			published++
+		default:
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Run(ctx)
+	}()
+
+	for i := 0; i < 4; i++ {
+		s.Record(ctx, domain.Click{LinkID: 1})
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(s.shutdownTimeout):
+		t.Fatal("Run overran shutdown timeout while the database is down")
+	}
+
+	if inserts != 4 || published != inserts {
+		t.Errorf("published %d of %d clicks before the write", published, inserts)
+	}
+}
+
+func TestProcessPublishesOnlyToSubscribers(t *testing.T) {
+	tests := []struct {
+		name        string
+		subscribers Subscribers
+		want        int
+	}{
+		{name: "no subscription", subscribers: subscribers(false), want: 0},
+		{name: "subscribed", subscribers: subscribers(true), want: 1},
+		{name: "not checked", want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepo{}
+			publisher := &recordPublisher{}
+			s := newTestService(repo)
+			s.publisher, s.subscribers = publisher, tt.subscribers
+
+			s.process(context.Background(), domain.Click{LinkID: 1})
+
+			if len(repo.clicks) != 1 {
+				t.Errorf("saved %d clicks, want 1", len(repo.clicks))
+			}
+			if len(publisher.events) != tt.want {
+				t.Errorf("published %v, want %d events", publisher.events, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordInvalidIP(t *testing.T) {
+	s := newTestService(&fakeRepo{})
+
+	for ip, want := range map[string]string{
+		"203.0.113.7": "203.0.113.7",
+		"2001:db8::1": "2001:db8::1",
+		"unknown":     "",
+		"1.2.3.4:80":  "",
+		"":            "",
+	} {
+		s.Record(context.Background(), domain.Click{IP: ip})
+		if got := (<-s.queue).IP; got != want {
+			t.Errorf("Record(%q) ip = %q, want %q", ip, got, want)
+		}
+	}
+}
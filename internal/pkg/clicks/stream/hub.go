@@ -0,0 +1,149 @@
+package stream
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sshlykov/shortener/internal/domain"
+)
+
+var (
+	ErrHubClosed    = errors.New("click stream closed")
+	ErrSlowConsumer = errors.New("subscriber buffer overflow")
+)
+
+type Event struct {
+	ID    uint64
+	Click domain.Click
+}
+
+type Filter func(click domain.Click) bool
+
+// Subscription - подписка на клики. Канал C закрывается при переполнении буфера,
+// отписке или закрытии хаба, причину можно узнать через Err
+type Subscription struct {
+	C <-chan Event
+
+	c      chan Event
+	filter Filter
+	err    error
+}
+
+func (s *Subscription) Err() error {
+	return s.err
+}
+
+// Hub раздает клики подписчикам и хранит последние события в кольцевом буфере,
+// чтобы переподключившийся клиент мог дочитать пропущенное по Last-Event-ID
+type Hub struct {
+	bufferSize int
+
+	mu     sync.Mutex
+	seq    uint64
+	ring   []Event
+	next   int
+	full   bool
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+func NewHub(ringSize, bufferSize int) *Hub {
+	return &Hub{
+		bufferSize: bufferSize,
+		// id событий растут и между перезапусками процесса, поэтому стартуем с текущего времени
+		seq:  uint64(time.Now().UnixMicro()),
+		ring: make([]Event, ringSize),
+		subs: make(map[*Subscription]struct{}),
+	}
+}
+
+func (h *Hub) Publish(click domain.Click) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+
+	h.seq++
+	event := Event{ID: h.seq, Click: click}
+
+	if len(h.ring) > 0 {
+		h.ring[h.next] = event
+		h.next = (h.next + 1) % len(h.ring)
+		h.full = h.full || h.next == 0
+	}
+
+	for sub := range h.subs {
+		if !sub.filter(click) {
+			continue
+		}
+		select {
+		case sub.c <- event:
+		default:
+			h.drop(sub, ErrSlowConsumer)
+		}
+	}
+}
+
+// Subscribe подписывает на клики, прошедшие фильтр. Если lastEventID не нулевой,
+// возвращаются события из кольцевого буфера, которые клиент еще не видел
+func (h *Hub) Subscribe(filter Filter, lastEventID uint64) (*Subscription, []Event, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, nil, ErrHubClosed
+	}
+
+	var backlog []Event
+	if lastEventID != 0 {
+		for _, event := range h.events() {
+			if event.ID > lastEventID && filter(event.Click) {
+				backlog = append(backlog, event)
+			}
+		}
+	}
+
+	c := make(chan Event, h.bufferSize)
+	sub := &Subscription{C: c, c: c, filter: filter}
+	h.subs[sub] = struct{}{}
+
+	return sub, backlog, nil
+}
+
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subs[sub]; ok {
+		h.drop(sub, nil)
+	}
+}
+
+// Close закрывает все подписки, новые подписки не принимаются
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.subs {
+		h.drop(sub, ErrHubClosed)
+	}
+}
+
+func (h *Hub) drop(sub *Subscription, err error) {
+	sub.err = err
+	close(sub.c)
+	delete(h.subs, sub)
+}
+
+// events возвращает содержимое кольцевого буфера от старых к новым
+func (h *Hub) events() []Event {
+	if !h.full {
+		return h.ring[:h.next]
+	}
+
+	return append(append([]Event{}, h.ring[h.next:]...), h.ring[:h.next]...)
+}
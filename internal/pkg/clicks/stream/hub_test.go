@@ -0,0 +1,76 @@
+package stream
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sshlykov/shortener/internal/domain"
+)
+
+func all(domain.Click) bool { return true }
+
+func TestHubResume(t *testing.T) {
+	h := NewHub(3, 10)
+
+	sub, _, err := h.Subscribe(all, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := int32(1); i <= 5; i++ {
+		h.Publish(domain.Click{LinkID: i})
+	}
+
+	var ids []uint64
+	for i := 0; i < 5; i++ {
+		ids = append(ids, (<-sub.C).ID)
+	}
+
+	_, backlog, err := h.Subscribe(all, ids[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backlog) != 2 || backlog[0].ID != ids[3] || backlog[1].ID != ids[4] {
+		t.Errorf("unexpected backlog: %+v", backlog)
+	}
+
+	// из кольца уже вытеснены старые события, отдаем то, что осталось
+	_, backlog, _ = h.Subscribe(all, ids[0])
+	if len(backlog) != 3 || backlog[0].ID != ids[2] {
+		t.Errorf("unexpected backlog: %+v", backlog)
+	}
+}
+
+func TestHubFilterAndOverflow(t *testing.T) {
+	h := NewHub(0, 1)
+
+	sub, _, _ := h.Subscribe(func(c domain.Click) bool { return c.Owner == "alice" }, 0)
+
+	h.Publish(domain.Click{Owner: "bob"})
+	h.Publish(domain.Click{Owner: "alice"})
+	h.Publish(domain.Click{Owner: "alice"})
+
+	if event, ok := <-sub.C; !ok || event.Click.Owner != "alice" {
+		t.Fatalf("expected alice click, got %+v", event)
+	}
+	if _, ok := <-sub.C; ok {
+		t.Fatal("subscription should be closed after overflow")
+	}
+	if !errors.Is(sub.Err(), ErrSlowConsumer) {
+		t.Errorf("unexpected error: %v", sub.Err())
+	}
+}
+
+func TestHubClose(t *testing.T) {
+	h := NewHub(1, 1)
+	sub, _, _ := h.Subscribe(all, 0)
+
+	h.Close()
+
+	if _, ok := <-sub.C; ok || !errors.Is(sub.Err(), ErrHubClosed) {
+		t.Errorf("subscription should be closed with ErrHubClosed, got %v", sub.Err())
+	}
+	if _, _, err := h.Subscribe(all, 0); !errors.Is(err, ErrHubClosed) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	h.Unsubscribe(sub)
+}
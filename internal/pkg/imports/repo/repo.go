@@ -0,0 +1,124 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/sshlykov/shortener/pkg/postgres"
+)
+
+type Import struct {
+	ImportID   int64           `db:"import_id"`
+	Source     string          `db:"source"`
+	Owner      string          `db:"owner"`
+	Status     string          `db:"status"`
+	Total      int             `db:"total"`
+	Processed  int             `db:"processed"`
+	Created    int             `db:"created"`
+	Skipped    int             `db:"skipped"`
+	Failed     int             `db:"failed"`
+	Errors     json.RawMessage `db:"errors"`
+	LastError  *string         `db:"last_error"`
+	CreatedAt  time.Time       `db:"created_at"`
+	UpdatedAt  time.Time       `db:"updated_at"`
+	FinishedAt *time.Time      `db:"finished_at"`
+}
+
+// Progress - счетчики импорта, сохраняются вместе с числом обработанных строк
+type Progress struct {
+	Total     int
+	Processed int
+	Created   int
+	Skipped   int
+	Failed    int
+	Errors    json.RawMessage
+}
+
+type Repository struct {
+	db postgres.DB
+}
+
+func New(db postgres.Client) *Repository {
+	return &Repository{db: db.DB()}
+}
+
+const importColumns = `import_id, source, owner, status, total, processed, created, skipped, failed, errors,
+       last_error, created_at, updated_at, finished_at`
+
+const insertImport = `
+INSERT INTO imports (source, owner, data)
+VALUES ($1, $2, $3)
+RETURNING ` + importColumns
+
+func (r *Repository) Insert(ctx context.Context, source, owner string, data []byte) (*Import, error) {
+	var imp Import
+	q := postgres.Query{Name: "imports.insert", Raw: insertImport}
+	if err := r.db.ScanSingleContext(ctx, q, &imp, source, owner, data); err != nil {
+		return nil, err
+	}
+
+	return &imp, nil
+}
+
+const getImport = `
+SELECT ` + importColumns + `
+FROM imports
+WHERE import_id = $1`
+
+func (r *Repository) Get(ctx context.Context, id int64) (*Import, error) {
+	var imp Import
+	q := postgres.Query{Name: "imports.get", Raw: getImport}
+	if err := r.db.ScanSingleContext(ctx, q, &imp, id); err != nil {
+		return nil, err
+	}
+
+	return &imp, nil
+}
+
+const getImportData = `SELECT data FROM imports WHERE import_id = $1`
+
+// GetData возвращает исходный файл, после завершения импорта он пустой
+func (r *Repository) GetData(ctx context.Context, id int64) ([]byte, error) {
+	var data []byte
+	q := postgres.Query{Name: "imports.get_data", Raw: getImportData}
+	err := r.db.QueryRowContext(ctx, q, id).Scan(&data)
+
+	return data, err
+}
+
+const saveProgress = `
+UPDATE imports
+SET status     = 'running',
+    total      = $2,
+    processed  = $3,
+    created    = $4,
+    skipped    = $5,
+    failed     = $6,
+    errors     = $7,
+    updated_at = now()
+WHERE import_id = $1`
+
+func (r *Repository) SaveProgress(ctx context.Context, id int64, p Progress) error {
+	q := postgres.Query{Name: "imports.save_progress", Raw: saveProgress}
+	_, err := r.db.ExecContext(ctx, q, id, p.Total, p.Processed, p.Created, p.Skipped, p.Failed, p.Errors)
+
+	return err
+}
+
+const finishImport = `
+UPDATE imports
+SET status      = $2,
+    last_error  = $3,
+    data        = NULL,
+    updated_at  = now(),
+    finished_at = now()
+WHERE import_id = $1`
+
+// Finish завершает импорт и удаляет исходный файл
+func (r *Repository) Finish(ctx context.Context, id int64, status string, lastError *string) error {
+	q := postgres.Query{Name: "imports.finish", Raw: finishImport}
+	_, err := r.db.ExecContext(ctx, q, id, status, lastError)
+
+	return err
+}
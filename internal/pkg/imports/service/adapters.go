@@ -0,0 +1,188 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type Source string
+
+const (
+	SourceBitly   Source = "bitly"
+	SourceTinyURL Source = "tinyurl"
+	SourceYOURLS  Source = "yourls"
+)
+
+// adapter описывает CSV-выгрузку сокращателя: для каждого поля - возможные названия колонки.
+// Ключ берется из колонки ключа, а если ее нет или она пустая - из последнего сегмента короткой ссылки
+type adapter struct {
+	url    []string
+	key    []string
+	short  []string
+	clicks []string
+}
+
+var adapters = map[Source]adapter{
+	SourceBitly: {
+		url:    []string{"long url", "long_url", "destination url", "original url"},
+		key:    []string{"custom back-half", "back-half", "custom_bitlinks"},
+		short:  []string{"bitlink", "short url", "short_url", "link"},
+		clicks: []string{"clicks", "total clicks", "engagements"},
+	},
+	SourceTinyURL: {
+		url:    []string{"long_url", "long url", "url", "destination"},
+		key:    []string{"alias"},
+		short:  []string{"tiny_url", "tinyurl", "short_url", "short url"},
+		clicks: []string{"hits", "clicks", "total clicks"},
+	},
+	// плагин выгрузки YOURLS повторяет колонки таблицы yourls_url
+	SourceYOURLS: {
+		url:    []string{"url", "long url"},
+		key:    []string{"keyword"},
+		short:  []string{"shorturl", "short url"},
+		clicks: []string{"clicks"},
+	},
+}
+
+func ParseSource(raw string) (Source, error) {
+	source := Source(strings.ToLower(strings.TrimSpace(raw)))
+	if _, ok := adapters[source]; !ok {
+		return "", ErrUnknownSource
+	}
+
+	return source, nil
+}
+
+// importRow - строка выгрузки в терминах нашей модели
+type importRow struct {
+	Line   int
+	Key    string
+	URL    string
+	Clicks int64
+	Err    error
+}
+
+// columns - номера колонок заголовка, -1 - колонки нет
+type columns struct {
+	url, key, short, clicks int
+}
+
+func (a adapter) bind(header []string) (columns, error) {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF")))
+		if _, dup := index[name]; !dup {
+			index[name] = i
+		}
+	}
+	find := func(names []string) int {
+		for _, name := range names {
+			if i, ok := index[name]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+
+	c := columns{url: find(a.url), key: find(a.key), short: find(a.short), clicks: find(a.clicks)}
+	if c.url < 0 {
+		return c, fmt.Errorf("%w: no url column among %q", ErrUnrecognizedFile, a.url)
+	}
+
+	return c, nil
+}
+
+// parse разбирает выгрузку целиком: ее размер ограничен при загрузке
+func parse(source Source, data []byte) ([]importRow, error) {
+	a, ok := adapters[source]
+	if !ok {
+		return nil, ErrUnknownSource
+	}
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnrecognizedFile, err)
+	}
+	cols, err := a.bind(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []importRow
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rows = append(rows, importRow{Line: parseErr.StartLine, Err: parseErr.Err})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := r.FieldPos(0)
+		rows = append(rows, cols.row(line, record))
+	}
+}
+
+func (c columns) row(line int, record []string) importRow {
+	field := func(i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	row := importRow{Line: line, URL: field(c.url), Key: field(c.key)}
+	if row.Key == "" {
+		row.Key = keyFromShortURL(field(c.short))
+	}
+
+	if raw := strings.ReplaceAll(field(c.clicks), ",", ""); raw != "" {
+		clicks, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || clicks < 0 {
+			row.Err = ErrInvalidClicks
+			return row
+		}
+		row.Clicks = clicks
+	}
+	if row.URL == "" {
+		row.Err = ErrEmptyURL
+	}
+
+	return row
+}
+
+// keyFromShortURL достает ключ из короткой ссылки: bit.ly/abc, https://tinyurl.com/abc
+func keyFromShortURL(short string) string {
+	if short == "" {
+		return ""
+	}
+	if !strings.Contains(short, "://") {
+		short = "https://" + short
+	}
+
+	parsed, err := url.Parse(short)
+	if err != nil {
+		return ""
+	}
+	path := strings.Trim(parsed.Path, "/")
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		path = path[i+1:]
+	}
+
+	return path
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		source Source
+		data   string
+		want   []importRow
+	}{
+		{
+			name:   "bitly",
+			source: SourceBitly,
+			data: "\uFEFFBitlink,Long URL,Custom back-half,Clicks\n" +
+				"bit.ly/3abcDef,https://example.com/a,,\"1,204\"\n" +
+				"bit.ly/xyz,https://example.com/b,docs,7\n",
+			want: []importRow{
+				{Line: 2, Key: "3abcDef", URL: "https://example.com/a", Clicks: 1204},
+				{Line: 3, Key: "docs", URL: "https://example.com/b", Clicks: 7},
+			},
+		},
+		{
+			name:   "tinyurl",
+			source: SourceTinyURL,
+			data: "tiny_url,long_url,hits\n" +
+				"https://tinyurl.com/my-alias,https://example.com,12\n" +
+				"https://tinyurl.com/y2k,,3\n",
+			want: []importRow{
+				{Line: 2, Key: "my-alias", URL: "https://example.com", Clicks: 12},
+				{Line: 3, Key: "y2k", Clicks: 3, Err: ErrEmptyURL},
+			},
+		},
+		{
+			name:   "yourls",
+			source: SourceYOURLS,
+			data: "keyword,url,title,timestamp,ip,clicks\n" +
+				"promo,https://example.com/promo,Promo,2020-01-01 00:00:00,127.0.0.1,-1\n",
+			want: []importRow{
+				{Line: 2, Key: "promo", URL: "https://example.com/promo", Err: ErrInvalidClicks},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := parse(tt.source, []byte(tt.data))
+			if err != nil {
+				t.Fatalf("parse() error = %v", err)
+			}
+			if len(rows) != len(tt.want) {
+				t.Fatalf("parse() = %d rows, want %d", len(rows), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := rows[i]
+				if got.Line != want.Line || got.Key != want.Key || got.Clicks != want.Clicks || !errors.Is(got.Err, want.Err) ||
+					(want.Err == nil && got.URL != want.URL) {
+					t.Errorf("row %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseUnrecognized(t *testing.T) {
+	_, err := parse(SourceYOURLS, []byte("Bitlink,Destination\nbit.ly/a,https://example.com\n"))
+	if !errors.Is(err, ErrUnrecognizedFile) {
+		t.Errorf("parse() error = %v, want ErrUnrecognizedFile", err)
+	}
+}
+
+func TestKeyFromShortURL(t *testing.T) {
+	tests := map[string]string{
+		"":                           "",
+		"bit.ly/3abc":                "3abc",
+		"https://tinyurl.com/alias/": "alias",
+		"http://sho.rt/a/b?x=1":      "b",
+	}
+	for in, want := range tests {
+		if got := keyFromShortURL(in); got != want {
+			t.Errorf("keyFromShortURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
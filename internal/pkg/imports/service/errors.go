@@ -0,0 +1,14 @@
+package service
+
+import "errors"
+
+var (
+	ErrUnknownSource     = errors.New("unknown import source, expected bitly, tinyurl or yourls")
+	ErrUnrecognizedFile  = errors.New("file does not look like an export of the source")
+	ErrImportNotFound    = errors.New("import not found")
+	ErrCantCreateImport  = errors.New("can't create import")
+	ErrCantGetImport     = errors.New("can't get import")
+	ErrImportDataMissing = errors.New("import file is missing")
+	ErrInvalidClicks     = errors.New("clicks should be a non-negative integer")
+	ErrEmptyURL          = errors.New("url is empty")
+)
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sshlykov/shortener/internal/domain"
+	repository "github.com/sshlykov/shortener/internal/pkg/imports/repo"
+	jobsrv "github.com/sshlykov/shortener/internal/pkg/jobs/service"
+	linksrv "github.com/sshlykov/shortener/internal/pkg/links/service"
+	"github.com/sshlykov/shortener/pkg/backoff"
+	"github.com/sshlykov/shortener/pkg/logger"
+)
+
+type outcome int
+
+const (
+	outcomeCreated outcome = iota
+	outcomeSkipped
+	outcomeFailed
+)
+
+// Handle - обработчик задачи импорта. Прогресс сохраняется каждые cfg.ProgressEvery строк,
+// повтор задачи продолжает с последней сохраненной строки. Строки после нее могли быть уже
+// импортированы, повторно они будут пропущены как существующие
+func (s *Service) Handle(ctx context.Context, job *jobsrv.Job) error {
+	var payload jobPayload
+	if err := job.Decode(&payload); err != nil {
+		return backoff.Permanent(err)
+	}
+
+	imp, err := s.repo.Get(ctx, payload.ImportID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return backoff.Permanent(ErrImportNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	if imp.Status == string(domain.ImportDone) || imp.Status == string(domain.ImportFailed) {
+		return nil
+	}
+
+	data, err := s.repo.GetData(ctx, imp.ImportID)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return s.fail(ctx, imp.ImportID, ErrImportDataMissing)
+	}
+	rows, err := parse(Source(imp.Source), data)
+	if err != nil {
+		return s.fail(ctx, imp.ImportID, err)
+	}
+
+	progress := &tracker{imp: toDomain(imp), maxIssues: s.cfg.MaxIssues}
+	progress.imp.Total = len(rows)
+	if err = s.save(ctx, progress); err != nil {
+		return err
+	}
+
+	for _, row := range rows[min(progress.imp.Processed, len(rows)):] {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		result, issue, err := s.importRow(ctx, imp.Owner, row)
+		if err != nil {
+			// ошибка базы, строка будет повторена вместе с задачей
+			_ = s.save(context.WithoutCancel(ctx), progress)
+			return err
+		}
+		progress.add(row, result, issue)
+
+		if s.cfg.ProgressEvery > 0 && progress.imp.Processed%s.cfg.ProgressEvery == 0 {
+			if err = s.save(ctx, progress); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err = s.save(ctx, progress); err != nil {
+		return err
+	}
+	logger.Info(ctx, "import finished", logger.Any("import_id", imp.ImportID), logger.Any("created", progress.imp.Created),
+		logger.Any("skipped", progress.imp.Skipped), logger.Any("failed", progress.imp.Failed))
+
+	return s.repo.Finish(ctx, imp.ImportID, string(domain.ImportDone), nil)
+}
+
+// importRow переносит строку. Ключ сохраняется, если подходит под наши правила, иначе генерируется новый.
+// Ключ, занятый ссылкой на тот же url, считается уже импортированным, на другой url - конфликтом
+func (s *Service) importRow(ctx context.Context, owner string, row importRow) (outcome, *domain.ImportIssue, error) {
+	issue := func(kind domain.ImportIssueKind, err error) *domain.ImportIssue {
+		return &domain.ImportIssue{Line: row.Line, Key: row.Key, Kind: kind, Message: err.Error()}
+	}
+	if row.Err != nil {
+		return outcomeFailed, issue(domain.ImportIssueInvalid, row.Err), nil
+	}
+
+	url, err := linksrv.NormalizeURL(row.URL)
+	if err != nil {
+		return outcomeFailed, issue(domain.ImportIssueInvalid, err), nil
+	}
+
+	var replaced *domain.ImportIssue
+	key := row.Key
+	if key != "" {
+		if err = linksrv.ValidateKey(key); err != nil {
+			replaced = issue(domain.ImportIssueKeyReplaced, fmt.Errorf("%w, a new key is generated", err))
+			key = ""
+		}
+	}
+
+	link, created, err := s.links.Ensure(ctx, domain.Link{Key: key, URL: url, Owner: owner})
+	if errors.Is(err, linksrv.ErrKeyConflict) {
+		return outcomeFailed, issue(domain.ImportIssueConflict, err), nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	result := outcomeSkipped
+	if created {
+		result = outcomeCreated
+	}
+
+	if row.Clicks > 0 {
+		if err = s.links.SetImportedClicks(ctx, link.Key, row.Clicks); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	return result, replaced, nil
+}
+
+func (s *Service) save(ctx context.Context, t *tracker) error {
+	issues, err := json.Marshal(t.imp.Issues)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.SaveProgress(ctx, t.imp.ID, repository.Progress{
+		Total:     t.imp.Total,
+		Processed: t.imp.Processed,
+		Created:   t.imp.Created,
+		Skipped:   t.imp.Skipped,
+		Failed:    t.imp.Failed,
+		Errors:    issues,
+	})
+}
+
+// fail завершает импорт, который нельзя выполнить, задача не повторяется
+func (s *Service) fail(ctx context.Context, id int64, cause error) error {
+	msg := cause.Error()
+	if err := s.repo.Finish(ctx, id, string(domain.ImportFailed), &msg); err != nil {
+		return err
+	}
+
+	return backoff.Permanent(cause)
+}
+
+type tracker struct {
+	imp       *domain.Import
+	maxIssues int
+}
+
+func (t *tracker) add(row importRow, result outcome, issue *domain.ImportIssue) {
+	t.imp.Processed++
+	switch result {
+	case outcomeCreated:
+		t.imp.Created++
+	case outcomeSkipped:
+		t.imp.Skipped++
+	case outcomeFailed:
+		t.imp.Failed++
+	}
+
+	if issue != nil && len(t.imp.Issues) < t.maxIssues {
+		if issue.Line == 0 {
+			issue.Line = row.Line
+		}
+		t.imp.Issues = append(t.imp.Issues, *issue)
+	}
+}
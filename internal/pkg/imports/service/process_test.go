@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/sshlykov/shortener/internal/config"
+	"github.com/sshlykov/shortener/internal/domain"
+	repository "github.com/sshlykov/shortener/internal/pkg/imports/repo"
+	jobsrv "github.com/sshlykov/shortener/internal/pkg/jobs/service"
+	linksrv "github.com/sshlykov/shortener/internal/pkg/links/service"
+)
+
+type memRepo struct {
+	Repository
+	imp   repository.Import
+	data  []byte
+	saves int
+}
+
+func (r *memRepo) Get(context.Context, int64) (*repository.Import, error) {
+	imp := r.imp
+	return &imp, nil
+}
+
+func (r *memRepo) GetData(context.Context, int64) ([]byte, error) {
+	return r.data, nil
+}
+
+func (r *memRepo) SaveProgress(_ context.Context, _ int64, p repository.Progress) error {
+	r.saves++
+	r.imp.Status = string(domain.ImportRunning)
+	r.imp.Total, r.imp.Processed, r.imp.Created, r.imp.Skipped, r.imp.Failed = p.Total, p.Processed, p.Created, p.Skipped, p.Failed
+	r.imp.Errors = p.Errors
+	return nil
+}
+
+func (r *memRepo) Finish(_ context.Context, _ int64, status string, lastError *string) error {
+	r.imp.Status, r.imp.LastError = status, lastError
+	return nil
+}
+
+type memLinks struct {
+	links  map[string]domain.Link
+	clicks map[string]int64
+	failOn string
+}
+
+func (l *memLinks) FindByURL(_ context.Context, owner, url string) (*domain.Link, error) {
+	for _, link := range l.links {
+		if link.Owner == owner && link.URL == url {
+			return &link, nil
+		}
+	}
+	return nil, linksrv.ErrLinkNotFound
+}
+
+func (l *memLinks) Ensure(ctx context.Context, link domain.Link) (*domain.Link, bool, error) {
+	if existing, ok := l.links[link.Key]; ok {
+		if existing.URL != link.URL || existing.Owner != link.Owner {
+			return nil, false, linksrv.ErrKeyConflict
+		}
+		return &existing, false, nil
+	}
+	if link.Key == "" {
+		if existing, err := l.FindByURL(ctx, link.Owner, link.URL); err == nil {
+			return existing, false, nil
+		}
+	}
+
+	if link.URL == l.failOn {
+		return nil, false, linksrv.ErrCantCreateLink
+	}
+	if link.Key == "" {
+		link.Key = "gen" + string(rune('a'+len(l.links)))
+	}
+	l.links[link.Key] = link
+	return &link, true, nil
+}
+
+func (l *memLinks) SetImportedClicks(_ context.Context, key string, clicks int64) error {
+	l.clicks[key] = clicks
+	return nil
+}
+
+const bitlyExport = "Bitlink,Long URL,Clicks\n" +
+	"bit.ly/docs,https://example.com/docs,10\n" + // новая ссылка с ключом
+	"bit.ly/taken,https://example.com/other,1\n" + // ключ занят другой ссылкой
+	"bit.ly/a,https://example.com/short,2\n" + // ключ короче допустимого
+	"bit.ly/same,https://example.com/same,3\n" + // уже импортирована
+	",ftp://example.com,\n" // некорректный url
+
+func newImport(data string) (*memRepo, *memLinks, *Service) {
+	repo := &memRepo{
+		imp:  repository.Import{ImportID: 1, Source: string(SourceBitly), Owner: "alice", Status: string(domain.ImportPending)},
+		data: []byte(data),
+	}
+	links := &memLinks{
+		links: map[string]domain.Link{
+			"taken": {Key: "taken", URL: "https://example.com/taken", Owner: "alice"},
+			"same":  {Key: "same", URL: "https://example.com/same", Owner: "alice"},
+		},
+		clicks: map[string]int64{},
+	}
+	s := &Service{repo: repo, links: links, cfg: config.Imports{ProgressEvery: 2, MaxIssues: 10}}
+
+	return repo, links, s
+}
+
+func importJob() *jobsrv.Job {
+	payload, _ := json.Marshal(jobPayload{ImportID: 1})
+	return &jobsrv.Job{ID: 1, Kind: JobKind, Payload: payload}
+}
+
+func TestHandle(t *testing.T) {
+	repo, links, s := newImport(bitlyExport)
+
+	if err := s.Handle(context.Background(), importJob()); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	imp := repo.imp
+	if imp.Status != string(domain.ImportDone) {
+		t.Errorf("status = %s, want done", imp.Status)
+	}
+	if imp.Total != 5 || imp.Processed != 5 || imp.Created != 2 || imp.Skipped != 1 || imp.Failed != 2 {
+		t.Errorf("counters = %+v", imp)
+	}
+	if repo.saves < 3 {
+		t.Errorf("progress saved %d times, want at least 3", repo.saves)
+	}
+
+	var issues []domain.ImportIssue
+	if err := json.Unmarshal(imp.Errors, &issues); err != nil {
+		t.Fatal(err)
+	}
+	kinds := make(map[int]domain.ImportIssueKind, len(issues))
+	for _, issue := range issues {
+		kinds[issue.Line] = issue.Kind
+	}
+	want := map[int]domain.ImportIssueKind{3: domain.ImportIssueConflict, 4: domain.ImportIssueKeyReplaced, 6: domain.ImportIssueInvalid}
+	for line, kind := range want {
+		if kinds[line] != kind {
+			t.Errorf("line %d issue = %q, want %q", line, kinds[line], kind)
+		}
+	}
+
+	if links.clicks["docs"] != 10 || links.clicks["same"] != 3 {
+		t.Errorf("imported clicks = %v", links.clicks)
+	}
+	if link, err := links.FindByURL(context.Background(), "alice", "https://example.com/short"); err != nil || link.Key == "a" {
+		t.Errorf("replaced key link = %+v, %v", link, err)
+	}
+}
+
+func TestHandleResumes(t *testing.T) {
+	repo, links, s := newImport(bitlyExport)
+	links.failOn = "https://example.com/short"
+
+	if err := s.Handle(context.Background(), importJob()); !errors.Is(err, linksrv.ErrCantCreateLink) {
+		t.Fatalf("Handle() error = %v, want ErrCantCreateLink", err)
+	}
+	if repo.imp.Processed != 2 || repo.imp.Status != string(domain.ImportRunning) {
+		t.Fatalf("after failure = %+v", repo.imp)
+	}
+
+	links.failOn = ""
+	if err := s.Handle(context.Background(), importJob()); err != nil {
+		t.Fatalf("retry error = %v", err)
+	}
+	if repo.imp.Processed != 5 || repo.imp.Created != 2 || repo.imp.Failed != 2 {
+		t.Errorf("after retry = %+v", repo.imp)
+	}
+}
+
+func TestHandleUnrecognized(t *testing.T) {
+	repo, _, s := newImport("keyword,url\npromo,https://example.com\n")
+
+	if err := s.Handle(context.Background(), importJob()); !errors.Is(err, ErrUnrecognizedFile) {
+		t.Fatalf("Handle() error = %v, want ErrUnrecognizedFile", err)
+	}
+	if repo.imp.Status != string(domain.ImportFailed) || repo.imp.LastError == nil {
+		t.Errorf("import = %+v, want failed with last error", repo.imp)
+	}
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sshlykov/shortener/internal/config"
+	"github.com/sshlykov/shortener/internal/domain"
+	repository "github.com/sshlykov/shortener/internal/pkg/imports/repo"
+	jobsrv "github.com/sshlykov/shortener/internal/pkg/jobs/service"
+	"github.com/sshlykov/shortener/pkg/logger"
+	"github.com/sshlykov/shortener/pkg/postgres"
+)
+
+// JobKind - вид задачи импорта в очереди jobs
+const JobKind = "links.import"
+
+type Repository interface {
+	Insert(ctx context.Context, source, owner string, data []byte) (*repository.Import, error)
+	Get(ctx context.Context, id int64) (*repository.Import, error)
+	GetData(ctx context.Context, id int64) ([]byte, error)
+	SaveProgress(ctx context.Context, id int64, p repository.Progress) error
+	Finish(ctx context.Context, id int64, status string, lastError *string) error
+}
+
+type LinkService interface {
+	Ensure(ctx context.Context, link domain.Link) (*domain.Link, bool, error)
+	SetImportedClicks(ctx context.Context, key string, clicks int64) error
+}
+
+type Enqueuer interface {
+	Enqueue(ctx context.Context, kind string, payload any, opts ...jobsrv.EnqueueOption) (int64, error)
+}
+
+// Service импортирует ссылки из выгрузок Bitly, TinyURL и YOURLS фоновыми задачами
+type Service struct {
+	repo  Repository
+	tx    postgres.TxManager
+	jobs  Enqueuer
+	links LinkService
+	cfg   config.Imports
+}
+
+func New(db postgres.Client, tx postgres.TxManager, cfg config.Imports, jobs Enqueuer, links LinkService) *Service {
+	return &Service{
+		repo:  repository.New(db),
+		tx:    tx,
+		jobs:  jobs,
+		links: links,
+		cfg:   cfg,
+	}
+}
+
+type jobPayload struct {
+	ImportID int64 `json:"import_id"`
+}
+
+// CreateImport сохраняет выгрузку и ставит задачу импорта. Заголовок проверяется сразу,
+// чтобы файл не того формата отклонялся при загрузке, а не в задаче
+func (s *Service) CreateImport(ctx context.Context, source Source, owner string, data []byte) (*domain.Import, error) {
+	if _, err := parse(source, data); err != nil {
+		return nil, err
+	}
+
+	var imp *repository.Import
+	err := s.tx.ReadCommitted(ctx, func(ctx context.Context) error {
+		var err error
+		if imp, err = s.repo.Insert(ctx, string(source), owner, data); err != nil {
+			return err
+		}
+		_, err = s.jobs.Enqueue(ctx, JobKind, jobPayload{ImportID: imp.ImportID},
+			jobsrv.UniqueKey(strconv.FormatInt(imp.ImportID, 10)))
+		return err
+	})
+	if err != nil {
+		logger.Error(ctx, "CreateImport", logger.Err(err), logger.Any("source", source))
+
+		return nil, ErrCantCreateImport
+	}
+
+	return toDomain(imp), nil
+}
+
+func (s *Service) GetImport(ctx context.Context, id int64) (*domain.Import, error) {
+	imp, err := s.repo.Get(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrImportNotFound
+	}
+	if err != nil {
+		logger.Error(ctx, "GetImport", logger.Err(err), logger.Any("import_id", id))
+
+		return nil, ErrCantGetImport
+	}
+
+	return toDomain(imp), nil
+}
+
+func toDomain(imp *repository.Import) *domain.Import {
+	res := &domain.Import{
+		ID:         imp.ImportID,
+		Source:     imp.Source,
+		Owner:      imp.Owner,
+		Status:     domain.ImportStatus(imp.Status),
+		Total:      imp.Total,
+		Processed:  imp.Processed,
+		Created:    imp.Created,
+		Skipped:    imp.Skipped,
+		Failed:     imp.Failed,
+		LastError:  imp.LastError,
+		CreatedAt:  imp.CreatedAt,
+		UpdatedAt:  imp.UpdatedAt,
+		FinishedAt: imp.FinishedAt,
+	}
+	_ = json.Unmarshal(imp.Errors, &res.Issues)
+
+	return res
+}
@@ -0,0 +1,177 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sshlykov/shortener/pkg/postgres"
+)
+
+type Job struct {
+	JobID       int64     `db:"job_id"`
+	Kind        string    `db:"kind"`
+	Payload     []byte    `db:"payload"`
+	Priority    int       `db:"priority"`
+	UniqueKey   *string   `db:"unique_key"`
+	Attempts    int       `db:"attempts"`
+	MaxAttempts int       `db:"max_attempts"`
+	RunAt       time.Time `db:"run_at"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+type Repository struct {
+	db postgres.DB
+}
+
+func New(db postgres.Client) *Repository {
+	return &Repository{db: db.DB()}
+}
+
+const insertJob = `
+WITH ins AS (
+    INSERT INTO jobs (kind, payload, priority, unique_key, max_attempts, run_at)
+    VALUES ($1, $2, $3, $4, $5, $6)
+    ON CONFLICT (kind, unique_key) WHERE unique_key IS NOT NULL AND status IN ('pending', 'running') DO NOTHING
+    RETURNING job_id, true AS created)
+SELECT job_id, created
+FROM ins
+UNION ALL
+SELECT job_id, false
+FROM jobs
+WHERE kind = $1
+  AND unique_key = $4
+  AND status IN ('pending', 'running')
+LIMIT 1`
+
+// Insert ставит задачу в очередь. Если задача с тем же ключом уже ждет или выполняется,
+// новая не создается и возвращается id существующей с created = false
+func (r *Repository) Insert(ctx context.Context, job *Job) (id int64, created bool, err error) {
+	q := postgres.Query{Name: "jobs.insert", Raw: insertJob}
+	err = r.db.QueryRowContext(ctx, q, job.Kind, job.Payload, job.Priority, job.UniqueKey, job.MaxAttempts,
+		job.RunAt).Scan(&id, &created)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// конкурентная вставка с тем же ключом еще не закоммичена
+		return 0, false, nil
+	}
+
+	return id, created, err
+}
+
+const jobColumns = `job_id, kind, payload, priority, unique_key, attempts, max_attempts, run_at, created_at`
+
+// lease истек на последней попытке - воркер упал, не сохранив результат, такая задача сразу dead
+const claimJobs = `
+WITH exhausted AS (
+    UPDATE jobs
+    SET status      = 'dead',
+        last_error  = 'lease expired on the last attempt',
+        updated_at  = now(),
+        finished_at = now()
+    WHERE job_id IN (SELECT job_id
+                     FROM jobs
+                     WHERE status = 'running'
+                       AND run_at <= now()
+                       AND attempts >= max_attempts
+                       AND kind = ANY ($3)
+                     FOR UPDATE SKIP LOCKED)
+)
+UPDATE jobs
+SET status     = 'running',
+    attempts   = attempts + 1,
+    run_at     = now() + make_interval(secs => $2),
+    updated_at = now()
+WHERE job_id IN (SELECT job_id
+                 FROM jobs
+                 WHERE status IN ('pending', 'running')
+                   AND run_at <= now()
+                   AND attempts < max_attempts
+                   AND kind = ANY ($3)
+                 ORDER BY priority DESC, run_at
+                 LIMIT $1 FOR UPDATE SKIP LOCKED)
+RETURNING ` + jobColumns
+
+// Claim забирает готовые задачи перечисленных видов. run_at сдвигается на lease: пока воркер жив,
+// он продлевает lease через Touch, а при падении процесса задача вернется в очередь.
+// Задача, у которой lease истек на последней попытке, не забирается, а переводится в dead
+func (r *Repository) Claim(ctx context.Context, limit int, lease time.Duration, kinds []string) ([]Job, error) {
+	var jobs []Job
+	q := postgres.Query{Name: "jobs.claim", Raw: claimJobs}
+	if err := r.db.ScanAllContext(ctx, q, &jobs, limit, lease.Seconds(), kinds); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// Условие attempts = $2 отсекает воркер, у которого истек lease и задачу уже забрал другой
+const touchJob = `
+UPDATE jobs
+SET run_at     = now() + make_interval(secs => $3),
+    updated_at = now()
+WHERE job_id = $1
+  AND attempts = $2
+  AND status = 'running'`
+
+// Touch продлевает lease выполняющейся задачи, false - задача уже не принадлежит этой попытке
+func (r *Repository) Touch(ctx context.Context, id int64, attempt int, lease time.Duration) (bool, error) {
+	q := postgres.Query{Name: "jobs.touch", Raw: touchJob}
+	tag, err := r.db.ExecContext(ctx, q, id, attempt, lease.Seconds())
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+const completeJob = `
+UPDATE jobs
+SET status      = 'done',
+    last_error  = NULL,
+    updated_at  = now(),
+    finished_at = now()
+WHERE job_id = $1
+  AND attempts = $2
+  AND status = 'running'`
+
+func (r *Repository) Complete(ctx context.Context, id int64, attempt int) error {
+	q := postgres.Query{Name: "jobs.complete", Raw: completeJob}
+	_, err := r.db.ExecContext(ctx, q, id, attempt)
+
+	return err
+}
+
+const failJob = `
+UPDATE jobs
+SET status      = $3,
+    run_at      = $4,
+    last_error  = $5,
+    updated_at  = now(),
+    finished_at = CASE WHEN $3 = 'dead' THEN now() END
+WHERE job_id = $1
+  AND attempts = $2
+  AND status = 'running'`
+
+// Fail возвращает задачу в очередь со статусом pending либо хоронит ее со статусом dead
+func (r *Repository) Fail(ctx context.Context, id int64, attempt int, status string, runAt time.Time,
+	lastError string) error {
+	q := postgres.Query{Name: "jobs.fail", Raw: failJob}
+	_, err := r.db.ExecContext(ctx, q, id, attempt, status, runAt, lastError)
+
+	return err
+}
+
+const purgeJobs = `DELETE FROM jobs WHERE status IN ('done', 'dead') AND finished_at < $1`
+
+// Purge удаляет завершенные задачи старше before
+func (r *Repository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	q := postgres.Query{Name: "jobs.purge", Raw: purgeJobs}
+	tag, err := r.db.ExecContext(ctx, q, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
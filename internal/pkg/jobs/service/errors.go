@@ -0,0 +1,13 @@
+package service
+
+import "errors"
+
+var (
+	ErrUnknownKind  = errors.New("unknown job kind")
+	ErrCantEnqueue  = errors.New("can't enqueue job")
+	ErrLeaseLost    = errors.New("job lease lost")
+	ErrHandlerPanic = errors.New("job handler panicked")
+	ErrInvalidKind  = errors.New("invalid job kind")
+	ErrDuplicateJob = errors.New("job with this unique key is already queued")
+	ErrCantPurge    = errors.New("can't purge finished jobs")
+)
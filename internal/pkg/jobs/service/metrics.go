@@ -0,0 +1,74 @@
+package service
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics - метрики очереди задач, nil отключает сбор
+type Metrics struct {
+	enqueued  *prometheus.CounterVec
+	processed *prometheus.CounterVec
+	duration  *prometheus.HistogramVec
+	running   *prometheus.GaugeVec
+}
+
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		enqueued: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "jobs_enqueued_total",
+				Help: "Total number of enqueued jobs by kind",
+			},
+			[]string{"kind"},
+		),
+		processed: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "jobs_processed_total",
+				Help: "Total number of processed job attempts by kind and result",
+			},
+			[]string{"kind", "result"},
+		),
+		duration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "jobs_duration_seconds",
+				Help:    "Job attempt duration in seconds by kind",
+				Buckets: []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 300, 900},
+			},
+			[]string{"kind"},
+		),
+		running: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "jobs_running",
+				Help: "Number of currently running jobs by kind",
+			},
+			[]string{"kind"},
+		),
+	}
+
+	reg.MustRegister(m.enqueued, m.processed, m.duration, m.running)
+
+	return m
+}
+
+func (m *Metrics) incEnqueued(kind string) {
+	if m == nil {
+		return
+	}
+	m.enqueued.WithLabelValues(kind).Inc()
+}
+
+func (m *Metrics) start(kind string) {
+	if m == nil {
+		return
+	}
+	m.running.WithLabelValues(kind).Inc()
+}
+
+func (m *Metrics) finish(kind string, result outcome, seconds float64) {
+	if m == nil {
+		return
+	}
+	m.running.WithLabelValues(kind).Dec()
+	m.processed.WithLabelValues(kind, string(result)).Inc()
+	m.duration.WithLabelValues(kind).Observe(seconds)
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sshlykov/shortener/internal/config"
+	repository "github.com/sshlykov/shortener/internal/pkg/jobs/repo"
+	"github.com/sshlykov/shortener/pkg/logger"
+	"github.com/sshlykov/shortener/pkg/postgres"
+)
+
+type Repository interface {
+	Insert(ctx context.Context, job *repository.Job) (int64, bool, error)
+	Claim(ctx context.Context, limit int, lease time.Duration, kinds []string) ([]repository.Job, error)
+	Touch(ctx context.Context, id int64, attempt int, lease time.Duration) (bool, error)
+	Complete(ctx context.Context, id int64, attempt int) error
+	Fail(ctx context.Context, id int64, attempt int, status string, runAt time.Time, lastError string) error
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Job - задача, переданная обработчику
+type Job struct {
+	ID          int64
+	Kind        string
+	Payload     json.RawMessage
+	Priority    int
+	UniqueKey   string
+	Attempt     int
+	MaxAttempts int
+	CreatedAt   time.Time
+}
+
+// Decode разбирает payload задачи в v
+func (j *Job) Decode(v any) error {
+	return json.Unmarshal(j.Payload, v)
+}
+
+// Handler выполняет задачу. Доставка как минимум однократная, обработчик должен быть идемпотентным.
+// Ошибка, обернутая в backoff.Permanent, не повторяется
+type Handler func(ctx context.Context, job *Job) error
+
+// Service - очередь фоновых задач в Postgres с пулом воркеров
+type Service struct {
+	repo    Repository
+	cfg     config.Jobs
+	metrics *Metrics
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+func New(db postgres.Client, cfg config.Jobs, metrics *Metrics) *Service {
+	return &Service{
+		repo:     repository.New(db),
+		cfg:      cfg,
+		metrics:  metrics,
+		handlers: make(map[string]Handler),
+	}
+}
+
+// Register задает обработчик задач вида kind. Вызывается до запуска Run
+func (s *Service) Register(kind string, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers[kind] = handler
+}
+
+func (s *Service) handler(kind string) (Handler, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	h, ok := s.handlers[kind]
+	return h, ok
+}
+
+func (s *Service) kinds() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	kinds := make([]string, 0, len(s.handlers))
+	for kind := range s.handlers {
+		kinds = append(kinds, kind)
+	}
+
+	return kinds
+}
+
+type enqueueOptions struct {
+	runAt       time.Time
+	priority    int
+	uniqueKey   *string
+	maxAttempts int
+}
+
+type EnqueueOption func(*enqueueOptions)
+
+// RunAt откладывает запуск задачи до t
+func RunAt(t time.Time) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.runAt = t
+	}
+}
+
+// Priority - задачи с большим приоритетом забираются раньше
+func Priority(p int) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.priority = p
+	}
+}
+
+// UniqueKey не дает поставить вторую задачу того же вида с тем же ключом, пока первая не завершена
+func UniqueKey(key string) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.uniqueKey = &key
+	}
+}
+
+func MaxAttempts(n int) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.maxAttempts = n
+	}
+}
+
+// Enqueue ставит задачу в очередь, внутри транзакции TxManager - вместе с изменением.
+// Если задача с тем же UniqueKey уже в очереди, возвращается ее id и ErrDuplicateJob
+func (s *Service) Enqueue(ctx context.Context, kind string, payload any, opts ...EnqueueOption) (int64, error) {
+	if kind == "" {
+		return 0, ErrInvalidKind
+	}
+
+	o := enqueueOptions{runAt: time.Now(), maxAttempts: s.cfg.MaxAttempts}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrCantEnqueue, err)
+	}
+
+	id, created, err := s.repo.Insert(ctx, &repository.Job{
+		Kind:        kind,
+		Payload:     raw,
+		Priority:    o.priority,
+		UniqueKey:   o.uniqueKey,
+		MaxAttempts: o.maxAttempts,
+		RunAt:       o.runAt,
+	})
+	if err != nil {
+		logger.Error(ctx, "Enqueue", logger.Err(err), logger.Any("kind", kind))
+		return 0, ErrCantEnqueue
+	}
+	if !created {
+		return id, ErrDuplicateJob
+	}
+
+	s.metrics.incEnqueued(kind)
+
+	return id, nil
+}
+
+func jobFromRepo(j *repository.Job) *Job {
+	job := &Job{
+		ID:          j.JobID,
+		Kind:        j.Kind,
+		Payload:     j.Payload,
+		Priority:    j.Priority,
+		Attempt:     j.Attempts,
+		MaxAttempts: j.MaxAttempts,
+		CreatedAt:   j.CreatedAt,
+	}
+	if j.UniqueKey != nil {
+		job.UniqueKey = *j.UniqueKey
+	}
+
+	return job
+}
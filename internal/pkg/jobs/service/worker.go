@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/sshlykov/shortener/pkg/backoff"
+	"github.com/sshlykov/shortener/pkg/logger"
+)
+
+type outcome string
+
+const (
+	outcomeDone  outcome = "done"
+	outcomeRetry outcome = "retry"
+	outcomeDead  outcome = "dead"
+)
+
+// Run запускает пул из cfg.Workers воркеров и работает, пока не будет отменен контекст.
+// При остановке новые задачи не забираются, а выполняющиеся получают cfg.ShutdownTimeout на завершение
+func (s *Service) Run(ctx context.Context) {
+	kinds := s.kinds()
+	if len(kinds) == 0 {
+		<-ctx.Done()
+		return
+	}
+
+	// задачи доживают до конца после отмены ctx, их контекст отменяется по таймауту
+	jobsCtx, cancelJobs := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelJobs()
+
+	slots := make(chan struct{}, s.cfg.Workers)
+	var wg sync.WaitGroup
+	defer func() {
+		timer := time.AfterFunc(s.cfg.ShutdownTimeout, cancelJobs)
+		defer timer.Stop()
+		wg.Wait()
+	}()
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.drain(ctx, jobsCtx, kinds, slots, &wg)
+		}
+	}
+}
+
+// drain забирает задачи, пока есть свободные воркеры и готовые задачи
+func (s *Service) drain(ctx, jobsCtx context.Context, kinds []string, slots chan struct{}, wg *sync.WaitGroup) {
+	for ctx.Err() == nil {
+		free := cap(slots) - len(slots)
+		if free == 0 {
+			return
+		}
+
+		jobs, err := s.repo.Claim(ctx, free, s.cfg.VisibilityTimeout, kinds)
+		if err != nil {
+			logger.Error(ctx, "Claim", logger.Err(err))
+			return
+		}
+
+		for i := range jobs {
+			slots <- struct{}{}
+			wg.Add(1)
+			go func(job *Job) {
+				defer wg.Done()
+				defer func() { <-slots }()
+				s.process(jobsCtx, job)
+			}(jobFromRepo(&jobs[i]))
+		}
+
+		if len(jobs) < free {
+			return
+		}
+	}
+}
+
+func (s *Service) process(ctx context.Context, job *Job) {
+	start := time.Now()
+	s.metrics.start(job.Kind)
+
+	err := s.execute(ctx, job)
+	result := decide(job, err)
+	s.metrics.finish(job.Kind, result, time.Since(start).Seconds())
+
+	if errors.Is(err, ErrLeaseLost) {
+		logger.Warn(ctx, "job lease lost", logger.Any("job_id", job.ID), logger.Any("kind", job.Kind))
+		return
+	}
+
+	// результат сохраняется и после отмены задачи по таймауту остановки
+	ctx = context.WithoutCancel(ctx)
+	switch result {
+	case outcomeDone:
+		err = s.repo.Complete(ctx, job.ID, job.Attempt)
+	default:
+		logger.Warn(ctx, "job failed", logger.Err(err), logger.Any("job_id", job.ID), logger.Any("kind", job.Kind),
+			logger.Any("attempt", job.Attempt), logger.Any("status", result))
+
+		status := "pending"
+		if result == outcomeDead {
+			status = "dead"
+		}
+		next := time.Now().Add(backoff.Delay(s.cfg.InitialInterval, s.cfg.MaxInterval, job.Attempt))
+		err = s.repo.Fail(ctx, job.ID, job.Attempt, status, next, err.Error())
+	}
+	if err != nil {
+		logger.Error(ctx, "job result not saved", logger.Err(err), logger.Any("job_id", job.ID))
+	}
+}
+
+// execute выполняет обработчик, продлевая lease, пока он работает
+func (s *Service) execute(ctx context.Context, job *Job) error {
+	handler, ok := s.handler(job.Kind)
+	if !ok {
+		return backoff.Permanent(ErrUnknownKind)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.heartbeat(ctx, job, cancel)
+	}()
+
+	err := s.call(ctx, handler, job)
+	if cause := context.Cause(ctx); errors.Is(cause, ErrLeaseLost) {
+		err = cause
+	}
+	cancel(nil)
+	<-done
+
+	return err
+}
+
+// call вызывает обработчик, паника становится ошибкой попытки: иначе она роняет процесс,
+// а задача после visibility timeout роняет следующую реплику
+func (s *Service) call(ctx context.Context, handler Handler, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error(ctx, "job handler panicked", logger.Any("job_id", job.ID), logger.Any("kind", job.Kind),
+				logger.Any("panic", r), logger.Any("stack", string(debug.Stack())))
+			err = fmt.Errorf("%w: %v", ErrHandlerPanic, r)
+		}
+	}()
+
+	return handler(ctx, job)
+}
+
+// heartbeat продлевает lease каждые пол visibility timeout, при потере lease отменяет обработчик
+func (s *Service) heartbeat(ctx context.Context, job *Job, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(s.cfg.VisibilityTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			owned, err := s.repo.Touch(ctx, job.ID, job.Attempt, s.cfg.VisibilityTimeout)
+			if err != nil {
+				logger.Error(ctx, "Touch", logger.Err(err), logger.Any("job_id", job.ID))
+				continue
+			}
+			if !owned {
+				cancel(ErrLeaseLost)
+				return
+			}
+		}
+	}
+}
+
+// decide определяет судьбу попытки: успех, повтор или dead после MaxAttempts и постоянных ошибок
+func decide(job *Job, err error) outcome {
+	var permanent *backoff.PermanentError
+	switch {
+	case err == nil:
+		return outcomeDone
+	case errors.As(err, &permanent), job.Attempt >= job.MaxAttempts:
+		return outcomeDead
+	default:
+		return outcomeRetry
+	}
+}
+
+// Purge удаляет задачи, завершенные раньше cfg.Retention
+func (s *Service) Purge(ctx context.Context) error {
+	if s.cfg.Retention <= 0 {
+		return nil
+	}
+
+	n, err := s.repo.Purge(ctx, time.Now().Add(-s.cfg.Retention))
+	if err != nil {
+		logger.Error(ctx, "Purge", logger.Err(err))
+		return ErrCantPurge
+	}
+	if n > 0 {
+		logger.Info(ctx, "finished jobs purged", logger.Any("count", n))
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sshlykov/shortener/internal/config"
+	repository "github.com/sshlykov/shortener/internal/pkg/jobs/repo"
+	"github.com/sshlykov/shortener/pkg/backoff"
+)
+
+type failCall struct {
+	id     int64
+	status string
+}
+
+type fakeRepo struct {
+	Repository
+
+	completed []int64
+	failed    []failCall
+}
+
+func (r *fakeRepo) Complete(_ context.Context, id int64, _ int) error {
+	r.completed = append(r.completed, id)
+	return nil
+}
+
+func (r *fakeRepo) Fail(_ context.Context, id int64, _ int, status string, _ time.Time, _ string) error {
+	r.failed = append(r.failed, failCall{id: id, status: status})
+	return nil
+}
+
+func newTestService(repo Repository) *Service {
+	return &Service{
+		repo: repo,
+		cfg: config.Jobs{
+			VisibilityTimeout: time.Minute,
+			InitialInterval:   time.Second,
+			MaxInterval:       time.Minute,
+		},
+		handlers: make(map[string]Handler),
+	}
+}
+
+func TestDecide(t *testing.T) {
+	errTemp := errors.New("temporary")
+	cases := []struct {
+		name    string
+		attempt int
+		err     error
+		want    outcome
+	}{
+		{"success", 1, nil, outcomeDone},
+		{"retry", 1, errTemp, outcomeRetry},
+		{"attempts exhausted", 3, errTemp, outcomeDead},
+		{"permanent", 1, backoff.Permanent(errTemp), outcomeDead},
+	}
+
+	for _, c := range cases {
+		job := &Job{Attempt: c.attempt, MaxAttempts: 3}
+		if got := decide(job, c.err); got != c.want {
+			t.Errorf("%s: got %s, want %s", c.name, got, c.want)
+		}
+	}
+}
+
+func TestProcess(t *testing.T) {
+	repo := &fakeRepo{}
+	s := newTestService(repo)
+	s.Register("ok", func(context.Context, *Job) error { return nil })
+	s.Register("broken", func(context.Context, *Job) error { return errors.New("boom") })
+	s.Register("invalid", func(context.Context, *Job) error { return backoff.Permanent(errors.New("bad payload")) })
+	s.Register("panics", func(context.Context, *Job) error { panic("nil map") })
+
+	ctx := context.Background()
+	s.process(ctx, jobFromRepo(&repository.Job{JobID: 1, Kind: "ok", Attempts: 1, MaxAttempts: 5}))
+	s.process(ctx, jobFromRepo(&repository.Job{JobID: 2, Kind: "broken", Attempts: 1, MaxAttempts: 5}))
+	s.process(ctx, jobFromRepo(&repository.Job{JobID: 3, Kind: "invalid", Attempts: 1, MaxAttempts: 5}))
+	s.process(ctx, jobFromRepo(&repository.Job{JobID: 4, Kind: "missing", Attempts: 1, MaxAttempts: 5}))
+	s.process(ctx, jobFromRepo(&repository.Job{JobID: 5, Kind: "panics", Attempts: 1, MaxAttempts: 5}))
+	s.process(ctx, jobFromRepo(&repository.Job{JobID: 6, Kind: "panics", Attempts: 5, MaxAttempts: 5}))
+
+	if len(repo.completed) != 1 || repo.completed[0] != 1 {
+		t.Errorf("expected job 1 completed, got %v", repo.completed)
+	}
+	want := []failCall{{2, "pending"}, {3, "dead"}, {4, "dead"}, {5, "pending"}, {6, "dead"}}
+	if len(repo.failed) != len(want) {
+		t.Fatalf("expected %d failed jobs, got %v", len(want), repo.failed)
+	}
+	for i := range want {
+		if repo.failed[i] != want[i] {
+			t.Errorf("failed[%d] = %+v, want %+v", i, repo.failed[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sshlykov/shortener/pkg/postgres"
+)
+
+type Link struct {
+	LinkID    int32      `db:"link_id"`
+	URL       string     `db:"url"`
+	Key       string     `db:"key"`
+	Owner     string     `db:"owner"`
+	CreatedAt time.Time  `db:"created_at"`
+	UpdatedAt time.Time  `db:"updated_at"`
+	ExpiresAt *time.Time `db:"expires_at"`
+}
+
+type Filter struct {
+	Owner  string
+	Limit  int
+	Offset int
+}
+
+type Repository struct {
+	db postgres.DB
+}
+
+func New(db postgres.Client) *Repository {
+	return &Repository{db: db.DB()}
+}
+
+const linkColumns = `link_id, url, key, owner, created_at, updated_at, expires_at`
+
+const getLinkByKey = `
+SELECT ` + linkColumns + `
+FROM links
+WHERE key = $1`
+
+func (r *Repository) GetByKey(ctx context.Context, key string) (*Link, error) {
+	var link Link
+	q := postgres.Query{Name: "links.get_by_key", Raw: getLinkByKey, ReadOnly: true}
+	if err := r.db.ScanSingleContext(ctx, q, &link, key); err != nil {
+		return nil, err
+	}
+
+	return &link, nil
+}
+
+const getLinkByURL = `
+SELECT ` + linkColumns + `
+FROM links
+WHERE owner = $1
+  AND url = $2
+ORDER BY link_id
+LIMIT 1`
+
+// GetByURL ищет ссылку владельца на url, поиск идет по индексу владельца
+func (r *Repository) GetByURL(ctx context.Context, owner, url string) (*Link, error) {
+	var link Link
+	q := postgres.Query{Name: "links.get_by_url", Raw: getLinkByURL}
+	if err := r.db.ScanSingleContext(ctx, q, &link, owner, url); err != nil {
+		return nil, err
+	}
+
+	return &link, nil
+}
+
+const nextLinkID = `SELECT nextval(pg_get_serial_sequence('links', 'link_id'))`
+
+func (r *Repository) NextID(ctx context.Context) (int32, error) {
+	var id int32
+	q := postgres.Query{Name: "links.next_id", Raw: nextLinkID}
+	err := r.db.QueryRowContext(ctx, q).Scan(&id)
+
+	return id, err
+}
+
+const insertLink = `
+INSERT INTO links (link_id, url, key, owner, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING ` + linkColumns
+
+func (r *Repository) Insert(ctx context.Context, link *Link) (*Link, error) {
+	var created Link
+	q := postgres.Query{Name: "links.insert", Raw: insertLink}
+	err := r.db.ScanSingleContext(ctx, q, &created, link.LinkID, link.URL, link.Key, link.Owner, link.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+const nextLinkIDs = `SELECT nextval(pg_get_serial_sequence('links', 'link_id'))::int FROM generate_series(1, $1)`
+
+func (r *Repository) NextIDs(ctx context.Context, n int) ([]int32, error) {
+	var ids []int32
+	q := postgres.Query{Name: "links.next_ids", Raw: nextLinkIDs}
+	if err := r.db.ScanAllContext(ctx, q, &ids, n); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+const insertLinkIfAbsent = `
+INSERT INTO links (link_id, url, key, owner, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (key) DO NOTHING
+RETURNING ` + linkColumns
+
+// InsertBatch вставляет ссылки одним батчем. Для занятого ключа в результате nil,
+// поэтому конфликт одной строки не прерывает транзакцию
+func (r *Repository) InsertBatch(ctx context.Context, links []Link) ([]*Link, error) {
+	created := make([]*Link, len(links))
+	q := postgres.Query{Name: "links.insert_if_absent", Raw: insertLinkIfAbsent}
+
+	b := postgres.NewBatch("links.insert_batch")
+	for i, link := range links {
+		b.QueueRow(q, func(row pgx.Row) error {
+			var l Link
+			err := row.Scan(&l.LinkID, &l.URL, &l.Key, &l.Owner, &l.CreatedAt, &l.UpdatedAt, &l.ExpiresAt)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil
+			}
+			if err == nil {
+				created[i] = &l
+			}
+			return err
+		}, link.LinkID, link.URL, link.Key, link.Owner, link.ExpiresAt)
+	}
+
+	results, err := r.db.SendBatchContext(ctx, b)
+	if err == nil {
+		err = postgres.BatchErr(results)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+const setImportedClicks = `
+UPDATE links
+SET imported_clicks = $2
+WHERE key = $1`
+
+// SetImportedClicks сохраняет число кликов, перенесенное из другого сервиса
+func (r *Repository) SetImportedClicks(ctx context.Context, key string, clicks int64) error {
+	q := postgres.Query{Name: "links.set_imported_clicks", Raw: setImportedClicks}
+	_, err := r.db.ExecContext(ctx, q, key, clicks)
+
+	return err
+}
+
+// $3 - менять ли срок жизни, тогда $4 - новый срок или NULL для бессрочной ссылки
+const updateLink = `
+UPDATE links
+SET url        = coalesce($2, url),
+    expires_at = CASE WHEN $3 THEN $4::timestamptz ELSE expires_at END,
+    expired_at = CASE WHEN $3 THEN NULL ELSE expired_at END,
+    updated_at = now()
+WHERE key = $1
+RETURNING ` + linkColumns
+
+// Update меняет url, если он не nil, и срок жизни, если setExpiry
+func (r *Repository) Update(ctx context.Context, key string, url *string, setExpiry bool,
+	expiresAt *time.Time) (*Link, error) {
+	var updated Link
+	q := postgres.Query{Name: "links.update", Raw: updateLink}
+	if err := r.db.ScanSingleContext(ctx, q, &updated, key, url, setExpiry, expiresAt); err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+const deleteLink = `
+DELETE
+FROM links
+WHERE key = $1
+RETURNING ` + linkColumns
+
+func (r *Repository) Delete(ctx context.Context, key string) (*Link, error) {
+	var deleted Link
+	q := postgres.Query{Name: "links.delete", Raw: deleteLink}
+	if err := r.db.ScanSingleContext(ctx, q, &deleted, key); err != nil {
+		return nil, err
+	}
+
+	return &deleted, nil
+}
+
+const listLinks = `
+SELECT ` + linkColumns + `
+FROM links
+WHERE ($1 = '' OR owner = $1)
+ORDER BY link_id
+LIMIT $2 OFFSET $3`
+
+func (r *Repository) List(ctx context.Context, filter Filter) ([]Link, error) {
+	var links []Link
+	q := postgres.Query{Name: "links.list", Raw: listLinks, ReadOnly: true}
+	if err := r.db.ScanAllContext(ctx, q, &links, filter.Owner, filter.Limit, filter.Offset); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// ExportRow - ссылка с числом кликов, Clicks заполняется только по запросу
+type ExportRow struct {
+	Link
+	Clicks *int64 `db:"clicks"`
+}
+
+const declareExport = `
+DECLARE links_export NO SCROLL CURSOR FOR
+SELECT ` + linkColumns + `,
+       CASE
+           WHEN $2 THEN imported_clicks + (SELECT count(*) FROM clicks c WHERE c.link_id = links.link_id)
+           END AS clicks
+FROM links
+WHERE ($1 = '' OR owner = $1)
+ORDER BY link_id`
+
+// DeclareExport открывает курсор выгрузки, вызывается внутри транзакции
+func (r *Repository) DeclareExport(ctx context.Context, owner string, withClicks bool) error {
+	q := postgres.Query{Name: "links.export_declare", Raw: declareExport}
+	_, err := r.db.ExecContext(ctx, q, owner, withClicks)
+
+	return err
+}
+
+// FetchExport читает следующие n строк курсора выгрузки
+func (r *Repository) FetchExport(ctx context.Context, n int) ([]ExportRow, error) {
+	var rows []ExportRow
+	q := postgres.Query{Name: "links.export_fetch", Raw: fmt.Sprintf("FETCH FORWARD %d FROM links_export", n)}
+	if err := r.db.ScanAllContext(ctx, q, &rows); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+const expireDueLinks = `
+UPDATE links
+SET expired_at = now()
+WHERE link_id IN (SELECT link_id
+                  FROM links
+                  WHERE expires_at <= now()
+                    AND expired_at IS NULL
+                  ORDER BY expires_at
+                  LIMIT $1 FOR UPDATE SKIP LOCKED)
+RETURNING ` + linkColumns
+
+// ExpireDue помечает истекшие ссылки, чтобы событие об истечении было отправлено один раз
+func (r *Repository) ExpireDue(ctx context.Context, limit int) ([]Link, error) {
+	var links []Link
+	q := postgres.Query{Name: "links.expire_due", Raw: expireDueLinks}
+	if err := r.db.ScanAllContext(ctx, q, &links, limit); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+
+	"github.com/sshlykov/shortener/internal/domain"
+	repository "github.com/sshlykov/shortener/internal/pkg/links/repo"
+	shorten "github.com/sshlykov/shortener/internal/pkg/shorten/service"
+	"github.com/sshlykov/shortener/pkg/logger"
+)
+
+// BatchResult - результат создания ссылки из пакета, индекс совпадает с индексом во входных данных
+type BatchResult struct {
+	Link *domain.Link
+	Err  error
+}
+
+// CreateBatch создает ссылки одной транзакцией. Каждая строка проверяется отдельно:
+// невалидная строка или занятый ключ не мешают остальным, ошибка базы откатывает весь пакет
+func (s *Service) CreateBatch(ctx context.Context, links []domain.Link) []BatchResult {
+	results := make([]BatchResult, len(links))
+
+	valid := make([]int, 0, len(links))
+	for i := range links {
+		if err := prepare(&links[i]); err != nil {
+			results[i].Err = err
+			continue
+		}
+		valid = append(valid, i)
+	}
+	if len(valid) == 0 {
+		return results
+	}
+
+	var created map[int]*domain.Link
+	err := s.tx.ReadCommitted(ctx, func(ctx context.Context) error {
+		var err error
+		created, err = s.insertBatch(ctx, links, valid)
+		return err
+	})
+	if err != nil {
+		logger.Error(ctx, "CreateBatch", logger.Err(err), logger.Any("rows", len(valid)))
+	}
+
+	for _, i := range valid {
+		switch link, ok := created[i]; {
+		case err != nil:
+			results[i].Err = ErrCantCreateLink
+		case !ok:
+			results[i].Err = ErrCantCreateLink
+		case link == nil:
+			results[i].Err = ErrKeyTaken
+		default:
+			results[i].Link = link
+		}
+	}
+
+	return results
+}
+
+func prepare(link *domain.Link) error {
+	var err error
+	if link.URL, err = NormalizeURL(link.URL); err != nil {
+		return err
+	}
+	if link.Key != "" {
+		return ValidateKey(link.Key)
+	}
+
+	return nil
+}
+
+// insertBatch вставляет строки pending. Сгенерированный ключ может совпасть с пользовательским,
+// такие строки получают новый id и повторяются, как в Create. Для занятого пользовательского ключа - nil
+func (s *Service) insertBatch(ctx context.Context, links []domain.Link, pending []int) (map[int]*domain.Link, error) {
+	created := make(map[int]*domain.Link, len(pending))
+
+	for attempt := 0; attempt < generateAttempts && len(pending) > 0; attempt++ {
+		ids, err := s.repo.NextIDs(ctx, len(pending))
+		if err != nil {
+			return nil, err
+		}
+
+		rows := make([]repository.Link, len(pending))
+		for j, i := range pending {
+			link := links[i]
+			rows[j] = repository.Link{LinkID: ids[j], URL: link.URL, Key: link.Key, Owner: link.Owner,
+				ExpiresAt: link.ExpiresAt}
+			if rows[j].Key == "" {
+				rows[j].Key = shorten.Shorten(uint32(ids[j]))
+			}
+		}
+
+		inserted, err := s.repo.InsertBatch(ctx, rows)
+		if err != nil {
+			return nil, err
+		}
+
+		var retry []int
+		for j, i := range pending {
+			if inserted[j] == nil {
+				if links[i].Key == "" {
+					retry = append(retry, i)
+				} else {
+					created[i] = nil
+				}
+				continue
+			}
+
+			link := toDomain(inserted[j])
+			if err = s.publish(ctx, domain.EventLinkCreated, link); err != nil {
+				return nil, err
+			}
+			created[i] = link
+		}
+		pending = retry
+	}
+
+	return created, nil
+}
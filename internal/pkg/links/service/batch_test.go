@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sshlykov/shortener/internal/domain"
+	repository "github.com/sshlykov/shortener/internal/pkg/links/repo"
+	shorten "github.com/sshlykov/shortener/internal/pkg/shorten/service"
+	"github.com/sshlykov/shortener/pkg/postgres/pgtest"
+)
+
+type batchRepo struct {
+	Repository
+	lastID int32
+	keys   map[string]bool
+}
+
+func (r *batchRepo) NextIDs(_ context.Context, n int) ([]int32, error) {
+	ids := make([]int32, n)
+	for i := range ids {
+		r.lastID++
+		ids[i] = r.lastID
+	}
+	return ids, nil
+}
+
+func (r *batchRepo) InsertBatch(_ context.Context, links []repository.Link) ([]*repository.Link, error) {
+	created := make([]*repository.Link, len(links))
+	for i, link := range links {
+		if r.keys[link.Key] {
+			continue
+		}
+		r.keys[link.Key] = true
+		created[i] = &link
+	}
+	return created, nil
+}
+
+type countPublisher struct {
+	events int
+}
+
+func (p *countPublisher) Publish(context.Context, domain.Event) error {
+	p.events++
+	return nil
+}
+
+func TestCreateBatch(t *testing.T) {
+	// первый сгенерированный ключ уже занят пользовательской ссылкой
+	repo := &batchRepo{keys: map[string]bool{"taken": true, shorten.Shorten(2): true}}
+	publisher := &countPublisher{}
+	s := &Service{repo: repo, tx: pgtest.PassTx{}, publisher: publisher}
+
+	results := s.CreateBatch(context.Background(), []domain.Link{
+		{Key: "docs", URL: "HTTPS://Example.com/docs"},
+		{URL: "ftp://example.com"},
+		{URL: "https://example.com/generated"},
+		{Key: "taken", URL: "https://example.com"},
+		{Key: "docs", URL: "https://example.com/again"},
+	})
+
+	if l := results[0].Link; l == nil || l.URL != "https://example.com/docs" {
+		t.Errorf("row 0 = %+v", results[0])
+	}
+	if !errors.Is(results[1].Err, ErrInvalidURL) {
+		t.Errorf("row 1 error = %v, want ErrInvalidURL", results[1].Err)
+	}
+	if l := results[2].Link; l == nil || l.Key == shorten.Shorten(2) {
+		t.Errorf("row 2 = %+v, want a fresh generated key", results[2])
+	}
+	for _, i := range []int{3, 4} {
+		if !errors.Is(results[i].Err, ErrKeyTaken) {
+			t.Errorf("row %d error = %v, want ErrKeyTaken", i, results[i].Err)
+		}
+	}
+	if publisher.events != 2 {
+		t.Errorf("published %d events, want 2", publisher.events)
+	}
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/sshlykov/shortener/internal/domain"
+)
+
+// recentCache - LRU последних отданных редиректов. Используется только пока база недоступна:
+// изменения на других репликах в него не попадают, поэтому при доступной базе он не читается
+type recentCache struct {
+	size int
+
+	mu    sync.Mutex
+	order *list.List
+	items map[string]*list.Element
+}
+
+func newRecentCache(size int) *recentCache {
+	if size <= 0 {
+		return nil
+	}
+
+	return &recentCache{size: size, order: list.New(), items: make(map[string]*list.Element, size)}
+}
+
+func (c *recentCache) get(key string) (*domain.Link, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	link := *el.Value.(*domain.Link)
+
+	return &link, true
+}
+
+func (c *recentCache) put(link *domain.Link) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stored := *link
+	if el, ok := c.items[link.Key]; ok {
+		el.Value = &stored
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[link.Key] = c.order.PushFront(&stored)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*domain.Link).Key)
+	}
+}
+
+func (c *recentCache) remove(key string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.order.Remove(el)
+		delete(c.items, key)
+	}
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/sshlykov/shortener/internal/domain"
+)
+
+func TestRecentCache(t *testing.T) {
+	c := newRecentCache(2)
+	c.put(&domain.Link{Key: "a", URL: "https://a.example.com"})
+	c.put(&domain.Link{Key: "b", URL: "https://b.example.com"})
+
+	// a становится самым свежим, вытесняется b
+	if _, ok := c.get("a"); !ok {
+		t.Fatal("a is missing")
+	}
+	c.put(&domain.Link{Key: "c", URL: "https://c.example.com"})
+	if _, ok := c.get("b"); ok {
+		t.Error("b should be evicted")
+	}
+
+	c.put(&domain.Link{Key: "a", URL: "https://a2.example.com"})
+	if link, _ := c.get("a"); link.URL != "https://a2.example.com" {
+		t.Errorf("a = %+v, want updated url", link)
+	}
+
+	c.remove("a")
+	if _, ok := c.get("a"); ok {
+		t.Error("a should be removed")
+	}
+
+	var disabled *recentCache = newRecentCache(0)
+	disabled.put(&domain.Link{Key: "a"})
+	if _, ok := disabled.get("a"); ok {
+		t.Error("disabled cache returned a link")
+	}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+
+	"github.com/sshlykov/shortener/internal/domain"
+	repository "github.com/sshlykov/shortener/internal/pkg/links/repo"
+	shorten "github.com/sshlykov/shortener/internal/pkg/shorten/service"
+	"github.com/sshlykov/shortener/pkg/logger"
+	"github.com/sshlykov/shortener/pkg/postgres"
+)
+
+// сгенерированный ключ может совпасть с пользовательским, тогда берем следующий id
+const generateAttempts = 3
+
+// Create создает ссылку, если ключ не задан - он генерируется из id ссылки
+func (s *Service) Create(ctx context.Context, link domain.Link) (*domain.Link, error) {
+	var err error
+	if link.URL, err = NormalizeURL(link.URL); err != nil {
+		return nil, err
+	}
+	if link.Key != "" {
+		if err = ValidateKey(link.Key); err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; attempt < generateAttempts; attempt++ {
+		var result *domain.Link
+		err = s.tx.ReadCommitted(ctx, func(ctx context.Context) error {
+			row := &repository.Link{URL: link.URL, Key: link.Key, Owner: link.Owner, ExpiresAt: link.ExpiresAt}
+			var err error
+			if row.LinkID, err = s.repo.NextID(ctx); err != nil {
+				return err
+			}
+			if row.Key == "" {
+				row.Key = shorten.Shorten(uint32(row.LinkID))
+			}
+
+			created, err := s.repo.Insert(ctx, row)
+			if err != nil {
+				return err
+			}
+
+			result = toDomain(created)
+			return s.publish(ctx, domain.EventLinkCreated, result)
+		})
+		if postgres.IsUniqueViolation(err) {
+			if link.Key != "" {
+				return nil, ErrKeyTaken
+			}
+			continue
+		}
+		if err != nil {
+			logger.Error(ctx, "Create", logger.Err(err), logger.Any("key", link.Key))
+
+			return nil, ErrCantCreateLink
+		}
+
+		return result, nil
+	}
+
+	return nil, ErrCantCreateLink
+}
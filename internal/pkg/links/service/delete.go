@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sshlykov/shortener/internal/domain"
+	"github.com/sshlykov/shortener/pkg/logger"
+)
+
+func (s *Service) Delete(ctx context.Context, key string) error {
+	err := s.tx.ReadCommitted(ctx, func(ctx context.Context) error {
+		deleted, err := s.repo.Delete(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		return s.publish(ctx, domain.EventLinkDeleted, toDomain(deleted))
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrLinkNotFound
+	}
+	if err != nil {
+		logger.Error(ctx, "Delete", logger.Err(err), logger.Any("key", key))
+
+		return ErrCantDeleteLink
+	}
+	s.cache.remove(key)
+
+	return nil
+}
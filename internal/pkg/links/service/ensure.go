@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sshlykov/shortener/internal/domain"
+	"github.com/sshlykov/shortener/pkg/postgres"
+)
+
+// Ensure создает ссылку, если такой еще нет, и сообщает, была ли она создана. Ссылка с ключом считается
+// существующей, если ключ ведет на тот же url того же владельца, ссылка без ключа - если у владельца
+// есть ссылка на этот url. Ключ, занятый другой ссылкой, - ErrKeyConflict.
+// Поиск идет в primary: реплика может еще не видеть ссылку, созданную предыдущей строкой загрузки
+func (s *Service) Ensure(ctx context.Context, link domain.Link) (*domain.Link, bool, error) {
+	var err error
+	if link.URL, err = NormalizeURL(link.URL); err != nil {
+		return nil, false, err
+	}
+
+	lookupCtx := postgres.PinPrimary(ctx)
+	var existing *domain.Link
+	if link.Key != "" {
+		if err = ValidateKey(link.Key); err != nil {
+			return nil, false, err
+		}
+		existing, err = s.Get(lookupCtx, link.Key)
+	} else {
+		existing, err = s.FindByURL(lookupCtx, link.Owner, link.URL)
+	}
+
+	switch {
+	case err == nil && (existing.URL != link.URL || existing.Owner != link.Owner):
+		return nil, false, ErrKeyConflict
+	case err == nil:
+		return existing, false, nil
+	case !errors.Is(err, ErrLinkNotFound):
+		return nil, false, err
+	}
+
+	created, err := s.Create(ctx, link)
+	if errors.Is(err, ErrKeyTaken) {
+		return nil, false, ErrKeyConflict
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return created, true, nil
+}
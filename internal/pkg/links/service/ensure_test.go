@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/sshlykov/shortener/internal/domain"
+	repository "github.com/sshlykov/shortener/internal/pkg/links/repo"
+	"github.com/sshlykov/shortener/pkg/postgres"
+	"github.com/sshlykov/shortener/pkg/postgres/pgtest"
+)
+
+type ensureRepo struct {
+	Repository
+	links  map[string]repository.Link
+	lastID int32
+	// raced - ключ, который занимают между поиском и вставкой
+	raced string
+}
+
+func (r *ensureRepo) GetByKey(_ context.Context, key string) (*repository.Link, error) {
+	if link, ok := r.links[key]; ok {
+		return &link, nil
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (r *ensureRepo) GetByURL(_ context.Context, owner, url string) (*repository.Link, error) {
+	for _, link := range r.links {
+		if link.Owner == owner && link.URL == url {
+			return &link, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (r *ensureRepo) NextID(context.Context) (int32, error) {
+	r.lastID++
+	return r.lastID, nil
+}
+
+func (r *ensureRepo) Insert(_ context.Context, link *repository.Link) (*repository.Link, error) {
+	if link.Key == r.raced {
+		return nil, &pgconn.PgError{Code: postgres.CodeUniqueViolation}
+	}
+	r.links[link.Key] = *link
+	return link, nil
+}
+
+func TestEnsure(t *testing.T) {
+	repo := &ensureRepo{links: map[string]repository.Link{
+		"docs": {LinkID: 100, Key: "docs", URL: "https://example.com/docs", Owner: "alice"},
+	}, raced: "raced"}
+	s := &Service{repo: repo, tx: pgtest.PassTx{}, publisher: &countPublisher{}}
+
+	tests := []struct {
+		name    string
+		link    domain.Link
+		created bool
+		err     error
+	}{
+		{name: "same key and url", link: domain.Link{Key: "docs", URL: "HTTPS://Example.com/docs", Owner: "alice"}},
+		{name: "key of another owner", link: domain.Link{Key: "docs", URL: "https://example.com/docs", Owner: "bob"}, err: ErrKeyConflict},
+		{name: "key to another url", link: domain.Link{Key: "docs", URL: "https://example.com/other", Owner: "alice"}, err: ErrKeyConflict},
+		{name: "owner already has url", link: domain.Link{URL: "https://example.com/docs", Owner: "alice"}},
+		{name: "new key", link: domain.Link{Key: "guide", URL: "https://example.com/guide", Owner: "alice"}, created: true},
+		{name: "new url", link: domain.Link{URL: "https://example.com/docs", Owner: "bob"}, created: true},
+		{name: "key taken after lookup", link: domain.Link{Key: "raced", URL: "https://example.com", Owner: "alice"}, err: ErrKeyConflict},
+		{name: "invalid key", link: domain.Link{Key: "no", URL: "https://example.com", Owner: "alice"}, err: ErrInvalidKey},
+		{name: "invalid url", link: domain.Link{URL: "ftp://example.com", Owner: "alice"}, err: ErrInvalidURL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link, created, err := s.Ensure(context.Background(), tt.link)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Ensure() error = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if created != tt.created || link.Owner != tt.link.Owner {
+				t.Errorf("Ensure() = %+v, created %v, want created %v", link, created, tt.created)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package service
+
+import "errors"
+
+var (
+	ErrLinkNotFound    = errors.New("link not found")
+	ErrLinkExpired     = errors.New("link expired")
+	ErrInvalidURL      = errors.New("invalid url")
+	ErrInvalidKey      = errors.New("invalid key")
+	ErrKeyTaken        = errors.New("key is already taken")
+	ErrKeyConflict     = errors.New("key is taken by a link to another url")
+	ErrCantGetLink     = errors.New("can't get link")
+	ErrCantCreateLink  = errors.New("can't create link")
+	ErrCantUpdateLink  = errors.New("can't update link")
+	ErrCantDeleteLink  = errors.New("can't delete link")
+	ErrCantListLinks   = errors.New("can't list links")
+	ErrCantExpireLinks = errors.New("can't expire links")
+	ErrCantExportLinks = errors.New("can't export links")
+	ErrTooManyExports  = errors.New("too many exports in progress")
+	ErrExportRestarted = errors.New("export transaction restarted")
+)
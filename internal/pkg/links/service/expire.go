@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+
+	"github.com/sshlykov/shortener/internal/domain"
+	"github.com/sshlykov/shortener/pkg/logger"
+)
+
+// ExpireDue находит истекшие ссылки и публикует для каждой событие link.expired
+func (s *Service) ExpireDue(ctx context.Context) error {
+	for {
+		var n int
+		err := s.tx.ReadCommitted(ctx, func(ctx context.Context) error {
+			rows, err := s.repo.ExpireDue(ctx, s.cfg.ExpireBatchSize)
+			if err != nil {
+				return err
+			}
+			n = len(rows)
+
+			for i := range rows {
+				if err = s.publish(ctx, domain.EventLinkExpired, toDomain(&rows[i])); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			logger.Error(ctx, "ExpireDue", logger.Err(err))
+
+			return ErrCantExpireLinks
+		}
+
+		if n < s.cfg.ExpireBatchSize {
+			return nil
+		}
+	}
+}
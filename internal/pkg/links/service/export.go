@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sshlykov/shortener/internal/domain"
+	"github.com/sshlykov/shortener/pkg/logger"
+	"github.com/sshlykov/shortener/pkg/postgres"
+)
+
+const defaultExportFetchSize = 1000
+
+type ExportFilter struct {
+	Owner string
+	// WithClicks добавляет к ссылкам общее число кликов
+	WithClicks bool
+}
+
+// ExportedLink - ссылка из выгрузки, Clicks заполнен при ExportFilter.WithClicks
+type ExportedLink struct {
+	domain.Link
+	Clicks *int64
+}
+
+// Export передает в fn все ссылки по фильтру в порядке id. Строки читаются из курсора порциями
+// по cfg.Export.FetchSize, поэтому память не растет с размером выгрузки, а statement_timeout
+// применяется к каждой порции, а не ко всей выгрузке. Ошибка fn прерывает выгрузку и возвращается как есть.
+// Транзакция курсора живет, пока клиент читает ответ, поэтому она только читает и уходит на реплику,
+// а одновременных выгрузок не больше cfg.Export.MaxConcurrent, сверх лимита - ErrTooManyExports.
+// TxManager повторяет транзакцию после 40001, а на реплике так приходят конфликты с восстановлением.
+// До первой строки повтор безопасен, после - выгрузка прерывается: иначе клиент получил бы строки дважды
+func (s *Service) Export(ctx context.Context, filter ExportFilter, fn func(link *ExportedLink) error) error {
+	if s.exports != nil {
+		select {
+		case s.exports <- struct{}{}:
+			defer func() { <-s.exports }()
+		default:
+			return ErrTooManyExports
+		}
+	}
+
+	fetchSize := s.cfg.Export.FetchSize
+	if fetchSize <= 0 {
+		fetchSize = defaultExportFetchSize
+	}
+
+	var (
+		fnErr   error
+		emitted int
+	)
+	err := s.tx.ReadCommitted(postgres.ReadOnlyTx(ctx), func(ctx context.Context) error {
+		if emitted > 0 {
+			return fmt.Errorf("%w after %d rows", ErrExportRestarted, emitted)
+		}
+		if err := s.repo.DeclareExport(ctx, filter.Owner, filter.WithClicks); err != nil {
+			return err
+		}
+
+		for {
+			rows, err := s.repo.FetchExport(ctx, fetchSize)
+			if err != nil {
+				return err
+			}
+
+			for i := range rows {
+				link := &ExportedLink{Link: *toDomain(&rows[i].Link), Clicks: rows[i].Clicks}
+				if fnErr = fn(link); fnErr != nil {
+					return fnErr
+				}
+				emitted++
+			}
+			if len(rows) < fetchSize {
+				return nil
+			}
+		}
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		logger.Error(ctx, "Export", logger.Err(err), logger.Any("owner", filter.Owner))
+
+		return ErrCantExportLinks
+	}
+
+	return err
+}
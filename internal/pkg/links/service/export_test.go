@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/sshlykov/shortener/internal/config"
+	repository "github.com/sshlykov/shortener/internal/pkg/links/repo"
+	"github.com/sshlykov/shortener/pkg/postgres"
+	"github.com/sshlykov/shortener/pkg/postgres/pgtest"
+)
+
+type exportRepo struct {
+	Repository
+	total, fetched int
+	fetches        int
+	// failOn - номер порции, на которой реплика отменяет запрос конфликтом с восстановлением
+	failOn int
+}
+
+func (r *exportRepo) DeclareExport(context.Context, string, bool) error {
+	r.fetched = 0
+	return nil
+}
+
+func (r *exportRepo) FetchExport(_ context.Context, n int) ([]repository.ExportRow, error) {
+	r.fetches++
+	if r.fetches == r.failOn {
+		return nil, &pgconn.PgError{Code: postgres.CodeSerializationFailure}
+	}
+	rows := make([]repository.ExportRow, 0, n)
+	for ; r.fetched < r.total && len(rows) < n; r.fetched++ {
+		rows = append(rows, repository.ExportRow{Link: repository.Link{LinkID: int32(r.fetched + 1)}})
+	}
+	return rows, nil
+}
+
+func TestExportFetchesInPortions(t *testing.T) {
+	repo := &exportRepo{total: 5}
+	s := &Service{repo: repo, tx: pgtest.PassTx{}, cfg: config.Links{Export: config.LinksExport{FetchSize: 2}}}
+
+	var ids []int32
+	err := s.Export(context.Background(), ExportFilter{}, func(link *ExportedLink) error {
+		ids = append(ids, link.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 5 || ids[4] != 5 || repo.fetches != 3 {
+		t.Errorf("ids = %v, fetches = %d", ids, repo.fetches)
+	}
+
+	repo.fetched = 0
+	stop := errors.New("client gone")
+	err = s.Export(context.Background(), ExportFilter{}, func(*ExportedLink) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Errorf("Export() error = %v, want callback error", err)
+	}
+}
+
+func TestExportLimit(t *testing.T) {
+	repo := &exportRepo{total: 1}
+	s := &Service{repo: repo, tx: pgtest.PassTx{}, exports: newExportLimit(1)}
+
+	err := s.Export(context.Background(), ExportFilter{}, func(*ExportedLink) error {
+		if err := s.Export(context.Background(), ExportFilter{}, func(*ExportedLink) error { return nil }); !errors.Is(err, ErrTooManyExports) {
+			t.Errorf("nested Export() error = %v, want ErrTooManyExports", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo.fetched = 0
+	if err = s.Export(context.Background(), ExportFilter{}, func(*ExportedLink) error { return nil }); err != nil {
+		t.Errorf("Export() after release error = %v", err)
+	}
+}
+
+// retryTx повторяет handler после retryable ошибки, как TxManager
+type retryTx struct {
+	pgtest.PassTx
+	attempts int
+}
+
+func (tx *retryTx) ReadCommitted(ctx context.Context, h postgres.Handler) error {
+	for {
+		tx.attempts++
+		err := h(ctx)
+		if !postgres.IsRetryable(err) || tx.attempts > 3 {
+			return err
+		}
+	}
+}
+
+func TestExportRetry(t *testing.T) {
+	tests := []struct {
+		name    string
+		failOn  int
+		wantIDs int
+		wantErr error
+	}{
+		{name: "conflict before the first row", failOn: 1, wantIDs: 5},
+		{name: "conflict after rows were sent", failOn: 2, wantIDs: 2, wantErr: ErrCantExportLinks},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &exportRepo{total: 5, failOn: tt.failOn}
+			tx := &retryTx{}
+			s := &Service{repo: repo, tx: tx, cfg: config.Links{Export: config.LinksExport{FetchSize: 2}}}
+
+			var ids []int32
+			err := s.Export(context.Background(), ExportFilter{}, func(link *ExportedLink) error {
+				ids = append(ids, link.ID)
+				return nil
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Export() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(ids) != tt.wantIDs || tx.attempts != 2 {
+				t.Errorf("ids = %v after %d attempts, want %d rows without duplicates", ids, tx.attempts, tt.wantIDs)
+			}
+		})
+	}
+}
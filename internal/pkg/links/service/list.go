@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+
+	"github.com/sshlykov/shortener/internal/domain"
+	repository "github.com/sshlykov/shortener/internal/pkg/links/repo"
+	"github.com/sshlykov/shortener/pkg/logger"
+)
+
+type Filter struct {
+	Owner  string
+	Limit  int
+	Offset int
+}
+
+func (s *Service) List(ctx context.Context, filter Filter) ([]*domain.Link, error) {
+	rows, err := s.repo.List(ctx, repository.Filter{
+		Owner:  filter.Owner,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	})
+	if err != nil {
+		logger.Error(ctx, "List", logger.Err(err))
+
+		return nil, ErrCantListLinks
+	}
+
+	links := make([]*domain.Link, 0, len(rows))
+	for i := range rows {
+		links = append(links, toDomain(&rows[i]))
+	}
+
+	return links, nil
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sshlykov/shortener/internal/domain"
+	"github.com/sshlykov/shortener/pkg/logger"
+)
+
+// Resolve возвращает ссылку для редиректа, истекшие ссылки не отдаются.
+// При ошибке или недоступности базы ссылка ищется в кэше последних редиректов, затем в fallback.
+// Ключа нет и там - остается ErrCantGetLink: снапшот может отставать, поэтому это не 404
+func (s *Service) Resolve(ctx context.Context, key string) (*domain.Link, error) {
+	var link *domain.Link
+	err := ErrCantGetLink
+	if s.degraded == nil || !s.degraded() {
+		link, err = s.Get(ctx, key)
+	}
+
+	switch {
+	case err == nil:
+		s.cache.put(link)
+	case errors.Is(err, ErrCantGetLink):
+		if fallback, ok := s.resolveFallback(key); ok {
+			logger.Debug(ctx, "link resolved without database", logger.Any("key", key))
+			link, err = fallback, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if link.Expired(time.Now()) {
+		return nil, ErrLinkExpired
+	}
+
+	return link, nil
+}
+
+func (s *Service) resolveFallback(key string) (*domain.Link, bool) {
+	if link, ok := s.cache.get(key); ok {
+		return link, true
+	}
+	if s.fallback != nil {
+		return s.fallback.Lookup(key)
+	}
+
+	return nil, false
+}
+
+func (s *Service) Get(ctx context.Context, key string) (*domain.Link, error) {
+	link, err := s.repo.GetByKey(ctx, key)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrLinkNotFound
+	}
+	if err != nil {
+		logger.Error(ctx, "Get", logger.Err(err), logger.Any("key", key))
+
+		return nil, ErrCantGetLink
+	}
+
+	return toDomain(link), nil
+}
+
+// FindByURL возвращает самую старую ссылку владельца на url, url должен быть нормализован
+func (s *Service) FindByURL(ctx context.Context, owner, url string) (*domain.Link, error) {
+	link, err := s.repo.GetByURL(ctx, owner, url)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrLinkNotFound
+	}
+	if err != nil {
+		logger.Error(ctx, "FindByURL", logger.Err(err), logger.Any("owner", owner))
+
+		return nil, ErrCantGetLink
+	}
+
+	return toDomain(link), nil
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sshlykov/shortener/internal/domain"
+	repository "github.com/sshlykov/shortener/internal/pkg/links/repo"
+)
+
+type downRepo struct {
+	Repository
+	links map[string]repository.Link
+	calls int
+}
+
+func (r *downRepo) GetByKey(_ context.Context, key string) (*repository.Link, error) {
+	r.calls++
+	if link, ok := r.links[key]; ok {
+		return &link, nil
+	}
+	return nil, errors.New("connection refused")
+}
+
+type mapFallback map[string]domain.Link
+
+func (f mapFallback) Lookup(key string) (*domain.Link, bool) {
+	link, ok := f[key]
+	return &link, ok
+}
+
+func TestResolveFallback(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	s := &Service{repo: &downRepo{}}
+
+	if _, err := s.Resolve(context.Background(), "docs"); !errors.Is(err, ErrCantGetLink) {
+		t.Fatalf("without fallback error = %v, want ErrCantGetLink", err)
+	}
+
+	s.UseFallback(mapFallback{
+		"docs": {Key: "docs", URL: "https://example.com/docs"},
+		"old":  {Key: "old", URL: "https://example.com/old", ExpiresAt: &past},
+	})
+
+	link, err := s.Resolve(context.Background(), "docs")
+	if err != nil || link.URL != "https://example.com/docs" {
+		t.Errorf("Resolve(docs) = %+v, %v", link, err)
+	}
+	if _, err = s.Resolve(context.Background(), "old"); !errors.Is(err, ErrLinkExpired) {
+		t.Errorf("Resolve(old) error = %v, want ErrLinkExpired", err)
+	}
+	// ключа нет в снапшоте - это не 404, снапшот мог отстать
+	if _, err = s.Resolve(context.Background(), "new"); !errors.Is(err, ErrCantGetLink) {
+		t.Errorf("Resolve(new) error = %v, want ErrCantGetLink", err)
+	}
+}
+
+func TestResolveDegraded(t *testing.T) {
+	repo := &downRepo{links: map[string]repository.Link{"docs": {LinkID: 1, Key: "docs", URL: "https://example.com/docs"}}}
+	s := &Service{repo: repo, cache: newRecentCache(10)}
+	degraded := false
+	s.SetDegraded(func() bool { return degraded })
+
+	if _, err := s.Resolve(context.Background(), "docs"); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+
+	// база недоступна: ссылка отдается из кэша без запроса
+	degraded = true
+	link, err := s.Resolve(context.Background(), "docs")
+	if err != nil || link.ID != 1 {
+		t.Errorf("degraded Resolve() = %+v, %v", link, err)
+	}
+	if repo.calls != 1 {
+		t.Errorf("repository called %d times, want 1", repo.calls)
+	}
+	if _, err = s.Resolve(context.Background(), "blog"); !errors.Is(err, ErrCantGetLink) {
+		t.Errorf("degraded Resolve(blog) error = %v, want ErrCantGetLink", err)
+	}
+}
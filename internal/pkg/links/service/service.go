@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/sshlykov/shortener/internal/config"
+	"github.com/sshlykov/shortener/internal/domain"
+	repository "github.com/sshlykov/shortener/internal/pkg/links/repo"
+	"github.com/sshlykov/shortener/pkg/postgres"
+)
+
+type Service struct {
+	repo      Repository
+	tx        postgres.TxManager
+	publisher Publisher
+	cfg       config.Links
+	fallback  Fallback
+	cache     *recentCache
+	degraded  func() bool
+	exports   chan struct{}
+}
+
+type Repository interface {
+	GetByKey(ctx context.Context, key string) (*repository.Link, error)
+	GetByURL(ctx context.Context, owner, url string) (*repository.Link, error)
+	NextID(ctx context.Context) (int32, error)
+	Insert(ctx context.Context, link *repository.Link) (*repository.Link, error)
+	NextIDs(ctx context.Context, n int) ([]int32, error)
+	InsertBatch(ctx context.Context, links []repository.Link) ([]*repository.Link, error)
+	SetImportedClicks(ctx context.Context, key string, clicks int64) error
+	Update(ctx context.Context, key string, url *string, setExpiry bool, expiresAt *time.Time) (*repository.Link, error)
+	Delete(ctx context.Context, key string) (*repository.Link, error)
+	List(ctx context.Context, filter repository.Filter) ([]repository.Link, error)
+	DeclareExport(ctx context.Context, owner string, withClicks bool) error
+	FetchExport(ctx context.Context, n int) ([]repository.ExportRow, error)
+	ExpireDue(ctx context.Context, limit int) ([]repository.Link, error)
+}
+
+// Fallback отдает ссылку для редиректа, когда база недоступна. У такой ссылки нет id
+type Fallback interface {
+	Lookup(key string) (*domain.Link, bool)
+}
+
+// UseFallback включает резолв из f при ошибке базы
+func (s *Service) UseFallback(f Fallback) {
+	s.fallback = f
+}
+
+// SetDegraded задает признак недоступной базы: пока degraded возвращает true,
+// Resolve не ходит в базу и сразу отдает ссылки из кэша и fallback
+func (s *Service) SetDegraded(degraded func() bool) {
+	s.degraded = degraded
+}
+
+// Publisher пишет событие в outbox, вызывается в той же транзакции, что и изменение ссылки
+type Publisher interface {
+	Publish(ctx context.Context, event domain.Event) error
+}
+
+func New(db postgres.Client, tx postgres.TxManager, cfg config.Links, publisher Publisher) *Service {
+	return &Service{
+		repo:      repository.New(db),
+		tx:        tx,
+		publisher: publisher,
+		cfg:       cfg,
+		cache:     newRecentCache(cfg.FallbackCacheSize),
+		exports:   newExportLimit(cfg.Export.MaxConcurrent),
+	}
+}
+
+// newExportLimit - семафор одновременных выгрузок, nil - без ограничения
+func newExportLimit(n int) chan struct{} {
+	if n <= 0 {
+		return nil
+	}
+
+	return make(chan struct{}, n)
+}
+
+func (s *Service) publish(ctx context.Context, eventType domain.EventType, link *domain.Link) error {
+	event, err := domain.NewEvent(eventType, link.Owner, link)
+	if err != nil {
+		return err
+	}
+
+	return s.publisher.Publish(ctx, event)
+}
+
+func toDomain(link *repository.Link) *domain.Link {
+	return &domain.Link{
+		ID:        link.LinkID,
+		Key:       link.Key,
+		URL:       link.URL,
+		Owner:     link.Owner,
+		CreatedAt: link.CreatedAt,
+		UpdatedAt: link.UpdatedAt,
+		ExpiresAt: link.ExpiresAt,
+	}
+}
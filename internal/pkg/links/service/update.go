@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sshlykov/shortener/internal/domain"
+	"github.com/sshlykov/shortener/pkg/logger"
+)
+
+// Expiry - изменение срока жизни в Update: без Set срок не меняется, Set с nil At делает ссылку бессрочной
+type Expiry struct {
+	Set bool
+	At  *time.Time
+}
+
+// Update меняет адрес, если url не nil, и срок жизни по expiry
+func (s *Service) Update(ctx context.Context, key string, url *string, expiry Expiry) (*domain.Link, error) {
+	if url != nil {
+		normalized, err := NormalizeURL(*url)
+		if err != nil {
+			return nil, err
+		}
+		url = &normalized
+	}
+
+	var result *domain.Link
+	err := s.tx.ReadCommitted(ctx, func(ctx context.Context) error {
+		updated, err := s.repo.Update(ctx, key, url, expiry.Set, expiry.At)
+		if err != nil {
+			return err
+		}
+
+		result = toDomain(updated)
+		return s.publish(ctx, domain.EventLinkUpdated, result)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrLinkNotFound
+	}
+	if err != nil {
+		logger.Error(ctx, "Update", logger.Err(err), logger.Any("key", key))
+
+		return nil, ErrCantUpdateLink
+	}
+	s.cache.remove(key)
+
+	return result, nil
+}
+
+// SetImportedClicks сохраняет число кликов ссылки из другого сервиса, повторный вызов перезаписывает его
+func (s *Service) SetImportedClicks(ctx context.Context, key string, clicks int64) error {
+	if err := s.repo.SetImportedClicks(ctx, key, clicks); err != nil {
+		logger.Error(ctx, "SetImportedClicks", logger.Err(err), logger.Any("key", key))
+
+		return ErrCantUpdateLink
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	MinKeyLen = 4
+	MaxKeyLen = 32
+)
+
+var (
+	keyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+	// ключи, совпадающие с маршрутами веб сервера
+	reservedKeys = map[string]struct{}{
+		"api": {},
+		"now": {},
+	}
+)
+
+// ValidateKey проверяет пользовательский ключ ссылки
+func ValidateKey(key string) error {
+	if len(key) < MinKeyLen || len(key) > MaxKeyLen {
+		return fmt.Errorf("%w: length should be from %d to %d", ErrInvalidKey, MinKeyLen, MaxKeyLen)
+	}
+	if !keyPattern.MatchString(key) {
+		return fmt.Errorf("%w: only latin letters, digits, '-' and '_' are allowed", ErrInvalidKey)
+	}
+	if _, ok := reservedKeys[strings.ToLower(key)]; ok {
+		return fmt.Errorf("%w: key is reserved", ErrInvalidKey)
+	}
+
+	return nil
+}
+
+// NormalizeURL проверяет ссылку и приводит ее к каноничному виду:
+// схема и хост в нижнем регистре, без стандартного порта
+func NormalizeURL(raw string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidURL, err.Error())
+	}
+
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return "", fmt.Errorf("%w: scheme should be http or https", ErrInvalidURL)
+	}
+	if parsed.Hostname() == "" {
+		return "", fmt.Errorf("%w: host is empty", ErrInvalidURL)
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	port := parsed.Port()
+	if (parsed.Scheme == "http" && port == "80") || (parsed.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	parsed.Host = host
+	if port != "" {
+		parsed.Host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		parsed.Host = "[" + host + "]"
+	}
+
+	return parsed.String(), nil
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	cases := map[string]string{
+		"  HTTPS://Example.COM:443/Path?q=1#frag ": "https://example.com/Path?q=1#frag",
+		"http://example.com:8080/":                 "http://example.com:8080/",
+		"http://[::1]:80/a":                        "http://[::1]/a",
+	}
+	for raw, want := range cases {
+		got, err := NormalizeURL(raw)
+		if err != nil || got != want {
+			t.Errorf("NormalizeURL(%q) = %q, %v; want %q", raw, got, err, want)
+		}
+	}
+
+	for _, raw := range []string{"ftp://example.com", "example.com", "http://", "http://%zz"} {
+		if _, err := NormalizeURL(raw); !errors.Is(err, ErrInvalidURL) {
+			t.Errorf("NormalizeURL(%q) should fail, got %v", raw, err)
+		}
+	}
+}
+
+func TestValidateKey(t *testing.T) {
+	for _, key := range []string{"abcd", "my-link_2024"} {
+		if err := ValidateKey(key); err != nil {
+			t.Errorf("ValidateKey(%q) = %v", key, err)
+		}
+	}
+	for _, key := range []string{"abc", "with space", "API", "кириллица"} {
+		if err := ValidateKey(key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("ValidateKey(%q) should fail, got %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/sshlykov/shortener/pkg/postgres"
+)
+
+type Message struct {
+	OutboxID  int64     `db:"outbox_id"`
+	EventID   string    `db:"event_id"`
+	EventType string    `db:"event_type"`
+	Owner     string    `db:"owner"`
+	Payload   []byte    `db:"payload"`
+	Attempts  int       `db:"attempts"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type Repository struct {
+	db postgres.DB
+}
+
+func New(db postgres.Client) *Repository {
+	return &Repository{db: db.DB()}
+}
+
+const insertMessage = `
+INSERT INTO outbox (event_id, event_type, owner, payload)
+VALUES ($1, $2, $3, $4)`
+
+// Insert пишет событие в outbox, если в контексте есть транзакция - в ней
+func (r *Repository) Insert(ctx context.Context, msg *Message) error {
+	q := postgres.Query{Name: "outbox.insert", Raw: insertMessage}
+	_, err := r.db.ExecContext(ctx, q, msg.EventID, msg.EventType, msg.Owner, msg.Payload)
+
+	return err
+}
+
+const claimMessages = `
+UPDATE outbox
+SET available_at = now() + make_interval(secs => $2)
+WHERE outbox_id IN (SELECT outbox_id
+                    FROM outbox
+                    WHERE available_at <= now()
+                    ORDER BY outbox_id
+                    LIMIT $1 FOR UPDATE SKIP LOCKED)
+RETURNING outbox_id, event_id, event_type, owner, payload, attempts, created_at`
+
+// Claim забирает пачку готовых сообщений, сдвигая available_at на lease: до его истечения
+// другие реплики их не видят, а при падении процесса сообщения вернутся в очередь
+func (r *Repository) Claim(ctx context.Context, limit int, lease time.Duration) ([]Message, error) {
+	var messages []Message
+	q := postgres.Query{Name: "outbox.claim", Raw: claimMessages}
+	if err := r.db.ScanAllContext(ctx, q, &messages, limit, lease.Seconds()); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+const deleteMessages = `DELETE FROM outbox WHERE outbox_id = ANY ($1)`
+
+func (r *Repository) Delete(ctx context.Context, ids []int64) error {
+	q := postgres.Query{Name: "outbox.delete", Raw: deleteMessages}
+	_, err := r.db.ExecContext(ctx, q, ids)
+
+	return err
+}
+
+const retryMessage = `
+UPDATE outbox
+SET attempts     = attempts + 1,
+    available_at = $2,
+    last_error   = $3
+WHERE outbox_id = $1`
+
+func (r *Repository) Retry(ctx context.Context, id int64, availableAt time.Time, lastError string) error {
+	q := postgres.Query{Name: "outbox.retry", Raw: retryMessage}
+	_, err := r.db.ExecContext(ctx, q, id, availableAt, lastError)
+
+	return err
+}
@@ -0,0 +1,7 @@
+package service
+
+import "errors"
+
+var (
+	ErrCantWriteOutbox = errors.New("can't write event to outbox")
+)
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/sshlykov/shortener/internal/config"
+	"github.com/sshlykov/shortener/internal/domain"
+	repository "github.com/sshlykov/shortener/internal/pkg/outbox/repo"
+	"github.com/sshlykov/shortener/pkg/backoff"
+	"github.com/sshlykov/shortener/pkg/logger"
+	"github.com/sshlykov/shortener/pkg/postgres"
+)
+
+type Repository interface {
+	Insert(ctx context.Context, msg *repository.Message) error
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]repository.Message, error)
+	Delete(ctx context.Context, ids []int64) error
+	Retry(ctx context.Context, id int64, availableAt time.Time, lastError string) error
+}
+
+// Handler обрабатывает событие из outbox. Доставка как минимум однократная,
+// поэтому обработчик должен быть идемпотентным (например, по Event.ID)
+type Handler func(ctx context.Context, event domain.Event) error
+
+type subscriber struct {
+	types   map[domain.EventType]struct{}
+	handler Handler
+}
+
+// Service пишет события в outbox в транзакции изменения и пересылает их зарегистрированным обработчикам
+type Service struct {
+	repo        Repository
+	tx          postgres.TxManager
+	cfg         config.Outbox
+	subscribers []subscriber
+}
+
+func New(db postgres.Client, tx postgres.TxManager, cfg config.Outbox) *Service {
+	return &Service{
+		repo: repository.New(db),
+		tx:   tx,
+		cfg:  cfg,
+	}
+}
+
+// Register добавляет обработчик для перечисленных типов событий, без типов - для всех.
+// Вызывается до запуска Run
+func (s *Service) Register(handler Handler, types ...domain.EventType) {
+	sub := subscriber{handler: handler}
+	if len(types) > 0 {
+		sub.types = make(map[domain.EventType]struct{}, len(types))
+		for _, t := range types {
+			sub.types[t] = struct{}{}
+		}
+	}
+
+	s.subscribers = append(s.subscribers, sub)
+}
+
+// Publish пишет событие в outbox. Вызывать нужно внутри транзакции изменения (TxManager),
+// тогда событие будет сохранено тогда и только тогда, когда закоммитится изменение
+func (s *Service) Publish(ctx context.Context, event domain.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.Insert(ctx, &repository.Message{
+		EventID:   event.ID,
+		EventType: string(event.Type),
+		Owner:     event.Owner,
+		Payload:   payload,
+	})
+	if err != nil {
+		logger.Error(ctx, "Publish", logger.Err(err), logger.Any("event", event.Type))
+
+		return ErrCantWriteOutbox
+	}
+
+	return nil
+}
+
+// Run пересылает события обработчикам, пока не будет отменен контекст
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.drain(ctx)
+		}
+	}
+}
+
+func (s *Service) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		n, err := s.relay(ctx)
+		if err != nil {
+			logger.Error(ctx, "outbox relay failed", logger.Err(err))
+			return
+		}
+		if n < s.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+// relay обрабатывает одну пачку сообщений. Сообщения забираются под lease без долгой транзакции,
+// обработчики работают вне транзакции, затем успешные удаляются, а неуспешные откладываются
+// с экспоненциальной задержкой в одной короткой транзакции.
+// Lease один на всю пачку: все обработчики укладываются в него, а сообщения, до которых очередь
+// не дошла до конца lease, не трогаются - их заберет следующий Claim, без дублей с другой репликой
+func (s *Service) relay(ctx context.Context) (int, error) {
+	// отсчет до Claim: в базе lease заканчивается не раньше
+	leaseCtx, cancel := context.WithDeadline(ctx, time.Now().Add(s.cfg.Lease))
+	defer cancel()
+
+	messages, err := s.repo.Claim(ctx, s.cfg.BatchSize, s.cfg.Lease)
+	if err != nil {
+		return 0, err
+	}
+	slices.SortFunc(messages, func(a, b repository.Message) int {
+		return cmp.Compare(a.OutboxID, b.OutboxID)
+	})
+
+	done := make([]int64, 0, len(messages))
+	failed := make(map[int64]error)
+	for i := range messages {
+		if leaseCtx.Err() != nil {
+			logger.Warn(ctx, "outbox lease expired before the batch was handled",
+				logger.Any("handled", i), logger.Any("claimed", len(messages)))
+			break
+		}
+
+		msg := &messages[i]
+		if err = s.dispatch(leaseCtx, msg); err == nil {
+			done = append(done, msg.OutboxID)
+			continue
+		}
+
+		logger.Warn(ctx, "outbox event handling failed", logger.Err(err),
+			logger.Any("event_id", msg.EventID), logger.Any("attempt", msg.Attempts+1))
+		failed[msg.OutboxID] = err
+	}
+
+	err = s.tx.ReadCommitted(ctx, func(ctx context.Context) error {
+		for i := range messages {
+			msg := &messages[i]
+			handleErr, ok := failed[msg.OutboxID]
+			if !ok {
+				continue
+			}
+			next := time.Now().Add(backoff.Delay(s.cfg.InitialInterval, s.cfg.MaxInterval, msg.Attempts+1))
+			if err := s.repo.Retry(ctx, msg.OutboxID, next, handleErr.Error()); err != nil {
+				return err
+			}
+		}
+
+		if len(done) == 0 {
+			return nil
+		}
+		return s.repo.Delete(ctx, done)
+	})
+
+	return len(messages), err
+}
+
+// dispatch передает событие обработчикам, ctx истекает вместе с lease пачки
+func (s *Service) dispatch(ctx context.Context, msg *repository.Message) error {
+	var event domain.Event
+	if err := json.Unmarshal(msg.Payload, &event); err != nil {
+		return err
+	}
+	event.Owner = msg.Owner
+
+	var errs error
+	for _, sub := range s.subscribers {
+		if sub.types != nil {
+			if _, ok := sub.types[event.Type]; !ok {
+				continue
+			}
+		}
+		if err := sub.handler(ctx, event); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%s: %w", event.Type, err))
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sshlykov/shortener/internal/config"
+	"github.com/sshlykov/shortener/internal/domain"
+	repository "github.com/sshlykov/shortener/internal/pkg/outbox/repo"
+	"github.com/sshlykov/shortener/pkg/postgres"
+)
+
+type txKey struct{}
+
+// fakeTx помечает контекст, чтобы проверить, что обработчики работают вне транзакции
+type fakeTx struct {
+	calls int
+}
+
+func (tx *fakeTx) ReadCommitted(ctx context.Context, h postgres.Handler) error {
+	tx.calls++
+	return h(context.WithValue(ctx, txKey{}, true))
+}
+func (tx *fakeTx) RepeatableRead(ctx context.Context, h postgres.Handler) error {
+	return tx.ReadCommitted(ctx, h)
+}
+func (tx *fakeTx) Serializable(ctx context.Context, h postgres.Handler) error {
+	return tx.ReadCommitted(ctx, h)
+}
+
+type retry struct {
+	id        int64
+	lastError string
+}
+
+type fakeRepo struct {
+	messages []repository.Message
+	lease    time.Duration
+	deleted  []int64
+	retried  []retry
+	inTx     []bool
+}
+
+func (r *fakeRepo) Insert(context.Context, *repository.Message) error { return nil }
+
+func (r *fakeRepo) Claim(ctx context.Context, limit int, lease time.Duration) ([]repository.Message, error) {
+	r.lease = lease
+	r.inTx = append(r.inTx, ctx.Value(txKey{}) != nil)
+	n := min(limit, len(r.messages))
+	claimed := r.messages[:n]
+	r.messages = r.messages[n:]
+	return claimed, nil
+}
+
+func (r *fakeRepo) Delete(ctx context.Context, ids []int64) error {
+	r.inTx = append(r.inTx, ctx.Value(txKey{}) != nil)
+	r.deleted = append(r.deleted, ids...)
+	return nil
+}
+
+func (r *fakeRepo) Retry(ctx context.Context, id int64, _ time.Time, lastError string) error {
+	r.inTx = append(r.inTx, ctx.Value(txKey{}) != nil)
+	r.retried = append(r.retried, retry{id: id, lastError: lastError})
+	return nil
+}
+
+func message(t *testing.T, id int64, eventType domain.EventType) repository.Message {
+	t.Helper()
+	payload, err := json.Marshal(domain.Event{ID: "evt", Type: eventType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repository.Message{OutboxID: id, EventType: string(eventType), Payload: payload}
+}
+
+func TestRelay(t *testing.T) {
+	repo := &fakeRepo{messages: []repository.Message{
+		message(t, 2, domain.EventLinkDeleted),
+		message(t, 1, domain.EventLinkCreated),
+		message(t, 3, domain.EventLinkCreated),
+	}}
+	tx := &fakeTx{}
+	s := &Service{repo: repo, tx: tx, cfg: config.Outbox{
+		BatchSize: 10, Lease: time.Minute, InitialInterval: time.Second, MaxInterval: time.Minute,
+	}}
+
+	var handled []domain.EventType
+	s.Register(func(ctx context.Context, event domain.Event) error {
+		if ctx.Value(txKey{}) != nil {
+			t.Error("handler called inside relay transaction")
+		}
+		handled = append(handled, event.Type)
+		if event.Type == domain.EventLinkDeleted {
+			return errors.New("endpoint is down")
+		}
+		return nil
+	})
+
+	n, err := s.relay(context.Background())
+	if err != nil || n != 3 {
+		t.Fatalf("relay() = %d, %v", n, err)
+	}
+
+	if repo.lease != time.Minute {
+		t.Errorf("claim lease = %s, want outbox.lease", repo.lease)
+	}
+	// события обрабатываются по порядку outbox_id
+	if want := []domain.EventType{domain.EventLinkCreated, domain.EventLinkDeleted, domain.EventLinkCreated}; !reflect.DeepEqual(handled, want) {
+		t.Errorf("handled = %v, want %v", handled, want)
+	}
+	if want := []int64{1, 3}; !reflect.DeepEqual(repo.deleted, want) {
+		t.Errorf("deleted = %v, want %v", repo.deleted, want)
+	}
+	if len(repo.retried) != 1 || repo.retried[0].id != 2 || repo.retried[0].lastError == "" {
+		t.Errorf("retried = %+v, want message 2 with error", repo.retried)
+	}
+	if want := []bool{false, true, true}; !reflect.DeepEqual(repo.inTx, want) {
+		t.Errorf("in tx = %v, want claim outside and delete/retry inside one transaction", repo.inTx)
+	}
+	if tx.calls != 1 {
+		t.Errorf("transactions = %d, want 1", tx.calls)
+	}
+}
+
+func TestRelayEmpty(t *testing.T) {
+	repo := &fakeRepo{}
+	s := &Service{repo: repo, tx: &fakeTx{}, cfg: config.Outbox{BatchSize: 10, Lease: time.Minute}}
+
+	n, err := s.relay(context.Background())
+	if err != nil || n != 0 {
+		t.Errorf("relay() = %d, %v", n, err)
+	}
+	if repo.deleted != nil || repo.retried != nil {
+		t.Errorf("nothing should be deleted or retried: %v %v", repo.deleted, repo.retried)
+	}
+}
+
+func TestRelayStopsAtLease(t *testing.T) {
+	repo := &fakeRepo{messages: []repository.Message{
+		message(t, 1, domain.EventLinkCreated),
+		message(t, 2, domain.EventLinkCreated),
+		message(t, 3, domain.EventLinkCreated),
+	}}
+	s := &Service{repo: repo, tx: &fakeTx{}, cfg: config.Outbox{
+		BatchSize: 10, Lease: 50 * time.Millisecond, InitialInterval: time.Second, MaxInterval: time.Minute,
+	}}
+
+	var handled int
+	s.Register(func(ctx context.Context, _ domain.Event) error {
+		handled++
+		if handled == 1 {
+			return nil
+		}
+		// обработчик дольше оставшегося lease
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	started := time.Now()
+	if _, err := s.relay(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("relay took %s, want it bounded by the lease", elapsed)
+	}
+	if handled != 2 {
+		t.Errorf("handled = %d, want the third message left for the next claim", handled)
+	}
+	if want := []int64{1}; !reflect.DeepEqual(repo.deleted, want) {
+		t.Errorf("deleted = %v, want %v", repo.deleted, want)
+	}
+	if len(repo.retried) != 1 || repo.retried[0].id != 2 {
+		t.Errorf("retried = %+v, want only the message cut by the lease", repo.retried)
+	}
+}
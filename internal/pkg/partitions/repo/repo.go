@@ -0,0 +1,89 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sshlykov/shortener/pkg/postgres"
+)
+
+type Partition struct {
+	Name string `db:"name"`
+	Rows int64  `db:"rows"`
+}
+
+type Repository struct {
+	db postgres.DB
+}
+
+func New(db postgres.Client) *Repository {
+	return &Repository{db: db.DB()}
+}
+
+const listPartitions = `
+SELECT c.relname AS name, greatest(c.reltuples, 0)::bigint AS rows
+FROM pg_inherits i
+         JOIN pg_class c ON c.oid = i.inhrelid
+WHERE i.inhparent = $1::regclass
+ORDER BY c.relname`
+
+func (r *Repository) ListPartitions(ctx context.Context, parent string) ([]Partition, error) {
+	var partitions []Partition
+	q := postgres.Query{Name: "partitions.list", Raw: listPartitions}
+	if err := r.db.ScanAllContext(ctx, q, &partitions, parent); err != nil {
+		return nil, err
+	}
+
+	return partitions, nil
+}
+
+// CreateTable создает пустую таблицу со структурой parent, которая затем подключается партицией
+func (r *Repository) CreateTable(ctx context.Context, parent, name string) error {
+	raw := fmt.Sprintf("CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS INCLUDING CONSTRAINTS)",
+		pgx.Identifier{name}.Sanitize(), pgx.Identifier{parent}.Sanitize())
+
+	_, err := r.db.ExecContext(ctx, postgres.Query{Name: "partitions.create_table", Raw: raw})
+	return err
+}
+
+// MoveRows переносит строки с key в [from, to) из таблицы src в dst
+func (r *Repository) MoveRows(ctx context.Context, src, dst, key string, from, to time.Time) (int64, error) {
+	raw := fmt.Sprintf(`
+WITH moved AS (DELETE FROM %[1]s WHERE %[3]s >= $1 AND %[3]s < $2 RETURNING *)
+INSERT INTO %[2]s SELECT * FROM moved`,
+		pgx.Identifier{src}.Sanitize(), pgx.Identifier{dst}.Sanitize(), pgx.Identifier{key}.Sanitize())
+
+	tag, err := r.db.ExecContext(ctx, postgres.Query{Name: "partitions.move_rows", Raw: raw}, from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (r *Repository) AttachPartition(ctx context.Context, parent, name string, from, to time.Time) error {
+	raw := fmt.Sprintf("ALTER TABLE %s ATTACH PARTITION %s FOR VALUES FROM ('%s') TO ('%s')",
+		pgx.Identifier{parent}.Sanitize(), pgx.Identifier{name}.Sanitize(),
+		from.UTC().Format(time.RFC3339), to.UTC().Format(time.RFC3339))
+
+	_, err := r.db.ExecContext(ctx, postgres.Query{Name: "partitions.attach", Raw: raw})
+	return err
+}
+
+func (r *Repository) DetachPartition(ctx context.Context, parent, name string) error {
+	raw := fmt.Sprintf("ALTER TABLE %s DETACH PARTITION %s",
+		pgx.Identifier{parent}.Sanitize(), pgx.Identifier{name}.Sanitize())
+
+	_, err := r.db.ExecContext(ctx, postgres.Query{Name: "partitions.detach", Raw: raw})
+	return err
+}
+
+func (r *Repository) DropPartition(ctx context.Context, name string) error {
+	raw := fmt.Sprintf("DROP TABLE IF EXISTS %s", pgx.Identifier{name}.Sanitize())
+
+	_, err := r.db.ExecContext(ctx, postgres.Query{Name: "partitions.drop", Raw: raw})
+	return err
+}
@@ -0,0 +1,9 @@
+package service
+
+import "errors"
+
+var (
+	ErrUnknownRetentionPolicy = errors.New("unknown retention policy")
+	ErrCantListPartitions     = errors.New("can't list partitions")
+	ErrCantMoveDefaultRows    = errors.New("can't move rows of the month out of the default partition")
+)
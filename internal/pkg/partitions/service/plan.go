@@ -0,0 +1,59 @@
+package service
+
+import (
+	"fmt"
+	"time"
+)
+
+// MonthStart возвращает начало месяца (UTC), в который попадает t
+func MonthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// PartitionName возвращает имя месячной партиции вида clicks_p2024_11
+func PartitionName(parent string, month time.Time) string {
+	month = MonthStart(month)
+	return fmt.Sprintf("%s_p%04d_%02d", parent, month.Year(), int(month.Month()))
+}
+
+// ParsePartitionName возвращает месяц партиции, false - если имя не похоже на месячную партицию (например, default)
+func ParsePartitionName(parent, name string) (time.Time, bool) {
+	var year, month int
+	if _, err := fmt.Sscanf(name, parent+"_p%04d_%02d", &year, &month); err != nil {
+		return time.Time{}, false
+	}
+	if month < 1 || month > 12 || PartitionName(parent, time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)) != name {
+		return time.Time{}, false
+	}
+
+	return time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC), true
+}
+
+// Plan считает, какие месяцы нужно создать (текущий и premake вперед) и какие партиции
+// вышли за срок хранения retention (в месяцах, 0 - хранить всегда)
+func Plan(now time.Time, parent string, existing []string, premake, retention int) (create []time.Time, expired []string) {
+	current := MonthStart(now)
+
+	months := make(map[time.Time]struct{}, len(existing))
+	for _, name := range existing {
+		month, ok := ParsePartitionName(parent, name)
+		if !ok {
+			continue
+		}
+		months[month] = struct{}{}
+
+		if retention > 0 && month.Before(current.AddDate(0, -retention, 0)) {
+			expired = append(expired, name)
+		}
+	}
+
+	for i := 0; i <= premake; i++ {
+		month := current.AddDate(0, i, 0)
+		if _, ok := months[month]; !ok {
+			create = append(create, month)
+		}
+	}
+
+	return create, expired
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParsePartitionName(t *testing.T) {
+	month, ok := ParsePartitionName("clicks", "clicks_p2024_11")
+	if !ok || !month.Equal(time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected result: %v %v", month, ok)
+	}
+
+	for _, name := range []string{"clicks_default", "clicks_p2024_13", "clicks_p2024_1", "other_p2024_11"} {
+		if _, ok := ParsePartitionName("clicks", name); ok {
+			t.Errorf("%s should not be parsed", name)
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	now := time.Date(2024, 11, 15, 10, 0, 0, 0, time.UTC)
+	existing := []string{
+		"clicks_default",
+		"clicks_p2023_10",
+		"clicks_p2023_11",
+		"clicks_p2024_11",
+		"clicks_p2024_12",
+	}
+
+	create, expired := Plan(now, "clicks", existing, 2, 12)
+
+	wantCreate := []time.Time{time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if !reflect.DeepEqual(create, wantCreate) {
+		t.Errorf("create = %v, want %v", create, wantCreate)
+	}
+	if want := []string{"clicks_p2023_10"}; !reflect.DeepEqual(expired, want) {
+		t.Errorf("expired = %v, want %v", expired, want)
+	}
+
+	if _, expired = Plan(now, "clicks", existing, 0, 0); expired != nil {
+		t.Errorf("retention 0 should keep everything, got %v", expired)
+	}
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sshlykov/shortener/internal/config"
+	repository "github.com/sshlykov/shortener/internal/pkg/partitions/repo"
+	"github.com/sshlykov/shortener/pkg/logger"
+	"github.com/sshlykov/shortener/pkg/postgres"
+)
+
+const (
+	ClicksTable = "clicks"
+	// ClicksDefault - default партиция, в нее попадают клики, пока партиции их месяца нет
+	ClicksDefault = "clicks_default"
+	// ClicksKey - ключ партиционирования
+	ClicksKey = "clicked_at"
+
+	PolicyDetach = "detach"
+	PolicyDrop   = "drop"
+)
+
+type Repository interface {
+	ListPartitions(ctx context.Context, parent string) ([]repository.Partition, error)
+	CreateTable(ctx context.Context, parent, name string) error
+	MoveRows(ctx context.Context, src, dst, key string, from, to time.Time) (int64, error)
+	AttachPartition(ctx context.Context, parent, name string, from, to time.Time) error
+	DetachPartition(ctx context.Context, parent, name string) error
+	DropPartition(ctx context.Context, name string) error
+}
+
+type Status struct {
+	Table      string                 `json:"table"`
+	Partitions []repository.Partition `json:"partitions"`
+	Created    []string               `json:"created,omitempty"`
+	Expired    []string               `json:"expired,omitempty"`
+	LastRun    time.Time              `json:"last_run"`
+	LastError  string                 `json:"last_error,omitempty"`
+}
+
+// Service поддерживает месячные партиции таблицы кликов: создает будущие заранее
+// и отцепляет/удаляет устаревшие согласно политике хранения
+type Service struct {
+	repo Repository
+	tx   postgres.TxManager
+	cfg  config.Partitions
+	now  func() time.Time
+
+	mu     sync.RWMutex
+	status Status
+}
+
+func New(db postgres.Client, tx postgres.TxManager, cfg config.Partitions) *Service {
+	return &Service{
+		repo:   repository.New(db),
+		tx:     tx,
+		cfg:    cfg,
+		now:    time.Now,
+		status: Status{Table: ClicksTable},
+	}
+}
+
+func (s *Service) Name() string {
+	return "partitions"
+}
+
+func (s *Service) Status(_ context.Context) any {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.status
+}
+
+// Maintain выполняет один проход обслуживания партиций
+func (s *Service) Maintain(ctx context.Context) error {
+	status := Status{Table: ClicksTable, LastRun: s.now()}
+
+	err := s.maintain(ctx, &status)
+	if err != nil {
+		status.LastError = err.Error()
+		logger.Error(ctx, "partition maintenance failed", logger.Err(err))
+	}
+
+	s.mu.Lock()
+	s.status = status
+	s.mu.Unlock()
+
+	return err
+}
+
+func (s *Service) maintain(ctx context.Context, status *Status) error {
+	if s.cfg.RetentionPolicy != PolicyDetach && s.cfg.RetentionPolicy != PolicyDrop {
+		return fmt.Errorf("%w: %q", ErrUnknownRetentionPolicy, s.cfg.RetentionPolicy)
+	}
+
+	partitions, err := s.repo.ListPartitions(ctx, ClicksTable)
+	if err != nil {
+		logger.Error(ctx, "ListPartitions", logger.Err(err))
+		return ErrCantListPartitions
+	}
+
+	names := make([]string, 0, len(partitions))
+	for _, p := range partitions {
+		names = append(names, p.Name)
+	}
+
+	create, expired := Plan(status.LastRun, ClicksTable, names, s.cfg.Premake, s.cfg.Retention)
+
+	var errs error
+	for _, month := range create {
+		name := PartitionName(ClicksTable, month)
+		moved, err := s.createPartition(ctx, name, month, month.AddDate(0, 1, 0))
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("create %s: %w", name, err))
+			continue
+		}
+		logger.Info(ctx, "partition created", logger.Any("partition", name), logger.Any("moved", moved))
+		status.Created = append(status.Created, name)
+	}
+
+	for _, name := range expired {
+		if err = s.expire(ctx, name); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%s %s: %w", s.cfg.RetentionPolicy, name, err))
+			continue
+		}
+		logger.Info(ctx, "partition expired", logger.Any("partition", name),
+			logger.Any("policy", s.cfg.RetentionPolicy))
+		status.Expired = append(status.Expired, name)
+	}
+
+	if status.Partitions, err = s.repo.ListPartitions(ctx, ClicksTable); err != nil {
+		errs = errors.Join(errs, ErrCantListPartitions)
+	}
+
+	return errs
+}
+
+// createPartition создает партицию месяца. Клики, попавшие в default партицию, пока партиции не было,
+// переносятся в нее в той же транзакции: иначе Postgres не подключит партицию на занятый диапазон
+func (s *Service) createPartition(ctx context.Context, name string, from, to time.Time) (int64, error) {
+	var moved int64
+	err := s.tx.ReadCommitted(ctx, func(ctx context.Context) error {
+		if err := s.repo.CreateTable(ctx, ClicksTable, name); err != nil {
+			return err
+		}
+
+		var err error
+		if moved, err = s.repo.MoveRows(ctx, ClicksDefault, name, ClicksKey, from, to); err != nil {
+			return fmt.Errorf("%w: %w", ErrCantMoveDefaultRows, err)
+		}
+
+		return s.repo.AttachPartition(ctx, ClicksTable, name, from, to)
+	})
+
+	return moved, err
+}
+
+// expire отцепляет партицию, а при политике drop удаляет ее в той же транзакции: отцепленную таблицу
+// ListPartitions уже не увидит, и упавший после detach drop больше не повторился бы
+func (s *Service) expire(ctx context.Context, name string) error {
+	return s.tx.ReadCommitted(ctx, func(ctx context.Context) error {
+		if err := s.repo.DetachPartition(ctx, ClicksTable, name); err != nil {
+			return err
+		}
+		if s.cfg.RetentionPolicy == PolicyDrop {
+			return s.repo.DropPartition(ctx, name)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sshlykov/shortener/internal/config"
+	repository "github.com/sshlykov/shortener/internal/pkg/partitions/repo"
+	"github.com/sshlykov/shortener/pkg/postgres"
+	"github.com/sshlykov/shortener/pkg/postgres/pgtest"
+)
+
+type fakeRepo struct {
+	existing []string
+	moveErr  error
+	dropErr  error
+	calls    []string
+}
+
+func (r *fakeRepo) ListPartitions(context.Context, string) ([]repository.Partition, error) {
+	partitions := make([]repository.Partition, 0, len(r.existing))
+	for _, name := range r.existing {
+		partitions = append(partitions, repository.Partition{Name: name})
+	}
+	return partitions, nil
+}
+
+func (r *fakeRepo) CreateTable(_ context.Context, _, name string) error {
+	r.calls = append(r.calls, "create "+name)
+	return nil
+}
+
+func (r *fakeRepo) MoveRows(_ context.Context, src, dst, _ string, _, _ time.Time) (int64, error) {
+	r.calls = append(r.calls, "move "+src+" "+dst)
+	return 5, r.moveErr
+}
+
+func (r *fakeRepo) AttachPartition(_ context.Context, _, name string, _, _ time.Time) error {
+	r.calls = append(r.calls, "attach "+name)
+	return nil
+}
+
+func (r *fakeRepo) DetachPartition(ctx context.Context, _, name string) error {
+	r.calls = append(r.calls, txCall(ctx, "detach "+name))
+	return nil
+}
+
+func (r *fakeRepo) DropPartition(ctx context.Context, name string) error {
+	r.calls = append(r.calls, txCall(ctx, "drop "+name))
+	return r.dropErr
+}
+
+type txKey struct{}
+
+// markTx помечает контекст, чтобы проверить, какие вызовы идут в транзакции
+type markTx struct {
+	pgtest.PassTx
+	rolledBack bool
+}
+
+func (tx *markTx) ReadCommitted(ctx context.Context, h postgres.Handler) error {
+	err := h(context.WithValue(ctx, txKey{}, true))
+	tx.rolledBack = tx.rolledBack || err != nil
+	return err
+}
+
+func txCall(ctx context.Context, call string) string {
+	if ctx.Value(txKey{}) != nil {
+		return call + " in tx"
+	}
+	return call
+}
+
+func TestMaintainMovesDefaultRows(t *testing.T) {
+	repo := &fakeRepo{existing: []string{"clicks_default"}}
+	s := &Service{repo: repo, tx: pgtest.PassTx{}, cfg: config.Partitions{RetentionPolicy: PolicyDetach},
+		now: func() time.Time { return time.Date(2024, 11, 15, 0, 0, 0, 0, time.UTC) }}
+
+	if err := s.Maintain(context.Background()); err != nil {
+		t.Fatalf("Maintain() error = %v", err)
+	}
+
+	want := []string{"create clicks_p2024_11", "move clicks_default clicks_p2024_11", "attach clicks_p2024_11"}
+	if !reflect.DeepEqual(repo.calls, want) {
+		t.Errorf("calls = %v, want %v", repo.calls, want)
+	}
+}
+
+func TestMaintainMoveFailed(t *testing.T) {
+	repo := &fakeRepo{moveErr: errors.New("lock timeout")}
+	s := &Service{repo: repo, tx: pgtest.PassTx{}, cfg: config.Partitions{RetentionPolicy: PolicyDetach}, now: time.Now}
+
+	if err := s.Maintain(context.Background()); !errors.Is(err, ErrCantMoveDefaultRows) {
+		t.Errorf("Maintain() error = %v, want ErrCantMoveDefaultRows", err)
+	}
+	for _, call := range repo.calls {
+		if call[:6] == "attach" {
+			t.Errorf("partition attached after failed move: %v", repo.calls)
+		}
+	}
+}
+
+func TestMaintainDropsInDetachTransaction(t *testing.T) {
+	repo := &fakeRepo{existing: []string{"clicks_p2024_11", "clicks_p2023_01"}, dropErr: errors.New("lock timeout")}
+	tx := &markTx{}
+	s := &Service{repo: repo, tx: tx, cfg: config.Partitions{RetentionPolicy: PolicyDrop, Retention: 12},
+		now: func() time.Time { return time.Date(2024, 11, 15, 0, 0, 0, 0, time.UTC) }}
+
+	if err := s.Maintain(context.Background()); err == nil {
+		t.Fatal("Maintain() error = nil, want failed drop")
+	}
+
+	want := []string{"detach clicks_p2023_01 in tx", "drop clicks_p2023_01 in tx"}
+	if !reflect.DeepEqual(repo.calls, want) {
+		t.Errorf("calls = %v, want %v", repo.calls, want)
+	}
+	if !tx.rolledBack {
+		t.Error("detach was not rolled back after failed drop")
+	}
+}
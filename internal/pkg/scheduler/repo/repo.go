@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sshlykov/shortener/pkg/postgres"
+)
+
+type Run struct {
+	Name            string     `db:"name"`
+	LastScheduledAt time.Time  `db:"last_scheduled_at"`
+	LastStartedAt   time.Time  `db:"last_started_at"`
+	LastFinishedAt  *time.Time `db:"last_finished_at"`
+	LastDurationMS  *int64     `db:"last_duration_ms"`
+	LastError       *string    `db:"last_error"`
+	LastInstance    string     `db:"last_instance"`
+}
+
+type Repository struct {
+	db postgres.DB
+}
+
+func New(db postgres.Client) *Repository {
+	return &Repository{db: db.DB()}
+}
+
+const tryLock = `SELECT pg_try_advisory_xact_lock(hashtext('scheduler'), hashtext($1))`
+
+// TryLock берет транзакционную advisory-блокировку задачи, вызывается внутри транзакции
+func (r *Repository) TryLock(ctx context.Context, name string) (bool, error) {
+	var locked bool
+	q := postgres.Query{Name: "scheduler.try_lock", Raw: tryLock}
+	err := r.db.QueryRowContext(ctx, q, name).Scan(&locked)
+
+	return locked, err
+}
+
+const startRun = `
+INSERT INTO scheduled_tasks (name, last_scheduled_at, last_started_at, last_instance)
+VALUES ($1, $2, now(), $3)
+ON CONFLICT (name) DO UPDATE
+    SET last_scheduled_at = excluded.last_scheduled_at,
+        last_started_at   = excluded.last_started_at,
+        last_finished_at  = NULL,
+        last_duration_ms  = NULL,
+        last_error        = NULL,
+        last_instance     = excluded.last_instance
+WHERE scheduled_tasks.last_scheduled_at < excluded.last_scheduled_at
+  AND (scheduled_tasks.last_finished_at IS NOT NULL
+    OR scheduled_tasks.last_started_at < now() - make_interval(secs => $4))
+RETURNING name`
+
+// Start отмечает запуск задачи за момент scheduledAt. false - за этот момент задача уже запускалась
+// или предыдущий запуск еще выполняется: незавершенный запуск старше runTimeout считается упавшим
+func (r *Repository) Start(ctx context.Context, name string, scheduledAt time.Time, instance string,
+	runTimeout time.Duration) (bool, error) {
+	var got string
+	q := postgres.Query{Name: "scheduler.start", Raw: startRun}
+	err := r.db.QueryRowContext(ctx, q, name, scheduledAt, instance, runTimeout.Seconds()).Scan(&got)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+const finishRun = `
+UPDATE scheduled_tasks
+SET last_finished_at = now(),
+    last_duration_ms = $2,
+    last_error       = $3
+WHERE name = $1
+  AND last_scheduled_at = $4`
+
+// Finish отмечает завершение запуска scheduledAt, более новый запуск другой реплики не перезаписывается
+func (r *Repository) Finish(ctx context.Context, name string, scheduledAt time.Time, duration time.Duration,
+	lastError *string) error {
+	q := postgres.Query{Name: "scheduler.finish", Raw: finishRun}
+	_, err := r.db.ExecContext(ctx, q, name, duration.Milliseconds(), lastError, scheduledAt)
+
+	return err
+}
+
+const listRuns = `
+SELECT name, last_scheduled_at, last_started_at, last_finished_at, last_duration_ms, last_error, last_instance
+FROM scheduled_tasks
+ORDER BY name`
+
+func (r *Repository) List(ctx context.Context) ([]Run, error) {
+	var runs []Run
+	q := postgres.Query{Name: "scheduler.list", Raw: listRuns, ReadOnly: true}
+	if err := r.db.ScanAllContext(ctx, q, &runs); err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
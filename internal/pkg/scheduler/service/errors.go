@@ -0,0 +1,7 @@
+package service
+
+import "errors"
+
+var (
+	ErrNoSchedule = errors.New("no schedule configured for task")
+)
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sshlykov/shortener/internal/config"
+	repository "github.com/sshlykov/shortener/internal/pkg/scheduler/repo"
+	"github.com/sshlykov/shortener/pkg/cron"
+	"github.com/sshlykov/shortener/pkg/logger"
+	"github.com/sshlykov/shortener/pkg/postgres"
+)
+
+type Repository interface {
+	TryLock(ctx context.Context, name string) (bool, error)
+	Start(ctx context.Context, name string, scheduledAt time.Time, instance string, runTimeout time.Duration) (bool, error)
+	Finish(ctx context.Context, name string, scheduledAt time.Time, duration time.Duration, lastError *string) error
+	List(ctx context.Context) ([]repository.Run, error)
+}
+
+// Task - периодическая задача, должна быть идемпотентной
+type Task func(ctx context.Context) error
+
+type TaskOption func(*task)
+
+// OnStart дополнительно запускает задачу при старте реплики
+func OnStart() TaskOption {
+	return func(t *task) {
+		t.onStart = true
+	}
+}
+
+type task struct {
+	name     string
+	run      Task
+	onStart  bool
+	expr     string
+	schedule cron.Schedule
+
+	mu    sync.RWMutex
+	state TaskState
+}
+
+// TaskState - локальное состояние задачи на этой реплике
+type TaskState struct {
+	Schedule     string     `json:"schedule"`
+	NextRun      *time.Time `json:"next_run,omitempty"`
+	Running      bool       `json:"running"`
+	LastRun      *time.Time `json:"last_run,omitempty"`
+	LastDuration string     `json:"last_duration,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+}
+
+// Service запускает задачи по cron-расписанию из конфига. Каждое срабатывание выполняет
+// только одна реплика: под advisory-блокировкой и с отметкой момента срабатывания в scheduled_tasks
+type Service struct {
+	repo     Repository
+	tx       postgres.TxManager
+	cfg      config.Scheduler
+	instance string
+	now      func() time.Time
+
+	tasks []*task
+}
+
+func New(db postgres.Client, tx postgres.TxManager, cfg config.Scheduler) *Service {
+	instance, _ := os.Hostname()
+
+	return &Service{
+		repo:     repository.New(db),
+		tx:       tx,
+		cfg:      cfg,
+		instance: instance,
+		now:      time.Now,
+	}
+}
+
+// Register добавляет задачу, расписание берется из cfg.Tasks по имени. Вызывается до запуска Run
+func (s *Service) Register(name string, run Task, opts ...TaskOption) {
+	t := &task{name: name, run: run, expr: s.cfg.Tasks[name]}
+	for _, opt := range opts {
+		opt(t)
+	}
+	t.state.Schedule = t.expr
+
+	s.tasks = append(s.tasks, t)
+}
+
+// Run запускает задачи по расписанию, пока не будет отменен контекст, и дожидается выполняющихся
+func (s *Service) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, t := range s.tasks {
+		if err := s.prepare(t); err != nil {
+			logger.Error(ctx, "task not scheduled", logger.Err(err), logger.Any("task", t.name))
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.loop(ctx, t)
+		}()
+	}
+
+	for name := range s.cfg.Tasks {
+		if !s.registered(name) {
+			logger.Warn(ctx, "schedule for unknown task", logger.Any("task", name))
+		}
+	}
+
+	wg.Wait()
+}
+
+func (s *Service) prepare(t *task) error {
+	if t.expr == "" {
+		return ErrNoSchedule
+	}
+
+	schedule, err := cron.Parse(t.expr)
+	if err != nil {
+		t.setError(err.Error())
+		return err
+	}
+	t.schedule = schedule
+
+	return nil
+}
+
+func (s *Service) registered(name string) bool {
+	for _, t := range s.tasks {
+		if t.name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Service) loop(ctx context.Context, t *task) {
+	if t.onStart {
+		s.fire(ctx, t, s.now())
+	}
+
+	for {
+		next := t.schedule.Next(s.now())
+		if next.IsZero() {
+			logger.Error(ctx, "task schedule never fires", logger.Any("task", t.name))
+			return
+		}
+		t.setNext(next)
+
+		timer := time.NewTimer(time.Until(next) + s.jitter())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.fire(ctx, t, next)
+		}
+	}
+}
+
+func (s *Service) jitter() time.Duration {
+	if s.cfg.Jitter <= 0 {
+		return 0
+	}
+
+	return rand.N(s.cfg.Jitter)
+}
+
+// fire выполняет срабатывание scheduledAt, если его еще не выполнила другая реплика.
+// Запуск отмечается короткой транзакцией под advisory-блокировкой, после коммита он виден в /status
+// всех реплик, а задача работает без удерживаемого соединения. Завершение пишется отдельным запросом
+func (s *Service) fire(ctx context.Context, t *task, scheduledAt time.Time) {
+	var started bool
+	err := s.tx.ReadCommitted(ctx, func(ctx context.Context) error {
+		locked, err := s.repo.TryLock(ctx, t.name)
+		if err != nil || !locked {
+			return err
+		}
+
+		started, err = s.repo.Start(ctx, t.name, scheduledAt, s.instance, s.cfg.RunTimeout)
+		return err
+	})
+	if err != nil {
+		logger.Error(ctx, "scheduled task run not recorded", logger.Err(err), logger.Any("task", t.name))
+		return
+	}
+	if !started {
+		return
+	}
+
+	duration, runErr := s.execute(ctx, t)
+
+	var lastError *string
+	if runErr != nil {
+		msg := runErr.Error()
+		lastError = &msg
+	}
+
+	// задачу могла прервать остановка, завершение все равно записывается
+	if err = s.repo.Finish(context.WithoutCancel(ctx), t.name, scheduledAt, duration, lastError); err != nil {
+		logger.Error(ctx, "scheduled task finish not recorded", logger.Err(err), logger.Any("task", t.name))
+	}
+}
+
+// execute выполняет задачу не дольше RunTimeout: после него запуск считается упавшим,
+// и следующее срабатывание может начаться на другой реплике
+func (s *Service) execute(ctx context.Context, t *task) (time.Duration, error) {
+	if s.cfg.RunTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.RunTimeout)
+		defer cancel()
+	}
+
+	start := s.now()
+	t.setRunning(start)
+
+	err := t.run(ctx)
+	duration := time.Since(start)
+	if err != nil {
+		logger.Error(ctx, "scheduled task failed", logger.Err(err), logger.Any("task", t.name))
+	}
+	t.setFinished(duration, err)
+
+	return duration, err
+}
+
+func (t *task) setNext(next time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.state.NextRun = &next
+}
+
+func (t *task) setError(msg string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.state.LastError = msg
+}
+
+func (t *task) setRunning(start time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.state.Running = true
+	t.state.LastRun = &start
+}
+
+func (t *task) setFinished(duration time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.state.Running = false
+	t.state.LastDuration = duration.String()
+	t.state.LastError = ""
+	if err != nil {
+		t.state.LastError = err.Error()
+	}
+}
+
+func (t *task) snapshot() TaskState {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.state
+}
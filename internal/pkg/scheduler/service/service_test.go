@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sshlykov/shortener/internal/config"
+	repository "github.com/sshlykov/shortener/internal/pkg/scheduler/repo"
+	"github.com/sshlykov/shortener/pkg/postgres"
+	"github.com/sshlykov/shortener/pkg/postgres/pgtest"
+)
+
+type txKey struct{}
+
+// markTx помечает контекст транзакции, чтобы проверить, что задача и Finish работают вне нее
+type markTx struct{}
+
+func (markTx) ReadCommitted(ctx context.Context, h postgres.Handler) error {
+	return h(context.WithValue(ctx, txKey{}, true))
+}
+func (tx markTx) RepeatableRead(ctx context.Context, h postgres.Handler) error {
+	return tx.ReadCommitted(ctx, h)
+}
+func (tx markTx) Serializable(ctx context.Context, h postgres.Handler) error {
+	return tx.ReadCommitted(ctx, h)
+}
+
+type fakeRepo struct {
+	locked       bool
+	lastFire     map[string]time.Time
+	finished     map[string]*string
+	finishedInTx bool
+}
+
+func (r *fakeRepo) TryLock(context.Context, string) (bool, error) {
+	return r.locked, nil
+}
+
+func (r *fakeRepo) Start(_ context.Context, name string, scheduledAt time.Time, _ string, _ time.Duration) (bool, error) {
+	if last, ok := r.lastFire[name]; ok && !last.Before(scheduledAt) {
+		return false, nil
+	}
+	r.lastFire[name] = scheduledAt
+	return true, nil
+}
+
+func (r *fakeRepo) Finish(ctx context.Context, name string, _ time.Time, _ time.Duration, lastError *string) error {
+	r.finished[name] = lastError
+	r.finishedInTx = ctx.Value(txKey{}) != nil
+	return nil
+}
+
+func (r *fakeRepo) List(context.Context) ([]repository.Run, error) {
+	return nil, nil
+}
+
+func TestFireRunsOncePerScheduledTime(t *testing.T) {
+	repo := &fakeRepo{locked: true, lastFire: map[string]time.Time{}, finished: map[string]*string{}}
+	s := &Service{repo: repo, tx: pgtest.PassTx{}, cfg: config.Scheduler{Tasks: map[string]string{"a": "* * * * *"}},
+		now: time.Now}
+
+	runs := 0
+	s.Register("a", func(context.Context) error {
+		runs++
+		return errors.New("boom")
+	})
+	task := s.tasks[0]
+
+	at := time.Date(2024, 12, 8, 12, 0, 0, 0, time.UTC)
+	s.fire(context.Background(), task, at)
+	s.fire(context.Background(), task, at)
+	if runs != 1 {
+		t.Fatalf("expected one run per scheduled time, got %d", runs)
+	}
+	if msg := repo.finished["a"]; msg == nil || *msg != "boom" {
+		t.Errorf("expected recorded error, got %v", msg)
+	}
+	if state := task.snapshot(); state.Running || state.LastError != "boom" {
+		t.Errorf("unexpected local state %+v", state)
+	}
+
+	repo.locked = false
+	s.fire(context.Background(), task, at.Add(time.Minute))
+	if runs != 1 {
+		t.Errorf("task should not run without the lock")
+	}
+}
+
+func TestFireRunsOutsideClaimTransaction(t *testing.T) {
+	repo := &fakeRepo{locked: true, lastFire: map[string]time.Time{}, finished: map[string]*string{}}
+	s := &Service{repo: repo, tx: markTx{}, cfg: config.Scheduler{RunTimeout: time.Minute}, now: time.Now}
+
+	var inTx, hasDeadline bool
+	s.Register("a", func(ctx context.Context) error {
+		inTx = ctx.Value(txKey{}) != nil
+		_, hasDeadline = ctx.Deadline()
+		return nil
+	})
+
+	s.fire(context.Background(), s.tasks[0], time.Now())
+	if inTx || repo.finishedInTx {
+		t.Errorf("task in tx = %v, finish in tx = %v, want both after the claim is committed", inTx, repo.finishedInTx)
+	}
+	if !hasDeadline {
+		t.Error("task should be limited by run timeout")
+	}
+	if _, ok := repo.finished["a"]; !ok {
+		t.Error("finish not recorded")
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	s := &Service{cfg: config.Scheduler{Tasks: map[string]string{"bad": "61 * * * *"}}}
+	s.Register("bad", func(context.Context) error { return nil })
+	s.Register("missing", func(context.Context) error { return nil })
+
+	if err := s.prepare(s.tasks[0]); err == nil {
+		t.Error("expected invalid expression error")
+	}
+	if err := s.prepare(s.tasks[1]); !errors.Is(err, ErrNoSchedule) {
+		t.Errorf("expected ErrNoSchedule, got %v", err)
+	}
+}
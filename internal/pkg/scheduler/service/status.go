@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/sshlykov/shortener/pkg/logger"
+)
+
+// ClusterRun - последний запуск задачи на любой из реплик
+type ClusterRun struct {
+	ScheduledAt time.Time  `json:"scheduled_at"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	DurationMS  *int64     `json:"duration_ms,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	Instance    string     `json:"instance"`
+}
+
+type TaskStatus struct {
+	Local   TaskState   `json:"local"`
+	LastRun *ClusterRun `json:"last_run,omitempty"`
+}
+
+func (s *Service) Name() string {
+	return "scheduler"
+}
+
+// Status отдает локальное состояние задач и их последние запуски по всем репликам
+func (s *Service) Status(ctx context.Context) any {
+	statuses := make(map[string]*TaskStatus, len(s.tasks))
+	for _, t := range s.tasks {
+		statuses[t.name] = &TaskStatus{Local: t.snapshot()}
+	}
+
+	runs, err := s.repo.List(ctx)
+	if err != nil {
+		logger.Error(ctx, "List", logger.Err(err))
+		return statuses
+	}
+
+	for i := range runs {
+		run := &runs[i]
+		status, ok := statuses[run.Name]
+		if !ok {
+			continue
+		}
+
+		status.LastRun = &ClusterRun{
+			ScheduledAt: run.LastScheduledAt,
+			StartedAt:   run.LastStartedAt,
+			FinishedAt:  run.LastFinishedAt,
+			DurationMS:  run.LastDurationMS,
+			Instance:    run.LastInstance,
+		}
+		if run.LastError != nil {
+			status.LastRun.Error = *run.LastError
+		}
+	}
+
+	return statuses
+}
@@ -0,0 +1,10 @@
+package service
+
+import "errors"
+
+var (
+	ErrUnknownFormat  = errors.New("unknown seed format")
+	ErrInvalidMapping = errors.New("invalid column mapping")
+	ErrMissingColumn  = errors.New("column not found")
+	ErrInvalidRow     = errors.New("invalid row")
+)
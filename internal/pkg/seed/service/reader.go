@@ -0,0 +1,275 @@
+package service
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sshlykov/shortener/internal/domain"
+)
+
+type Format string
+
+const (
+	FormatCSV   Format = "csv"
+	FormatJSONL Format = "jsonl"
+	FormatJSON  Format = "json"
+)
+
+// ParseFormat разбирает формат, пустой формат определяется по расширению файла
+func ParseFormat(format, path string) (Format, error) {
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+
+	switch strings.ToLower(format) {
+	case "csv":
+		return FormatCSV, nil
+	case "jsonl", "ndjson":
+		return FormatJSONL, nil
+	case "json":
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+// Поля ссылки, которые можно загрузить
+const (
+	FieldKey       = "key"
+	FieldURL       = "url"
+	FieldOwner     = "owner"
+	FieldExpiresAt = "expires_at"
+)
+
+// Mapping - какая колонка файла заполняет поле ссылки, по умолчанию колонка называется как поле
+type Mapping map[string]string
+
+// ParseMapping разбирает список вида url=long_url,key=slug
+func ParseMapping(raw string) (Mapping, error) {
+	m := Mapping{FieldKey: FieldKey, FieldURL: FieldURL, FieldOwner: FieldOwner, FieldExpiresAt: FieldExpiresAt}
+	if strings.TrimSpace(raw) == "" {
+		return m, nil
+	}
+
+	for _, pair := range strings.Split(raw, ",") {
+		field, column, ok := strings.Cut(pair, "=")
+		field, column = strings.TrimSpace(field), strings.TrimSpace(column)
+		if !ok || column == "" {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidMapping, pair)
+		}
+		if _, known := m[field]; !known {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidMapping, field)
+		}
+		m[field] = column
+	}
+
+	return m, nil
+}
+
+// Row - строка файла. Ошибка разбора строки не прерывает загрузку и попадает в отчет.
+// Line - номер строки файла, для JSON-массива - номер элемента
+type Row struct {
+	Line int
+	Link domain.Link
+	Err  error
+}
+
+// Reader читает строки по одной, в конце возвращает io.EOF
+type Reader interface {
+	Next() (Row, error)
+}
+
+func NewReader(r io.Reader, format Format, mapping Mapping) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r, mapping)
+	case FormatJSONL:
+		return &jsonlReader{scanner: newScanner(r), mapping: mapping}, nil
+	case FormatJSON:
+		return newJSONReader(r, mapping)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+// bom - метка порядка байт, с которой Excel сохраняет CSV в UTF-8
+const bom = "\uFEFF"
+
+type csvReader struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+func newCSVReader(r io.Reader, mapping Mapping) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read csv header: %w", err)
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.TrimSpace(strings.TrimPrefix(name, bom))] = i
+	}
+
+	columns := make(map[string]int, len(mapping))
+	for field, column := range mapping {
+		if i, ok := index[column]; ok {
+			columns[field] = i
+		} else if field == FieldURL {
+			return nil, fmt.Errorf("%w: %q", ErrMissingColumn, column)
+		}
+	}
+
+	return &csvReader{r: cr, columns: columns}, nil
+}
+
+func (r *csvReader) Next() (Row, error) {
+	record, err := r.r.Read()
+	if err == io.EOF {
+		return Row{}, io.EOF
+	}
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return Row{Line: parseErr.StartLine, Err: fmt.Errorf("%w: %w", ErrInvalidRow, parseErr.Err)}, nil
+		}
+		return Row{}, err
+	}
+	line, _ := r.r.FieldPos(0)
+
+	values := make(map[string]string, len(r.columns))
+	for field, i := range r.columns {
+		if i < len(record) {
+			values[field] = strings.TrimSpace(record[i])
+		}
+	}
+
+	return toRow(line, values), nil
+}
+
+type jsonlReader struct {
+	scanner *bufio.Scanner
+	mapping Mapping
+	line    int
+}
+
+func (r *jsonlReader) Next() (Row, error) {
+	for r.scanner.Scan() {
+		r.line++
+		raw := strings.TrimSpace(r.scanner.Text())
+		if raw == "" {
+			continue
+		}
+
+		var obj map[string]any
+		if err := json.Unmarshal([]byte(raw), &obj); err != nil {
+			return Row{Line: r.line, Err: fmt.Errorf("%w: %w", ErrInvalidRow, err)}, nil
+		}
+
+		return toRow(r.line, mappedValues(obj, r.mapping)), nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return Row{}, err
+	}
+
+	return Row{}, io.EOF
+}
+
+// jsonReader читает JSON-массив объектов по одному элементу, не загружая его целиком
+type jsonReader struct {
+	dec     *json.Decoder
+	mapping Mapping
+	n       int
+}
+
+func newJSONReader(r io.Reader, mapping Mapping) (*jsonReader, error) {
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, fmt.Errorf("%w: expected a JSON array", ErrInvalidRow)
+	}
+
+	return &jsonReader{dec: dec, mapping: mapping}, nil
+}
+
+func (r *jsonReader) Next() (Row, error) {
+	if !r.dec.More() {
+		if _, err := r.dec.Token(); err != nil {
+			return Row{}, err
+		}
+		return Row{}, io.EOF
+	}
+	r.n++
+
+	var obj map[string]any
+	if err := r.dec.Decode(&obj); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return Row{Line: r.n, Err: fmt.Errorf("%w: element is not an object", ErrInvalidRow)}, nil
+		}
+		return Row{}, err
+	}
+
+	return toRow(r.n, mappedValues(obj, r.mapping)), nil
+}
+
+func mappedValues(obj map[string]any, mapping Mapping) map[string]string {
+	values := make(map[string]string, len(mapping))
+	for field, column := range mapping {
+		values[field] = stringValue(obj[column])
+	}
+
+	return values
+}
+
+// строка JSONL ограничена 1 МиБ
+const maxLineSize = 1 << 20
+
+func newScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	return scanner
+}
+
+func stringValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func toRow(line int, values map[string]string) Row {
+	row := Row{Line: line, Link: domain.Link{
+		Key:   values[FieldKey],
+		URL:   values[FieldURL],
+		Owner: values[FieldOwner],
+	}}
+
+	if raw := values[FieldExpiresAt]; raw != "" {
+		expiresAt, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			row.Err = fmt.Errorf("%w: expires_at should be RFC 3339: %q", ErrInvalidRow, raw)
+			return row
+		}
+		row.Link.ExpiresAt = &expiresAt
+	}
+
+	return row
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func readAll(t *testing.T, r Reader) []Row {
+	t.Helper()
+
+	var rows []Row
+	for {
+		row, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return rows
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		rows = append(rows, row)
+	}
+}
+
+func TestCSVReaderMapping(t *testing.T) {
+	mapping, err := ParseMapping("url=long_url, key=slug")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := "\uFEFFslug,long_url,owner,expires_at\n" +
+		"docs,https://example.com/docs,team,2030-01-01T00:00:00Z\n" +
+		"\"multi\nline\",https://example.com,,\n" +
+		"bad,https://example.com,,tomorrow\n"
+	r, err := NewReader(strings.NewReader(in), FormatCSV, mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := readAll(t, r)
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3", len(rows))
+	}
+	if l := rows[0].Link; l.Key != "docs" || l.URL != "https://example.com/docs" || l.Owner != "team" || l.ExpiresAt == nil {
+		t.Errorf("row 0 = %+v", l)
+	}
+	if rows[1].Line != 3 || rows[2].Line != 5 {
+		t.Errorf("lines = %d, %d; want 3, 5", rows[1].Line, rows[2].Line)
+	}
+	if !errors.Is(rows[2].Err, ErrInvalidRow) {
+		t.Errorf("row 2 error = %v, want ErrInvalidRow", rows[2].Err)
+	}
+}
+
+func TestCSVReaderMissingURLColumn(t *testing.T) {
+	mapping, _ := ParseMapping("")
+	if _, err := NewReader(strings.NewReader("key,link\n"), FormatCSV, mapping); !errors.Is(err, ErrMissingColumn) {
+		t.Errorf("NewReader() error = %v, want ErrMissingColumn", err)
+	}
+}
+
+func TestJSONLReader(t *testing.T) {
+	mapping, _ := ParseMapping("url=target")
+	in := `{"key": "abcd", "target": "https://example.com"}` + "\n\n" + `{"key": 12345, "target": "http://x.y"}` + "\n{broken\n"
+
+	r, err := NewReader(strings.NewReader(in), FormatJSONL, mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := readAll(t, r)
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3", len(rows))
+	}
+	if rows[1].Line != 3 || rows[1].Link.Key != "12345" {
+		t.Errorf("row 1 = %+v", rows[1])
+	}
+	if !errors.Is(rows[2].Err, ErrInvalidRow) {
+		t.Errorf("row 2 error = %v, want ErrInvalidRow", rows[2].Err)
+	}
+}
+
+func TestParseMappingErrors(t *testing.T) {
+	for _, raw := range []string{"link=url", "url", "url="} {
+		if _, err := ParseMapping(raw); !errors.Is(err, ErrInvalidMapping) {
+			t.Errorf("ParseMapping(%q) error = %v, want ErrInvalidMapping", raw, err)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat("", "links.ndjson"); err != nil || f != FormatJSONL {
+		t.Errorf("ParseFormat() = %q, %v", f, err)
+	}
+	if _, err := ParseFormat("", "links.xml"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("ParseFormat() error = %v, want ErrUnknownFormat", err)
+	}
+}
+
+func TestJSONReader(t *testing.T) {
+	mapping, _ := ParseMapping("")
+	in := `[{"url": "https://example.com", "key": "abcd"}, 42, {"url": "http://x.y"}]`
+
+	r, err := NewReader(strings.NewReader(in), FormatJSON, mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := readAll(t, r)
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3", len(rows))
+	}
+	if rows[0].Link.Key != "abcd" || !errors.Is(rows[1].Err, ErrInvalidRow) || rows[2].Line != 3 {
+		t.Errorf("rows = %+v", rows)
+	}
+
+	if _, err = NewReader(strings.NewReader(`{"url": "x"}`), FormatJSON, mapping); !errors.Is(err, ErrInvalidRow) {
+		t.Errorf("NewReader() error = %v, want ErrInvalidRow", err)
+	}
+}
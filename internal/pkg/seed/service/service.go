@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/sshlykov/shortener/internal/domain"
+)
+
+type LinkService interface {
+	Ensure(ctx context.Context, link domain.Link) (*domain.Link, bool, error)
+}
+
+// Service загружает ссылки из файлов через сервис ссылок, с той же валидацией, что и в API
+type Service struct {
+	links LinkService
+}
+
+func New(links LinkService) *Service {
+	return &Service{links: links}
+}
+
+// RowError - ошибка загрузки строки
+type RowError struct {
+	Line  int    `json:"line"`
+	Key   string `json:"key,omitempty"`
+	URL   string `json:"url,omitempty"`
+	Error string `json:"error"`
+}
+
+type Report struct {
+	Created int        `json:"created"`
+	Skipped int        `json:"skipped"`
+	Failed  int        `json:"failed"`
+	Errors  []RowError `json:"errors,omitempty"`
+}
+
+// Load загружает все строки. Повторная загрузка того же файла ничего не меняет: ссылка с ключом
+// пропускается, если ключ уже ведет на тот же url, а ссылка без ключа - если у владельца есть ссылка на этот url.
+// Ошибки строк собираются в отчет, ошибка возвращается, только если файл не удалось дочитать
+func (s *Service) Load(ctx context.Context, rows Reader) (*Report, error) {
+	report := &Report{}
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		row, err := rows.Next()
+		if errors.Is(err, io.EOF) {
+			return report, nil
+		}
+		if err != nil {
+			return report, err
+		}
+
+		if row.Err == nil {
+			var created bool
+			if _, created, row.Err = s.links.Ensure(ctx, row.Link); row.Err == nil {
+				if created {
+					report.Created++
+				} else {
+					report.Skipped++
+				}
+				continue
+			}
+		}
+
+		report.Failed++
+		report.Errors = append(report.Errors, RowError{
+			Line:  row.Line,
+			Key:   row.Link.Key,
+			URL:   row.Link.URL,
+			Error: row.Err.Error(),
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/sshlykov/shortener/internal/domain"
+	linksrv "github.com/sshlykov/shortener/internal/pkg/links/service"
+)
+
+type fakeLinks struct {
+	links []domain.Link
+}
+
+// Ensure повторяет правила linksrv.Service.Ensure на срезе ссылок
+func (f *fakeLinks) Ensure(_ context.Context, link domain.Link) (*domain.Link, bool, error) {
+	var err error
+	if link.URL, err = linksrv.NormalizeURL(link.URL); err != nil {
+		return nil, false, err
+	}
+	if link.Key != "" {
+		if err = linksrv.ValidateKey(link.Key); err != nil {
+			return nil, false, err
+		}
+	}
+
+	for i := range f.links {
+		existing := &f.links[i]
+		same := existing.URL == link.URL && existing.Owner == link.Owner
+		switch {
+		case link.Key != "" && existing.Key == link.Key && !same:
+			return nil, false, linksrv.ErrKeyConflict
+		case link.Key != "" && existing.Key == link.Key, link.Key == "" && same:
+			return existing, false, nil
+		}
+	}
+
+	if link.Key == "" {
+		link.Key = "gen" + string(rune('a'+len(f.links)))
+	}
+	f.links = append(f.links, link)
+	return &link, true, nil
+}
+
+type rowsReader struct {
+	rows []Row
+}
+
+func (r *rowsReader) Next() (Row, error) {
+	if len(r.rows) == 0 {
+		return Row{}, io.EOF
+	}
+	row := r.rows[0]
+	r.rows = r.rows[1:]
+	return row, nil
+}
+
+func TestLoadIsIdempotent(t *testing.T) {
+	rows := []Row{
+		{Line: 2, Link: domain.Link{Key: "docs", URL: "HTTPS://Example.com:443/docs"}},
+		{Line: 3, Link: domain.Link{URL: "https://example.com/free", Owner: "team"}},
+		{Line: 4, Link: domain.Link{Key: "no", URL: "https://example.com"}},
+		{Line: 5, Link: domain.Link{Key: "docs", URL: "https://example.com/other"}},
+		{Line: 6, Err: ErrInvalidRow},
+	}
+	links := &fakeLinks{}
+	s := New(links)
+
+	report, err := s.Load(context.Background(), &rowsReader{rows: rows})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Created != 2 || report.Skipped != 0 || report.Failed != 3 {
+		t.Fatalf("first load = %+v", report)
+	}
+	if report.Errors[0].Line != 4 {
+		t.Errorf("first error line = %d, want 4", report.Errors[0].Line)
+	}
+	if report.Errors[1].Error != linksrv.ErrKeyConflict.Error() {
+		t.Errorf("conflict error = %q", report.Errors[1].Error)
+	}
+	if links.links[0].URL != "https://example.com/docs" {
+		t.Errorf("url not normalized: %q", links.links[0].URL)
+	}
+
+	report, err = s.Load(context.Background(), &rowsReader{rows: rows})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Created != 0 || report.Skipped != 2 || len(links.links) != 2 {
+		t.Errorf("second load = %+v, links = %d", report, len(links.links))
+	}
+}
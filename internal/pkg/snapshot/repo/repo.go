@@ -0,0 +1,51 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sshlykov/shortener/pkg/postgres"
+)
+
+type Link struct {
+	Key       string     `db:"key"`
+	URL       string     `db:"url"`
+	ExpiresAt *time.Time `db:"expires_at"`
+}
+
+type Repository struct {
+	db postgres.DB
+}
+
+func New(db postgres.Client) *Repository {
+	return &Repository{db: db.DB()}
+}
+
+// ключи сортируются побайтово: по этому порядку идет бинарный поиск в файле снапшота
+const declareActive = `
+DECLARE links_snapshot NO SCROLL CURSOR FOR
+SELECT key, url, expires_at
+FROM links
+WHERE expired_at IS NULL
+  AND (expires_at IS NULL OR expires_at > now())
+ORDER BY key COLLATE "C"`
+
+// DeclareActive открывает курсор по действующим ссылкам, вызывается внутри транзакции
+func (r *Repository) DeclareActive(ctx context.Context) error {
+	q := postgres.Query{Name: "snapshot.declare", Raw: declareActive}
+	_, err := r.db.ExecContext(ctx, q)
+
+	return err
+}
+
+// FetchActive читает следующие n строк курсора
+func (r *Repository) FetchActive(ctx context.Context, n int) ([]Link, error) {
+	var links []Link
+	q := postgres.Query{Name: "snapshot.fetch", Raw: fmt.Sprintf("FETCH FORWARD %d FROM links_snapshot", n)}
+	if err := r.db.ScanAllContext(ctx, q, &links); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
@@ -0,0 +1,10 @@
+package service
+
+import "errors"
+
+var (
+	ErrInvalidTable = errors.New("invalid snapshot lookup file")
+	ErrUnsorted     = errors.New("snapshot keys are not sorted")
+	ErrTooLarge     = errors.New("snapshot exceeds 4 GiB")
+	ErrNoSnapshot   = errors.New("snapshot is not loaded")
+)
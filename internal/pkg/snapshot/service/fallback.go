@@ -0,0 +1,112 @@
+package service
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sshlykov/shortener/internal/domain"
+)
+
+// reloadInterval - как часто проверять, не обновился ли файл поиска
+const reloadInterval = 10 * time.Second
+
+// Fallback отдает ссылки из файла поиска снапшота, когда база недоступна. Файл читается
+// при первом обращении и перечитывается, если изменился, не чаще раза в reloadInterval
+type Fallback struct {
+	path string
+
+	mu        sync.Mutex
+	table     *Table
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+func NewFallback(path string) *Fallback {
+	return &Fallback{path: path}
+}
+
+// Lookup ищет ссылку по ключу. У ссылки из снапшота нет id, поэтому ID равен 0
+func (f *Fallback) Lookup(key string) (*domain.Link, bool) {
+	table, err := f.load()
+	if err != nil {
+		return nil, false
+	}
+
+	entry, ok := table.Lookup(key)
+	if !ok {
+		return nil, false
+	}
+
+	return &domain.Link{Key: entry.Key, URL: entry.URL, ExpiresAt: entry.ExpiresAt, CreatedAt: table.Created()}, true
+}
+
+// Reload сбрасывает интервал проверки, следующий Lookup перечитает файл, если он изменился
+func (f *Fallback) Reload() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.checkedAt = time.Time{}
+}
+
+func (f *Fallback) status() any {
+	table, err := f.load()
+
+	status := struct {
+		Path      string     `json:"path"`
+		Links     int        `json:"links"`
+		CreatedAt *time.Time `json:"created_at,omitempty"`
+		Error     string     `json:"error,omitempty"`
+	}{Path: f.path}
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	created := table.Created()
+	status.Links, status.CreatedAt = table.Len(), &created
+
+	return status
+}
+
+func (f *Fallback) load() (*Table, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(f.checkedAt) < reloadInterval {
+		return f.loaded()
+	}
+	f.checkedAt = now
+
+	info, err := os.Stat(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// файл удален - продолжаем отдавать загруженный снапшот
+		return f.loaded()
+	}
+	if err != nil || info.ModTime().Equal(f.modTime) {
+		return f.loaded()
+	}
+
+	raw, err := os.ReadFile(f.path)
+	if err != nil {
+		return f.loaded()
+	}
+	table, err := ParseTable(raw)
+	if err != nil {
+		// недописанный или битый файл не заменяет рабочий снапшот
+		return f.loaded()
+	}
+	f.table, f.modTime = table, info.ModTime()
+
+	return f.table, nil
+}
+
+func (f *Fallback) loaded() (*Table, error) {
+	if f.table == nil {
+		return nil, ErrNoSnapshot
+	}
+
+	return f.table, nil
+}
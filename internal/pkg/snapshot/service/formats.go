@@ -0,0 +1,86 @@
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	NginxFile = "links.map"
+	CaddyFile = "links.caddy"
+	TableFile = "links.bin"
+)
+
+// textFile пишет текстовый файл снапшота построчно во временный файл рядом с итоговым
+type textFile struct {
+	file *os.File
+	buf  *bufio.Writer
+	line func(key, url string) string
+}
+
+func newTextFile(dir, name, header string, line func(key, url string) string) (*textFile, error) {
+	file, err := os.CreateTemp(dir, ".snapshot-*-"+name)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &textFile{file: file, buf: bufio.NewWriter(file), line: line}
+	_, err = f.buf.WriteString(header)
+
+	return f, err
+}
+
+func (f *textFile) add(key, url string) error {
+	_, err := f.buf.WriteString(f.line(key, url))
+	return err
+}
+
+func (f *textFile) close() {
+	_ = f.file.Close()
+	_ = os.Remove(f.file.Name())
+}
+
+func nginxHeader(created time.Time) string {
+	return fmt.Sprintf(`# shortener redirect snapshot, generated at %s
+#
+# map $uri $shortener_target {
+#     include %s;
+# }
+# server {
+#     if ($shortener_target) {
+#         return 302 $shortener_target;
+#     }
+# }
+`, created.Format(time.RFC3339), NginxFile)
+}
+
+// nginxLine - строка блока map. В значении map подставляются переменные, поэтому $ в url экранируется процентной кодировкой
+func nginxLine(key, url string) string {
+	return fmt.Sprintf("%s %s;\n", nginxQuote("/"+key), nginxQuote(strings.ReplaceAll(url, "$", "%24")))
+}
+
+func nginxQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func caddyHeader(created time.Time) string {
+	return fmt.Sprintf(`# shortener redirect snapshot, generated at %s
+#
+# example.com {
+#     import %s
+# }
+`, created.Format(time.RFC3339), CaddyFile)
+}
+
+// caddyLine - директива redir. Фигурные скобки в url Caddy считает плейсхолдерами, они кодируются
+func caddyLine(key, url string) string {
+	url = strings.NewReplacer("{", "%7B", "}", "%7D").Replace(url)
+	return fmt.Sprintf("redir %s %s 302\n", caddyQuote("/"+key), caddyQuote(url))
+}
+
+func caddyQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
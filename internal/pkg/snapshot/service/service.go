@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sshlykov/shortener/internal/config"
+	repository "github.com/sshlykov/shortener/internal/pkg/snapshot/repo"
+	"github.com/sshlykov/shortener/pkg/logger"
+	"github.com/sshlykov/shortener/pkg/postgres"
+)
+
+const fetchSize = 1000
+
+type Repository interface {
+	DeclareActive(ctx context.Context) error
+	FetchActive(ctx context.Context, n int) ([]repository.Link, error)
+}
+
+// Service выгружает действующие ссылки в статические файлы редиректов: map для nginx,
+// конфиг для Caddy и бинарный файл поиска, из которого сервис отдает редиректы без базы
+type Service struct {
+	repo     Repository
+	tx       postgres.TxManager
+	cfg      config.Snapshot
+	fallback *Fallback
+}
+
+func New(db postgres.Client, tx postgres.TxManager, cfg config.Snapshot) *Service {
+	return &Service{
+		repo:     repository.New(db),
+		tx:       tx,
+		cfg:      cfg,
+		fallback: NewFallback(filepath.Join(cfg.Dir, TableFile)),
+	}
+}
+
+// Fallback возвращает резолвер по файлу поиска этого снапшота
+func (s *Service) Fallback() *Fallback {
+	return s.fallback
+}
+
+func (s *Service) Name() string {
+	return "snapshot"
+}
+
+// Status - загруженный файл поиска: число ссылок и время генерации
+func (s *Service) Status(context.Context) any {
+	return s.fallback.status()
+}
+
+// Check проверяет, что файл поиска читается: без него fallback не отдаст ни одной ссылки
+func (s *Service) Check(context.Context) error {
+	_, err := s.fallback.load()
+	return err
+}
+
+type Result struct {
+	Links     int       `json:"links"`
+	CreatedAt time.Time `json:"created_at"`
+	Files     []string  `json:"files"`
+}
+
+// Generate пишет файлы снапшота в cfg.Dir. Файлы собираются во временных файлах и заменяются
+// только после успешной выгрузки, поэтому читатели видят либо старый снапшот, либо новый целиком
+func (s *Service) Generate(ctx context.Context) (*Result, error) {
+	dir := s.cfg.Dir
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	created := time.Now().UTC()
+
+	nginx, err := newTextFile(dir, NginxFile, nginxHeader(created), nginxLine)
+	if err != nil {
+		return nil, err
+	}
+	defer nginx.close()
+	caddy, err := newTextFile(dir, CaddyFile, caddyHeader(created), caddyLine)
+	if err != nil {
+		return nil, err
+	}
+	defer caddy.close()
+	table, err := newTableWriter(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer table.close()
+
+	result := &Result{CreatedAt: created}
+	err = s.tx.ReadCommitted(ctx, func(ctx context.Context) error {
+		if err := s.repo.DeclareActive(ctx); err != nil {
+			return err
+		}
+
+		for {
+			rows, err := s.repo.FetchActive(ctx, fetchSize)
+			if err != nil {
+				return err
+			}
+
+			for _, row := range rows {
+				if err = table.add(row.Key, row.URL, row.ExpiresAt); err != nil {
+					return err
+				}
+				if err = nginx.add(row.Key, row.URL); err != nil {
+					return err
+				}
+				if err = caddy.add(row.Key, row.URL); err != nil {
+					return err
+				}
+				result.Links++
+			}
+			if len(rows) < fetchSize {
+				return nil
+			}
+		}
+	})
+	if err != nil {
+		logger.Error(ctx, "Generate", logger.Err(err))
+
+		return nil, err
+	}
+
+	if err = writeTable(table, filepath.Join(dir, TableFile), created); err != nil {
+		return nil, err
+	}
+	result.Files = append(result.Files, filepath.Join(dir, TableFile))
+	s.fallback.Reload()
+
+	for _, f := range []struct {
+		name string
+		file *textFile
+	}{{NginxFile, nginx}, {CaddyFile, caddy}} {
+		if err = f.file.buf.Flush(); err != nil {
+			return nil, err
+		}
+		if err = commit(f.file.file, filepath.Join(dir, f.name)); err != nil {
+			return nil, err
+		}
+		result.Files = append(result.Files, filepath.Join(dir, f.name))
+	}
+
+	return result, nil
+}
+
+// Refresh - задача планировщика snapshot.generate
+func (s *Service) Refresh(ctx context.Context) error {
+	result, err := s.Generate(ctx)
+	if err != nil {
+		return err
+	}
+	logger.Info(ctx, "snapshot generated", logger.Any("links", result.Links))
+
+	return nil
+}
+
+func writeTable(table *tableWriter, path string, created time.Time) error {
+	out, err := os.CreateTemp(filepath.Dir(path), ".snapshot-*-"+TableFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = out.Close()
+		_ = os.Remove(out.Name())
+	}()
+
+	if err = table.finish(out, created); err != nil {
+		return err
+	}
+
+	return commit(out, path)
+}
+
+// commit сбрасывает временный файл на диск и атомарно заменяет им path
+func commit(tmp *os.File, path string) error {
+	if err := tmp.Chmod(0o644); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
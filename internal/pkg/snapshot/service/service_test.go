@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sshlykov/shortener/internal/config"
+	repository "github.com/sshlykov/shortener/internal/pkg/snapshot/repo"
+	"github.com/sshlykov/shortener/pkg/postgres/pgtest"
+)
+
+type sliceRepo struct {
+	links []repository.Link
+}
+
+func (r *sliceRepo) DeclareActive(context.Context) error { return nil }
+
+func (r *sliceRepo) FetchActive(_ context.Context, n int) ([]repository.Link, error) {
+	n = min(n, len(r.links))
+	rows := r.links[:n]
+	r.links = r.links[n:]
+	return rows, nil
+}
+
+func TestGenerate(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "snapshot")
+	cfg := config.Snapshot{Dir: dir}
+	s := &Service{
+		repo: &sliceRepo{links: []repository.Link{
+			{Key: "docs", URL: `https://example.com/docs?q="x"`},
+			{Key: "pay", URL: "https://example.com/pay?sum=$10&tpl={id}"},
+		}},
+		tx:       pgtest.PassTx{},
+		cfg:      cfg,
+		fallback: NewFallback(filepath.Join(dir, TableFile)),
+	}
+
+	result, err := s.Generate(context.Background())
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if result.Links != 2 || len(result.Files) != 3 {
+		t.Errorf("result = %+v", result)
+	}
+
+	nginx, _ := os.ReadFile(filepath.Join(dir, NginxFile))
+	for _, line := range []string{
+		`"/docs" "https://example.com/docs?q=\"x\"";`,
+		`"/pay" "https://example.com/pay?sum=%2410&tpl={id}";`,
+	} {
+		if !strings.Contains(string(nginx), line+"\n") {
+			t.Errorf("nginx map has no line %s:\n%s", line, nginx)
+		}
+	}
+	caddy, _ := os.ReadFile(filepath.Join(dir, CaddyFile))
+	if line := `redir "/pay" "https://example.com/pay?sum=$10&tpl=%7Bid%7D" 302`; !strings.Contains(string(caddy), line+"\n") {
+		t.Errorf("caddy config has no line %s:\n%s", line, caddy)
+	}
+
+	link, ok := s.Fallback().Lookup("pay")
+	if !ok || link.URL != "https://example.com/pay?sum=$10&tpl={id}" || link.ID != 0 {
+		t.Errorf("fallback Lookup() = %+v, %v", link, ok)
+	}
+
+	tmp, _ := filepath.Glob(filepath.Join(dir, ".snapshot-*"))
+	if len(tmp) != 0 {
+		t.Errorf("temporary files left: %v", tmp)
+	}
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+// Формат файла поиска, все числа little endian:
+//
+//	magic    [4]byte "SHRT"
+//	version  uint16
+//	reserved uint16
+//	count    uint32
+//	created  int64, unix seconds
+//	offsets  [count]uint32 - смещение записи от начала данных, записи отсортированы по ключу побайтово
+//	data     записи: uvarint длина ключа, ключ, uvarint длина url, url, varint expires_at (unix seconds, 0 - без срока)
+//	crc32    uint32, IEEE по всему предыдущему содержимому
+//
+// Поиск - бинарный по таблице смещений, файл целиком читается в память
+const (
+	tableMagic   = "SHRT"
+	tableVersion = 1
+	headerSize   = 4 + 2 + 2 + 4 + 8
+)
+
+// Entry - ссылка из файла поиска
+type Entry struct {
+	Key       string
+	URL       string
+	ExpiresAt *time.Time
+}
+
+// tableWriter копит записи во временном файле: число записей и смещения известны только в конце
+type tableWriter struct {
+	data    *os.File
+	buf     *bufio.Writer
+	offsets []uint32
+	size    uint64
+	lastKey string
+}
+
+func newTableWriter(dir string) (*tableWriter, error) {
+	data, err := os.CreateTemp(dir, ".snapshot-*.data")
+	if err != nil {
+		return nil, err
+	}
+
+	return &tableWriter{data: data, buf: bufio.NewWriter(data)}, nil
+}
+
+func (w *tableWriter) add(key, url string, expiresAt *time.Time) error {
+	if len(w.offsets) > 0 && key <= w.lastKey {
+		return fmt.Errorf("%w: %q after %q", ErrUnsorted, key, w.lastKey)
+	}
+	if w.size > math.MaxUint32 {
+		return ErrTooLarge
+	}
+	w.offsets = append(w.offsets, uint32(w.size))
+	w.lastKey = key
+
+	var expires int64
+	if expiresAt != nil {
+		expires = expiresAt.Unix()
+	}
+
+	record := binary.AppendUvarint(nil, uint64(len(key)))
+	record = append(record, key...)
+	record = binary.AppendUvarint(record, uint64(len(url)))
+	record = append(record, url...)
+	record = binary.AppendVarint(record, expires)
+
+	w.size += uint64(len(record))
+	_, err := w.buf.Write(record)
+
+	return err
+}
+
+// finish пишет файл целиком в out
+func (w *tableWriter) finish(out io.Writer, created time.Time) error {
+	if w.size > math.MaxUint32 {
+		return ErrTooLarge
+	}
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	if _, err := w.data.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	crc := crc32.NewIEEE()
+	dst := bufio.NewWriter(io.MultiWriter(out, crc))
+
+	header := make([]byte, 0, headerSize+4*len(w.offsets))
+	header = append(header, tableMagic...)
+	header = binary.LittleEndian.AppendUint16(header, tableVersion)
+	header = binary.LittleEndian.AppendUint16(header, 0)
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(w.offsets)))
+	header = binary.LittleEndian.AppendUint64(header, uint64(created.Unix()))
+	for _, offset := range w.offsets {
+		header = binary.LittleEndian.AppendUint32(header, offset)
+	}
+	if _, err := dst.Write(header); err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, w.data); err != nil {
+		return err
+	}
+	if err := dst.Flush(); err != nil {
+		return err
+	}
+
+	return binary.Write(out, binary.LittleEndian, crc.Sum32())
+}
+
+func (w *tableWriter) close() {
+	_ = w.data.Close()
+	_ = os.Remove(w.data.Name())
+}
+
+// Table - загруженный файл поиска
+type Table struct {
+	created time.Time
+	offsets []byte
+	data    []byte
+}
+
+// ParseTable проверяет файл поиска и готовит его к поиску без копирования данных
+func ParseTable(raw []byte) (*Table, error) {
+	if len(raw) < headerSize+4 || string(raw[:4]) != tableMagic {
+		return nil, fmt.Errorf("%w: bad header", ErrInvalidTable)
+	}
+	if version := binary.LittleEndian.Uint16(raw[4:]); version != tableVersion {
+		return nil, fmt.Errorf("%w: version %d", ErrInvalidTable, version)
+	}
+
+	body, sum := raw[:len(raw)-4], binary.LittleEndian.Uint32(raw[len(raw)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrInvalidTable)
+	}
+
+	count := int(binary.LittleEndian.Uint32(raw[8:]))
+	created := int64(binary.LittleEndian.Uint64(raw[12:]))
+	if len(body) < headerSize+4*count {
+		return nil, fmt.Errorf("%w: truncated offsets", ErrInvalidTable)
+	}
+
+	return &Table{
+		created: time.Unix(created, 0).UTC(),
+		offsets: body[headerSize : headerSize+4*count],
+		data:    body[headerSize+4*count:],
+	}, nil
+}
+
+func (t *Table) Len() int {
+	return len(t.offsets) / 4
+}
+
+// Created - время генерации снапшота
+func (t *Table) Created() time.Time {
+	return t.created
+}
+
+func (t *Table) Lookup(key string) (Entry, bool) {
+	n := t.Len()
+	i := sort.Search(n, func(i int) bool {
+		k, _ := t.key(i)
+		return k >= key
+	})
+	if i == n {
+		return Entry{}, false
+	}
+
+	entry, err := t.entry(i)
+	if err != nil || entry.Key != key {
+		return Entry{}, false
+	}
+
+	return entry, true
+}
+
+func (t *Table) key(i int) (string, []byte) {
+	offset := binary.LittleEndian.Uint32(t.offsets[4*i:])
+	if int(offset) >= len(t.data) {
+		return "", nil
+	}
+	rec := t.data[offset:]
+
+	l, n := binary.Uvarint(rec)
+	if n <= 0 || uint64(len(rec)-n) < l {
+		return "", nil
+	}
+
+	return string(rec[n : n+int(l)]), rec[n+int(l):]
+}
+
+func (t *Table) entry(i int) (Entry, error) {
+	key, rest := t.key(i)
+	if rest == nil {
+		return Entry{}, ErrInvalidTable
+	}
+
+	l, n := binary.Uvarint(rest)
+	if n <= 0 || uint64(len(rest)-n) < l {
+		return Entry{}, ErrInvalidTable
+	}
+	url := string(rest[n : n+int(l)])
+
+	expires, m := binary.Varint(rest[n+int(l):])
+	if m <= 0 {
+		return Entry{}, ErrInvalidTable
+	}
+
+	entry := Entry{Key: key, URL: url}
+	if expires != 0 {
+		t := time.Unix(expires, 0).UTC()
+		entry.ExpiresAt = &t
+	}
+
+	return entry, nil
+}
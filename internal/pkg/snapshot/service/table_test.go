@@ -0,0 +1,86 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func buildTable(t *testing.T, keys ...string) []byte {
+	t.Helper()
+
+	w, err := newTableWriter(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.close()
+
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, key := range keys {
+		var at *time.Time
+		if i%2 == 1 {
+			at = &expires
+		}
+		if err = w.add(key, "https://example.com/"+key, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err = w.finish(&buf, time.Unix(1700000000, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestTableLookup(t *testing.T) {
+	keys := make([]string, 0, 100)
+	for i := 0; i < 100; i++ {
+		keys = append(keys, fmt.Sprintf("k%03d", i))
+	}
+
+	table, err := ParseTable(buildTable(t, keys...))
+	if err != nil {
+		t.Fatalf("ParseTable() error = %v", err)
+	}
+	if table.Len() != 100 || table.Created().Unix() != 1700000000 {
+		t.Errorf("table len = %d, created = %v", table.Len(), table.Created())
+	}
+
+	for i, key := range keys {
+		entry, ok := table.Lookup(key)
+		if !ok || entry.URL != "https://example.com/"+key || (entry.ExpiresAt != nil) != (i%2 == 1) {
+			t.Errorf("Lookup(%q) = %+v, %v", key, entry, ok)
+		}
+	}
+	for _, key := range []string{"", "k", "k0995", "zzz"} {
+		if _, ok := table.Lookup(key); ok {
+			t.Errorf("Lookup(%q) found a missing key", key)
+		}
+	}
+}
+
+func TestTableRejects(t *testing.T) {
+	raw := buildTable(t, "a", "b")
+	corrupt := bytes.Clone(raw)
+	corrupt[len(corrupt)-8] ^= 0xff
+
+	for name, data := range map[string][]byte{"empty": nil, "corrupt": corrupt, "truncated": raw[:len(raw)-1]} {
+		if _, err := ParseTable(data); !errors.Is(err, ErrInvalidTable) {
+			t.Errorf("%s: ParseTable() error = %v, want ErrInvalidTable", name, err)
+		}
+	}
+
+	w, err := newTableWriter(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.close()
+	_ = w.add("b", "https://example.com", nil)
+	if err = w.add("a", "https://example.com", nil); !errors.Is(err, ErrUnsorted) {
+		t.Errorf("add() error = %v, want ErrUnsorted", err)
+	}
+}
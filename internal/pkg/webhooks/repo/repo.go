@@ -0,0 +1,225 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/sshlykov/shortener/pkg/postgres"
+)
+
+type Subscription struct {
+	SubscriptionID int64     `db:"subscription_id"`
+	URL            string    `db:"url"`
+	Secret         string    `db:"secret"`
+	Events         []string  `db:"events"`
+	Owner          string    `db:"owner"`
+	Active         bool      `db:"active"`
+	CreatedAt      time.Time `db:"created_at"`
+}
+
+type Delivery struct {
+	DeliveryID     int64      `db:"delivery_id"`
+	SubscriptionID int64      `db:"subscription_id"`
+	EventID        string     `db:"event_id"`
+	Event          string     `db:"event"`
+	Payload        []byte     `db:"payload"`
+	Status         string     `db:"status"`
+	Attempts       int        `db:"attempts"`
+	NextAttemptAt  time.Time  `db:"next_attempt_at"`
+	LastStatusCode *int       `db:"last_status_code"`
+	LastError      *string    `db:"last_error"`
+	CreatedAt      time.Time  `db:"created_at"`
+	DeliveredAt    *time.Time `db:"delivered_at"`
+}
+
+// ClaimedDelivery - доставка вместе с адресом и секретом подписки
+type ClaimedDelivery struct {
+	Delivery
+	URL    string `db:"url"`
+	Secret string `db:"secret"`
+}
+
+type Repository struct {
+	db postgres.DB
+}
+
+func New(db postgres.Client) *Repository {
+	return &Repository{db: db.DB()}
+}
+
+const subscriptionColumns = `subscription_id, url, secret, events, owner, active, created_at`
+
+const insertSubscription = `
+INSERT INTO webhook_subscriptions (url, secret, events, owner)
+VALUES ($1, $2, $3, $4)
+RETURNING ` + subscriptionColumns
+
+func (r *Repository) CreateSubscription(ctx context.Context, sub *Subscription) (*Subscription, error) {
+	var created Subscription
+	q := postgres.Query{Name: "webhooks.insert_subscription", Raw: insertSubscription}
+	if err := r.db.ScanSingleContext(ctx, q, &created, sub.URL, sub.Secret, sub.Events, sub.Owner); err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+const listSubscriptions = `
+SELECT ` + subscriptionColumns + `
+FROM webhook_subscriptions
+WHERE ($1 = '' OR owner = $1)
+ORDER BY subscription_id`
+
+func (r *Repository) ListSubscriptions(ctx context.Context, owner string) ([]Subscription, error) {
+	var subs []Subscription
+	q := postgres.Query{Name: "webhooks.list_subscriptions", Raw: listSubscriptions}
+	if err := r.db.ScanAllContext(ctx, q, &subs, owner); err != nil {
+		return nil, err
+	}
+
+	return subs, nil
+}
+
+const deleteSubscription = `DELETE FROM webhook_subscriptions WHERE subscription_id = $1`
+
+func (r *Repository) DeleteSubscription(ctx context.Context, id int64) (bool, error) {
+	q := postgres.Query{Name: "webhooks.delete_subscription", Raw: deleteSubscription}
+	tag, err := r.db.ExecContext(ctx, q, id)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+const hasSubscribers = `
+SELECT EXISTS (SELECT 1 FROM webhook_subscriptions WHERE active AND $1 = ANY (events))`
+
+// HasSubscribers проверяет, есть ли активная подписка на событие хотя бы у одного владельца
+func (r *Repository) HasSubscribers(ctx context.Context, event string) (bool, error) {
+	var exists bool
+	q := postgres.Query{Name: "webhooks.has_subscribers", Raw: hasSubscribers, ReadOnly: true}
+	if err := r.db.QueryRowContext(ctx, q, event).Scan(&exists); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+const enqueueDeliveries = `
+INSERT INTO webhook_deliveries (subscription_id, event_id, event, payload)
+SELECT subscription_id, $1, $2, $4
+FROM webhook_subscriptions
+WHERE active
+  AND $2 = ANY (events)
+  AND (owner = '' OR owner = $3)
+ON CONFLICT (subscription_id, event_id) DO NOTHING`
+
+// Enqueue ставит событие в очередь доставки для всех подходящих подписок
+func (r *Repository) Enqueue(ctx context.Context, eventID, event, owner string, payload []byte) (int64, error) {
+	q := postgres.Query{Name: "webhooks.enqueue", Raw: enqueueDeliveries}
+	tag, err := r.db.ExecContext(ctx, q, eventID, event, owner, payload)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+const deliveryColumns = `d.delivery_id, d.subscription_id, d.event_id, d.event, d.payload, d.status, d.attempts,
+       d.next_attempt_at, d.last_status_code, d.last_error, d.created_at, d.delivered_at`
+
+const claimDeliveries = `
+UPDATE webhook_deliveries d
+SET attempts        = d.attempts + 1,
+    next_attempt_at = now() + make_interval(secs => $2)
+FROM webhook_subscriptions s
+WHERE d.delivery_id IN (SELECT delivery_id
+                        FROM webhook_deliveries
+                        WHERE status = 'pending'
+                          AND next_attempt_at <= now()
+                        ORDER BY next_attempt_at
+                        LIMIT $1 FOR UPDATE SKIP LOCKED)
+  AND s.subscription_id = d.subscription_id
+RETURNING ` + deliveryColumns + `, s.url, s.secret`
+
+// Claim забирает готовые к отправке доставки. На время отправки next_attempt_at сдвигается на lease,
+// чтобы другие реплики их не взяли, а при падении процесса доставка вернулась в очередь
+func (r *Repository) Claim(ctx context.Context, limit int, lease time.Duration) ([]ClaimedDelivery, error) {
+	var deliveries []ClaimedDelivery
+	q := postgres.Query{Name: "webhooks.claim", Raw: claimDeliveries}
+	if err := r.db.ScanAllContext(ctx, q, &deliveries, limit, lease.Seconds()); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+const markDelivered = `
+UPDATE webhook_deliveries
+SET status           = 'delivered',
+    last_status_code = $2,
+    last_error       = NULL,
+    delivered_at     = now()
+WHERE delivery_id = $1`
+
+func (r *Repository) MarkDelivered(ctx context.Context, id int64, statusCode int) error {
+	q := postgres.Query{Name: "webhooks.mark_delivered", Raw: markDelivered}
+	_, err := r.db.ExecContext(ctx, q, id, statusCode)
+
+	return err
+}
+
+const markFailed = `
+UPDATE webhook_deliveries
+SET status           = $2,
+    last_status_code = $3,
+    last_error       = $4,
+    next_attempt_at  = $5
+WHERE delivery_id = $1`
+
+func (r *Repository) MarkFailed(ctx context.Context, id int64, status string, statusCode *int, lastError string,
+	nextAttemptAt time.Time) error {
+	q := postgres.Query{Name: "webhooks.mark_failed", Raw: markFailed}
+	_, err := r.db.ExecContext(ctx, q, id, status, statusCode, lastError, nextAttemptAt)
+
+	return err
+}
+
+const listDeliveries = `
+SELECT ` + deliveryColumns + `
+FROM webhook_deliveries d
+WHERE d.subscription_id = $1
+  AND ($2 = '' OR d.status = $2)
+ORDER BY d.delivery_id DESC
+LIMIT $3 OFFSET $4`
+
+func (r *Repository) ListDeliveries(ctx context.Context, subscriptionID int64, status string,
+	limit, offset int) ([]Delivery, error) {
+	var deliveries []Delivery
+	q := postgres.Query{Name: "webhooks.list_deliveries", Raw: listDeliveries, ReadOnly: true}
+	if err := r.db.ScanAllContext(ctx, q, &deliveries, subscriptionID, status, limit, offset); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+const replayDelivery = `
+UPDATE webhook_deliveries d
+SET status          = 'pending',
+    attempts        = 0,
+    next_attempt_at = now(),
+    last_error      = NULL
+WHERE d.delivery_id = $1
+RETURNING ` + deliveryColumns
+
+func (r *Repository) Replay(ctx context.Context, id int64) (*Delivery, error) {
+	var delivery Delivery
+	q := postgres.Query{Name: "webhooks.replay", Raw: replayDelivery}
+	if err := r.db.ScanSingleContext(ctx, q, &delivery, id); err != nil {
+		return nil, err
+	}
+
+	return &delivery, nil
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sshlykov/shortener/internal/domain"
+	"github.com/sshlykov/shortener/pkg/logger"
+)
+
+// Publish ставит событие в очередь доставки подписчикам, повторная постановка того же события игнорируется
+func (s *Service) Publish(ctx context.Context, event domain.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	if _, err = s.repo.Enqueue(ctx, event.ID, string(event.Type), event.Owner, payload); err != nil {
+		logger.Error(ctx, "Publish", logger.Err(err), logger.Any("event", event.Type))
+
+		return ErrCantEnqueue
+	}
+
+	return nil
+}
+
+// Deliveries - журнал доставок подписки, от новых к старым
+func (s *Service) Deliveries(ctx context.Context, subscriptionID int64, status domain.DeliveryStatus,
+	limit, offset int) ([]*domain.WebhookDelivery, error) {
+	rows, err := s.repo.ListDeliveries(ctx, subscriptionID, string(status), limit, offset)
+	if err != nil {
+		logger.Error(ctx, "Deliveries", logger.Err(err), logger.Any("subscription_id", subscriptionID))
+
+		return nil, ErrCantListDeliveries
+	}
+
+	deliveries := make([]*domain.WebhookDelivery, 0, len(rows))
+	for i := range rows {
+		deliveries = append(deliveries, deliveryToDomain(&rows[i]))
+	}
+
+	return deliveries, nil
+}
+
+// Replay возвращает доставку в очередь со сброшенным счетчиком попыток (в том числе из dead)
+func (s *Service) Replay(ctx context.Context, deliveryID int64) (*domain.WebhookDelivery, error) {
+	delivery, err := s.repo.Replay(ctx, deliveryID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		logger.Error(ctx, "Replay", logger.Err(err), logger.Any("delivery_id", deliveryID))
+
+		return nil, ErrCantReplayDelivery
+	}
+
+	return deliveryToDomain(delivery), nil
+}
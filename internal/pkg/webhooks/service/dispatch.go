@@ -0,0 +1,122 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sshlykov/shortener/internal/domain"
+	repository "github.com/sshlykov/shortener/internal/pkg/webhooks/repo"
+	"github.com/sshlykov/shortener/pkg/backoff"
+	"github.com/sshlykov/shortener/pkg/logger"
+)
+
+const maxResponseDrain = 64 * 1024
+
+// Run забирает доставки из очереди, пока не будет отменен контекст.
+// Начатые отправки доводятся до конца, чтобы не сжигать попытки при остановке
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.drain(ctx)
+		}
+	}
+}
+
+// drain отправляет пачки, пока очередь не опустеет
+func (s *Service) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		if s.dispatch(ctx) < s.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+// dispatch отправляет одну пачку доставок и возвращает ее размер
+func (s *Service) dispatch(ctx context.Context) int {
+	deliveries, err := s.repo.Claim(ctx, s.cfg.BatchSize, 2*s.cfg.Timeout)
+	if err != nil {
+		logger.Error(ctx, "Claim", logger.Err(err))
+		return 0
+	}
+
+	inflight := context.WithoutCancel(ctx)
+
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		wg.Add(1)
+		go func(d *repository.ClaimedDelivery) {
+			defer wg.Done()
+			s.deliver(inflight, d)
+		}(&deliveries[i])
+	}
+	wg.Wait()
+
+	return len(deliveries)
+}
+
+func (s *Service) deliver(ctx context.Context, d *repository.ClaimedDelivery) {
+	statusCode, err := s.send(ctx, d)
+	if err == nil {
+		if err = s.repo.MarkDelivered(ctx, d.DeliveryID, statusCode); err != nil {
+			logger.Error(ctx, "MarkDelivered", logger.Err(err), logger.Any("delivery_id", d.DeliveryID))
+		}
+		return
+	}
+
+	var code *int
+	if statusCode != 0 {
+		code = &statusCode
+	}
+
+	status := domain.DeliveryPending
+	if d.Attempts >= s.cfg.MaxAttempts {
+		status = domain.DeliveryDead
+	}
+
+	logger.Warn(ctx, "webhook delivery failed", logger.Err(err), logger.Any("delivery_id", d.DeliveryID),
+		logger.Any("attempt", d.Attempts), logger.Any("status", status))
+
+	next := time.Now().Add(backoff.Delay(s.cfg.InitialInterval, s.cfg.MaxInterval, d.Attempts))
+	if err = s.repo.MarkFailed(ctx, d.DeliveryID, string(status), code, err.Error(), next); err != nil {
+		logger.Error(ctx, "MarkFailed", logger.Err(err), logger.Any("delivery_id", d.DeliveryID))
+	}
+}
+
+func (s *Service) send(ctx context.Context, d *repository.ClaimedDelivery) (int, error) {
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, d.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(d.DeliveryID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(d.Secret, timestamp, d.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseDrain))
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return resp.StatusCode, fmt.Errorf("%w: %d", ErrUnexpectedStatus, resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sshlykov/shortener/internal/config"
+	"github.com/sshlykov/shortener/internal/domain"
+	repository "github.com/sshlykov/shortener/internal/pkg/webhooks/repo"
+	"github.com/sshlykov/shortener/pkg/backoff"
+)
+
+type outcome struct {
+	status     string
+	statusCode *int
+	lastError  string
+	next       time.Time
+}
+
+type fakeRepo struct {
+	Repository
+
+	mu         sync.Mutex
+	deliveries []repository.ClaimedDelivery
+	lease      time.Duration
+	outcomes   map[int64]outcome
+	replayed   map[int64]*repository.Delivery
+}
+
+func (r *fakeRepo) Claim(_ context.Context, limit int, lease time.Duration) ([]repository.ClaimedDelivery, error) {
+	r.lease = lease
+	n := min(limit, len(r.deliveries))
+	claimed := r.deliveries[:n]
+	r.deliveries = r.deliveries[n:]
+	return claimed, nil
+}
+
+func (r *fakeRepo) MarkDelivered(_ context.Context, id int64, statusCode int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.outcomes[id] = outcome{status: string(domain.DeliveryDelivered), statusCode: &statusCode}
+	return nil
+}
+
+func (r *fakeRepo) MarkFailed(_ context.Context, id int64, status string, statusCode *int, lastError string,
+	nextAttemptAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.outcomes[id] = outcome{status: status, statusCode: statusCode, lastError: lastError, next: nextAttemptAt}
+	return nil
+}
+
+func (r *fakeRepo) Replay(_ context.Context, id int64) (*repository.Delivery, error) {
+	d, ok := r.replayed[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return d, nil
+}
+
+func newTestService(repo Repository) *Service {
+	cfg := config.Webhooks{
+		BatchSize: 10, Timeout: time.Second, MaxAttempts: 3,
+		InitialInterval: time.Second, MaxInterval: time.Minute,
+	}
+	return &Service{repo: repo, cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+}
+
+func TestDispatch(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+		if !Verify("secret", timestamp, body, r.Header.Get(HeaderSignature)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	code := func(c int) *int { return &c }
+	tests := []struct {
+		name      string
+		url       string
+		secret    string
+		attempts  int
+		status    domain.DeliveryStatus
+		code      *int
+		retriedIn time.Duration
+	}{
+		{name: "delivered", url: receiver.URL + "/ok", secret: "secret", attempts: 1,
+			status: domain.DeliveryDelivered, code: code(http.StatusNoContent)},
+		{name: "server error is retried", url: receiver.URL + "/down", secret: "secret", attempts: 1,
+			status: domain.DeliveryPending, code: code(http.StatusInternalServerError), retriedIn: time.Second},
+		{name: "backoff grows with attempts", url: receiver.URL + "/down", secret: "secret", attempts: 2,
+			status: domain.DeliveryPending, code: code(http.StatusInternalServerError), retriedIn: 1500 * time.Millisecond},
+		{name: "bad signature is retried", url: receiver.URL + "/ok", secret: "other", attempts: 1,
+			status: domain.DeliveryPending, code: code(http.StatusUnauthorized), retriedIn: time.Second},
+		{name: "connection error has no status", url: closed.URL, secret: "secret", attempts: 1,
+			status: domain.DeliveryPending, retriedIn: time.Second},
+		{name: "dead after max attempts", url: receiver.URL + "/down", secret: "secret", attempts: 3,
+			status: domain.DeliveryDead, code: code(http.StatusInternalServerError), retriedIn: 2250 * time.Millisecond},
+	}
+
+	repo := &fakeRepo{outcomes: map[int64]outcome{}}
+	for i, tt := range tests {
+		repo.deliveries = append(repo.deliveries, repository.ClaimedDelivery{
+			Delivery: repository.Delivery{DeliveryID: int64(i + 1), Event: string(domain.EventLinkCreated),
+				Payload: []byte(`{"type":"link.created"}`), Attempts: tt.attempts},
+			URL:    tt.url,
+			Secret: tt.secret,
+		})
+	}
+	s := newTestService(repo)
+
+	started := time.Now()
+	if n := s.dispatch(context.Background()); n != len(tests) {
+		t.Fatalf("dispatch() = %d, want %d", n, len(tests))
+	}
+	if repo.lease != 2*s.cfg.Timeout {
+		t.Errorf("claim lease = %s, want twice the request timeout", repo.lease)
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := repo.outcomes[int64(i+1)]
+			if !ok {
+				t.Fatal("delivery was not marked")
+			}
+			if got.status != string(tt.status) {
+				t.Errorf("status = %s, want %s", got.status, tt.status)
+			}
+			if (got.statusCode == nil) != (tt.code == nil) || got.statusCode != nil && *got.statusCode != *tt.code {
+				t.Errorf("status code = %v, want %v", got.statusCode, tt.code)
+			}
+			if tt.status == domain.DeliveryDelivered {
+				return
+			}
+			if got.lastError == "" {
+				t.Error("last error is empty")
+			}
+			// backoff.Delay разбрасывает интервал на RandomizationFactor в обе стороны
+			spread := time.Duration(backoff.DefaultRandomizationFactor * float64(tt.retriedIn))
+			if delay := got.next.Sub(started); delay < tt.retriedIn-spread || delay > tt.retriedIn+spread+time.Second {
+				t.Errorf("next attempt in %s, want %s ± %s", delay, tt.retriedIn, spread)
+			}
+		})
+	}
+}
+
+func TestDispatchEmpty(t *testing.T) {
+	repo := &fakeRepo{outcomes: map[int64]outcome{}}
+	if n := newTestService(repo).dispatch(context.Background()); n != 0 || len(repo.outcomes) != 0 {
+		t.Errorf("dispatch() = %d with outcomes %v", n, repo.outcomes)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	repo := &fakeRepo{replayed: map[int64]*repository.Delivery{
+		1: {DeliveryID: 1, Status: string(domain.DeliveryPending)},
+	}}
+	s := newTestService(repo)
+
+	got, err := s.Replay(context.Background(), 1)
+	if err != nil || got.ID != 1 || got.Status != domain.DeliveryPending || got.Attempts != 0 {
+		t.Errorf("Replay(1) = %+v, %v", got, err)
+	}
+	if _, err = s.Replay(context.Background(), 2); !errors.Is(err, ErrDeliveryNotFound) {
+		t.Errorf("Replay(2) error = %v, want %v", err, ErrDeliveryNotFound)
+	}
+}
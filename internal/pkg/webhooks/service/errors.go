@@ -0,0 +1,17 @@
+package service
+
+import "errors"
+
+var (
+	ErrInvalidURL             = errors.New("invalid webhook url")
+	ErrInvalidEvents          = errors.New("invalid webhook events")
+	ErrSubscriptionNotFound   = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound       = errors.New("webhook delivery not found")
+	ErrCantCreateSubscription = errors.New("can't create webhook subscription")
+	ErrCantListSubscriptions  = errors.New("can't list webhook subscriptions")
+	ErrCantDeleteSubscription = errors.New("can't delete webhook subscription")
+	ErrCantEnqueue            = errors.New("can't enqueue webhook deliveries")
+	ErrCantListDeliveries     = errors.New("can't list webhook deliveries")
+	ErrCantReplayDelivery     = errors.New("can't replay webhook delivery")
+	ErrUnexpectedStatus       = errors.New("unexpected response status")
+)
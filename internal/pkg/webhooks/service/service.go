@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/sshlykov/shortener/internal/config"
+	"github.com/sshlykov/shortener/internal/domain"
+	repository "github.com/sshlykov/shortener/internal/pkg/webhooks/repo"
+	"github.com/sshlykov/shortener/pkg/postgres"
+)
+
+type Repository interface {
+	CreateSubscription(ctx context.Context, sub *repository.Subscription) (*repository.Subscription, error)
+	ListSubscriptions(ctx context.Context, owner string) ([]repository.Subscription, error)
+	DeleteSubscription(ctx context.Context, id int64) (bool, error)
+	Enqueue(ctx context.Context, eventID, event, owner string, payload []byte) (int64, error)
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]repository.ClaimedDelivery, error)
+	MarkDelivered(ctx context.Context, id int64, statusCode int) error
+	MarkFailed(ctx context.Context, id int64, status string, statusCode *int, lastError string,
+		nextAttemptAt time.Time) error
+	ListDeliveries(ctx context.Context, subscriptionID int64, status string, limit, offset int) ([]repository.Delivery, error)
+	Replay(ctx context.Context, id int64) (*repository.Delivery, error)
+	HasSubscribers(ctx context.Context, event string) (bool, error)
+}
+
+// Service управляет подписками на вебхуки и доставляет события из персистентной очереди
+type Service struct {
+	repo   Repository
+	cfg    config.Webhooks
+	client *http.Client
+	// subscribed - есть ли подписчики на событие, сбрасывается при изменении подписок на этой реплике
+	subscribed *subscribedCache
+}
+
+func New(db postgres.Client, cfg config.Webhooks) *Service {
+	return &Service{
+		repo:   repository.New(db),
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+
+		subscribed: newSubscribedCache(cfg.SubscribedTTL),
+	}
+}
+
+func subscriptionToDomain(sub *repository.Subscription) *domain.WebhookSubscription {
+	events := make([]domain.EventType, 0, len(sub.Events))
+	for _, event := range sub.Events {
+		events = append(events, domain.EventType(event))
+	}
+
+	return &domain.WebhookSubscription{
+		ID:        sub.SubscriptionID,
+		URL:       sub.URL,
+		Secret:    sub.Secret,
+		Events:    events,
+		Owner:     sub.Owner,
+		Active:    sub.Active,
+		CreatedAt: sub.CreatedAt,
+	}
+}
+
+func deliveryToDomain(d *repository.Delivery) *domain.WebhookDelivery {
+	return &domain.WebhookDelivery{
+		ID:             d.DeliveryID,
+		SubscriptionID: d.SubscriptionID,
+		EventID:        d.EventID,
+		Event:          domain.EventType(d.Event),
+		Payload:        d.Payload,
+		Status:         domain.DeliveryStatus(d.Status),
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt,
+		DeliveredAt:    d.DeliveredAt,
+	}
+}
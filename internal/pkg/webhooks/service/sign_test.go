@@ -0,0 +1,20 @@
+package service
+
+import "testing"
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"type":"link.created"}`)
+
+	// echo -n '1700000000.{"type":"link.created"}' | openssl dgst -sha256 -hmac secret
+	want := "sha256=e1032797c4ade9efa21db4fc0269d850ce98f9fb57d0c8365e806096506b205e"
+	if got := Sign("secret", 1700000000, body); got != want {
+		t.Fatalf("Sign() = %s, want %s", got, want)
+	}
+
+	if !Verify("secret", 1700000000, body, want) {
+		t.Error("signature should be verified")
+	}
+	if Verify("other", 1700000000, body, want) || Verify("secret", 1700000001, body, want) {
+		t.Error("signature should depend on secret and timestamp")
+	}
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sshlykov/shortener/internal/domain"
+	"github.com/sshlykov/shortener/pkg/logger"
+)
+
+type subscribedEntry struct {
+	subscribed bool
+	checkedAt  time.Time
+}
+
+// subscribedCache помнит, есть ли активные подписки на событие, не дольше ttl
+type subscribedCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[domain.EventType]subscribedEntry
+}
+
+func newSubscribedCache(ttl time.Duration) *subscribedCache {
+	return &subscribedCache{ttl: ttl, entries: make(map[domain.EventType]subscribedEntry)}
+}
+
+func (c *subscribedCache) get(event domain.EventType) (bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[event]
+	if !ok || time.Since(entry.checkedAt) >= c.ttl {
+		return false, false
+	}
+
+	return entry.subscribed, true
+}
+
+func (c *subscribedCache) put(event domain.EventType, subscribed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[event] = subscribedEntry{subscribed: subscribed, checkedAt: time.Now()}
+}
+
+func (c *subscribedCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clear(c.entries)
+}
+
+// Subscribed сообщает, есть ли активная подписка на событие, чтобы частые события без подписчиков
+// не писались в outbox. Ответ кэшируется на cfg.SubscribedTTL, при ошибке базы считается, что подписчики есть
+func (s *Service) Subscribed(ctx context.Context, event domain.EventType) bool {
+	if subscribed, ok := s.subscribed.get(event); ok {
+		return subscribed
+	}
+
+	subscribed, err := s.repo.HasSubscribers(ctx, string(event))
+	if err != nil {
+		logger.Error(ctx, "Subscribed", logger.Err(err), logger.Any("event", event))
+
+		return true
+	}
+	s.subscribed.put(event, subscribed)
+
+	return subscribed
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sshlykov/shortener/internal/domain"
+	repository "github.com/sshlykov/shortener/internal/pkg/webhooks/repo"
+)
+
+type subscribersRepo struct {
+	Repository
+
+	subscribed bool
+	err        error
+	lookups    int
+}
+
+func (r *subscribersRepo) HasSubscribers(context.Context, string) (bool, error) {
+	r.lookups++
+	return r.subscribed, r.err
+}
+
+func (r *subscribersRepo) CreateSubscription(_ context.Context,
+	sub *repository.Subscription) (*repository.Subscription, error) {
+	r.subscribed = true
+	return sub, nil
+}
+
+func TestSubscribed(t *testing.T) {
+	repo := &subscribersRepo{}
+	s := &Service{repo: repo, subscribed: newSubscribedCache(time.Minute)}
+	ctx := context.Background()
+
+	if s.Subscribed(ctx, domain.EventLinkClicked) || s.Subscribed(ctx, domain.EventLinkClicked) {
+		t.Fatal("Subscribed() = true without subscriptions")
+	}
+	if repo.lookups != 1 {
+		t.Errorf("lookups = %d, want the answer cached", repo.lookups)
+	}
+
+	// новая подписка на этой реплике видна сразу
+	_, err := s.CreateSubscription(ctx, domain.WebhookSubscription{
+		URL: "https://example.com/hook", Secret: "secret", Events: []domain.EventType{domain.EventLinkClicked},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Subscribed(ctx, domain.EventLinkClicked) || repo.lookups != 2 {
+		t.Errorf("after subscription lookups = %d, want a fresh lookup", repo.lookups)
+	}
+}
+
+func TestSubscribedExpires(t *testing.T) {
+	repo := &subscribersRepo{}
+	s := &Service{repo: repo, subscribed: newSubscribedCache(0)}
+
+	s.Subscribed(context.Background(), domain.EventLinkClicked)
+	s.Subscribed(context.Background(), domain.EventLinkClicked)
+	if repo.lookups != 2 {
+		t.Errorf("lookups = %d, want every call to check with ttl 0", repo.lookups)
+	}
+}
+
+func TestSubscribedDatabaseDown(t *testing.T) {
+	repo := &subscribersRepo{err: errors.New("connection refused")}
+	s := &Service{repo: repo, subscribed: newSubscribedCache(time.Minute)}
+
+	if !s.Subscribed(context.Background(), domain.EventLinkClicked) {
+		t.Error("Subscribed() = false on error, want events kept")
+	}
+	repo.err = nil
+	s.Subscribed(context.Background(), domain.EventLinkClicked)
+	if repo.lookups != 2 {
+		t.Errorf("lookups = %d, want errors not cached", repo.lookups)
+	}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+
+	"github.com/sshlykov/shortener/internal/domain"
+	repository "github.com/sshlykov/shortener/internal/pkg/webhooks/repo"
+	"github.com/sshlykov/shortener/pkg/logger"
+)
+
+const secretLen = 32
+
+// CreateSubscription создает подписку, если секрет не задан - генерирует его
+func (s *Service) CreateSubscription(ctx context.Context,
+	sub domain.WebhookSubscription) (*domain.WebhookSubscription, error) {
+	parsed, err := url.Parse(sub.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, ErrInvalidURL
+	}
+
+	if len(sub.Events) == 0 {
+		return nil, ErrInvalidEvents
+	}
+	events := make([]string, 0, len(sub.Events))
+	for _, event := range sub.Events {
+		if !event.Valid() {
+			return nil, ErrInvalidEvents
+		}
+		events = append(events, string(event))
+	}
+
+	if sub.Secret == "" {
+		secret := make([]byte, secretLen)
+		if _, err = rand.Read(secret); err != nil {
+			logger.Error(ctx, "CreateSubscription", logger.Err(err))
+
+			return nil, ErrCantCreateSubscription
+		}
+		sub.Secret = hex.EncodeToString(secret)
+	}
+
+	created, err := s.repo.CreateSubscription(ctx, &repository.Subscription{
+		URL:    sub.URL,
+		Secret: sub.Secret,
+		Events: events,
+		Owner:  sub.Owner,
+	})
+	if err != nil {
+		logger.Error(ctx, "CreateSubscription", logger.Err(err))
+
+		return nil, ErrCantCreateSubscription
+	}
+	s.subscribed.reset()
+
+	return subscriptionToDomain(created), nil
+}
+
+func (s *Service) ListSubscriptions(ctx context.Context, owner string) ([]*domain.WebhookSubscription, error) {
+	rows, err := s.repo.ListSubscriptions(ctx, owner)
+	if err != nil {
+		logger.Error(ctx, "ListSubscriptions", logger.Err(err))
+
+		return nil, ErrCantListSubscriptions
+	}
+
+	subs := make([]*domain.WebhookSubscription, 0, len(rows))
+	for i := range rows {
+		subs = append(subs, subscriptionToDomain(&rows[i]))
+	}
+
+	return subs, nil
+}
+
+func (s *Service) DeleteSubscription(ctx context.Context, id int64) error {
+	deleted, err := s.repo.DeleteSubscription(ctx, id)
+	if err != nil {
+		logger.Error(ctx, "DeleteSubscription", logger.Err(err), logger.Any("id", id))
+
+		return ErrCantDeleteSubscription
+	}
+	if !deleted {
+		return ErrSubscriptionNotFound
+	}
+	s.subscribed.reset()
+
+	return nil
+}
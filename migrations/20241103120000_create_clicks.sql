@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE clicks
+(
+    click_id   bigserial,
+    link_id    integer     NOT NULL REFERENCES links (link_id) ON DELETE CASCADE,
+    clicked_at timestamptz NOT NULL DEFAULT now(),
+    referer    text,
+    user_agent text,
+    ip         inet,
+    PRIMARY KEY (click_id, clicked_at)
+) PARTITION BY RANGE (clicked_at);
+
+CREATE INDEX clicks_link_id_clicked_at_idx ON clicks (link_id, clicked_at);
+
+-- Партиции по месяцам создает сервис partitions, default ловит клики вне созданных диапазонов
+CREATE TABLE clicks_default PARTITION OF clicks DEFAULT;
+
+-- Текущий месяц и 3 вперед (partitions.premake по умолчанию) создаются сразу, чтобы клики до первого
+-- обслуживания не копились в default
+DO
+$$
+    DECLARE
+        first_month timestamp := date_trunc('month', now() AT TIME ZONE 'UTC');
+        month       timestamp;
+    BEGIN
+        FOR i IN 0..3
+            LOOP
+                month := first_month + make_interval(months => i);
+                EXECUTE format('CREATE TABLE %I PARTITION OF clicks FOR VALUES FROM (%L) TO (%L)',
+                               'clicks_p' || to_char(month, 'YYYY_MM'),
+                               month AT TIME ZONE 'UTC', (month + interval '1 month') AT TIME ZONE 'UTC');
+            END LOOP;
+    END
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE clicks;
+-- +goose StatementEnd
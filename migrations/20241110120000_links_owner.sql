@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE links
+    ADD COLUMN owner      text        NOT NULL DEFAULT '',
+    ADD COLUMN created_at timestamptz NOT NULL DEFAULT now();
+
+CREATE UNIQUE INDEX links_key_uidx ON links (key);
+CREATE INDEX links_owner_idx ON links (owner);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX links_owner_idx;
+DROP INDEX links_key_uidx;
+
+ALTER TABLE links
+    DROP COLUMN created_at,
+    DROP COLUMN owner;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE links
+    ADD COLUMN updated_at timestamptz NOT NULL DEFAULT now(),
+    ADD COLUMN expires_at timestamptz,
+    ADD COLUMN expired_at timestamptz;
+
+CREATE INDEX links_expires_at_idx ON links (expires_at) WHERE expires_at IS NOT NULL AND expired_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX links_expires_at_idx;
+
+ALTER TABLE links
+    DROP COLUMN expired_at,
+    DROP COLUMN expires_at,
+    DROP COLUMN updated_at;
+-- +goose StatementEnd
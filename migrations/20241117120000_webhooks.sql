@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE webhook_subscriptions
+(
+    subscription_id bigserial PRIMARY KEY,
+    url             text        NOT NULL,
+    secret          text        NOT NULL,
+    events          text[]      NOT NULL,
+    owner           text        NOT NULL DEFAULT '',
+    active          boolean     NOT NULL DEFAULT true,
+    created_at      timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE webhook_deliveries
+(
+    delivery_id      bigserial PRIMARY KEY,
+    subscription_id  bigint      NOT NULL REFERENCES webhook_subscriptions (subscription_id) ON DELETE CASCADE,
+    event_id         text        NOT NULL,
+    event            text        NOT NULL,
+    payload          jsonb       NOT NULL,
+    status           text        NOT NULL DEFAULT 'pending',
+    attempts         integer     NOT NULL DEFAULT 0,
+    next_attempt_at  timestamptz NOT NULL DEFAULT now(),
+    last_status_code integer,
+    last_error       text,
+    created_at       timestamptz NOT NULL DEFAULT now(),
+    delivered_at     timestamptz
+);
+
+CREATE INDEX webhook_deliveries_pending_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX webhook_deliveries_subscription_idx ON webhook_deliveries (subscription_id, delivery_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE webhook_deliveries;
+DROP TABLE webhook_subscriptions;
+-- +goose StatementEnd
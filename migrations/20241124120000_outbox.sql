@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE outbox
+(
+    outbox_id    bigserial PRIMARY KEY,
+    event_id     text        NOT NULL,
+    event_type   text        NOT NULL,
+    owner        text        NOT NULL DEFAULT '',
+    payload      jsonb       NOT NULL,
+    attempts     integer     NOT NULL DEFAULT 0,
+    available_at timestamptz NOT NULL DEFAULT now(),
+    last_error   text,
+    created_at   timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX outbox_available_at_idx ON outbox (available_at, outbox_id);
+
+-- relay доставляет события как минимум один раз, повторная постановка доставки игнорируется
+CREATE UNIQUE INDEX webhook_deliveries_event_uidx ON webhook_deliveries (subscription_id, event_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX webhook_deliveries_event_uidx;
+DROP TABLE outbox;
+-- +goose StatementEnd
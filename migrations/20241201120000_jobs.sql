@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE jobs
+(
+    job_id       bigserial PRIMARY KEY,
+    kind         text        NOT NULL,
+    payload      jsonb       NOT NULL DEFAULT '{}',
+    priority     integer     NOT NULL DEFAULT 0,
+    unique_key   text,
+    status       text        NOT NULL DEFAULT 'pending',
+    attempts     integer     NOT NULL DEFAULT 0,
+    max_attempts integer     NOT NULL,
+    -- для pending - время запуска, для running - конец lease, после которого задачу заберет другой воркер
+    run_at       timestamptz NOT NULL DEFAULT now(),
+    last_error   text,
+    created_at   timestamptz NOT NULL DEFAULT now(),
+    updated_at   timestamptz NOT NULL DEFAULT now(),
+    finished_at  timestamptz
+);
+
+CREATE INDEX jobs_ready_idx ON jobs (priority DESC, run_at) WHERE status IN ('pending', 'running');
+CREATE INDEX jobs_finished_idx ON jobs (finished_at) WHERE status IN ('done', 'dead');
+
+-- одновременно может ждать или выполняться только одна задача с ключом
+CREATE UNIQUE INDEX jobs_unique_key_uidx ON jobs (kind, unique_key)
+    WHERE unique_key IS NOT NULL AND status IN ('pending', 'running');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE jobs;
+-- +goose StatementEnd
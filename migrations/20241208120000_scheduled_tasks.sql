@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE scheduled_tasks
+(
+    name              text PRIMARY KEY,
+    -- момент по расписанию, за который задача уже запускалась, защищает от повторного запуска другой репликой
+    last_scheduled_at timestamptz NOT NULL,
+    last_started_at   timestamptz NOT NULL,
+    last_finished_at  timestamptz,
+    last_duration_ms  bigint,
+    last_error        text,
+    last_instance     text        NOT NULL DEFAULT ''
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE scheduled_tasks;
+-- +goose StatementEnd
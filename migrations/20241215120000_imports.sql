@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+-- клики, перенесенные из другого сокращателя при импорте, сами клики не восстанавливаются
+ALTER TABLE links
+    ADD COLUMN imported_clicks bigint NOT NULL DEFAULT 0;
+
+CREATE TABLE imports
+(
+    import_id   bigserial PRIMARY KEY,
+    source      text        NOT NULL,
+    owner       text        NOT NULL DEFAULT '',
+    status      text        NOT NULL DEFAULT 'pending',
+    -- исходный файл, очищается после завершения импорта
+    data        bytea,
+    total       integer     NOT NULL DEFAULT 0,
+    -- сколько строк обработано, с этого места импорт продолжается после повтора задачи
+    processed   integer     NOT NULL DEFAULT 0,
+    created     integer     NOT NULL DEFAULT 0,
+    skipped     integer     NOT NULL DEFAULT 0,
+    failed      integer     NOT NULL DEFAULT 0,
+    errors      jsonb       NOT NULL DEFAULT '[]',
+    last_error  text,
+    created_at  timestamptz NOT NULL DEFAULT now(),
+    updated_at  timestamptz NOT NULL DEFAULT now(),
+    finished_at timestamptz
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE imports;
+
+ALTER TABLE links
+    DROP COLUMN imported_clicks;
+-- +goose StatementEnd
@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"embed"
+	"errors"
+	"sync"
+
+	"github.com/pressly/goose/v3"
+)
+
+// FS - SQL-миграции, вшитые в бинарник. Go-миграции регистрируются через init при импорте пакета
+//
+//go:embed *.sql
+var FS embed.FS
+
+// MinVersion - самая старая схема, с которой работает код. Повышается, когда код начинает
+// зависеть от новой миграции
+const MinVersion int64 = 20241215120000
+
+// NewProvider - goose провайдер вшитых миграций: SQL из FS вместе с зарегистрированными Go-миграциями
+func NewProvider(db *sql.DB) (*goose.Provider, error) {
+	return goose.NewProvider(goose.DialectPostgres, db, FS)
+}
+
+// LatestVersion - версия последней вшитой миграции, SQL или Go. Берется из того же списка источников,
+// что и у мигратора. Для списка база не нужна, поэтому провайдер создается без подключения
+var LatestVersion = sync.OnceValue(func() int64 {
+	provider, err := NewProvider(sql.OpenDB(noConnector{}))
+	if err != nil {
+		return 0
+	}
+
+	sources := provider.ListSources()
+	if len(sources) == 0 {
+		return 0
+	}
+
+	return sources[len(sources)-1].Version
+})
+
+var errNoConnection = errors.New("migrations: provider has no database connection")
+
+// noConnector - подключение-заглушка для провайдера, которому нужен только список миграций
+type noConnector struct{}
+
+func (noConnector) Connect(context.Context) (driver.Conn, error) { return nil, errNoConnection }
+func (noConnector) Driver() driver.Driver                        { return noConnector{} }
+func (noConnector) Open(string) (driver.Conn, error)             { return nil, errNoConnection }
@@ -197,6 +197,24 @@ func (b *ExponentialBackOff) NextBackOff() time.Duration {
 	return next
 }
 
+// Delay returns the randomized backoff before the retry that follows the given attempt
+// (1-based), with default multiplier and randomization and no elapsed time limit.
+// It is meant for persisted retries, where the attempt number is stored instead of a BackOff.
+func Delay(initial, max time.Duration, attempt int) time.Duration {
+	b := NewExponentialBackOff(
+		WithInitialInterval(initial),
+		WithMaxInterval(max),
+		WithMaxElapsedTime(0),
+	)
+
+	delay := b.NextBackOff()
+	for i := 1; i < attempt; i++ {
+		delay = b.NextBackOff()
+	}
+
+	return delay
+}
+
 // GetElapsedTime returns the elapsed time since an ExponentialBackOff instance
 // is created and is reset when Reset() is called.
 //
@@ -159,3 +159,13 @@ func TestNewExponentialBackOff(t *testing.T) {
 		t.Errorf("Expected Clock to be SystemClock, got %v", backOff.Clock)
 	}
 }
+
+func TestDelay(t *testing.T) {
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 1500 * time.Millisecond, 10: 10 * time.Second} {
+		got := Delay(time.Second, 10*time.Second, attempt)
+		delta := time.Duration(DefaultRandomizationFactor * float64(want))
+		if got < want-delta || got > want+delta+1 {
+			t.Errorf("Delay(attempt %d) = %s, want %s ± %s", attempt, got, want, delta)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+// Package cron разбирает cron-выражения из пяти полей (минута, час, день месяца, месяц, день недели)
+// и дескрипторы @hourly, @daily, @weekly, @monthly, @yearly и @every <duration>
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidExpression = errors.New("invalid cron expression")
+
+// Schedule вычисляет следующее срабатывание после t
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+type field struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minutes = field{min: 0, max: 59}
+	hours   = field{min: 0, max: 23}
+	days    = field{min: 1, max: 31}
+	months  = field{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	weekdays = field{min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse разбирает выражение. Если ограничены и день месяца, и день недели,
+// срабатывание происходит при совпадении любого из них, как в классическом cron
+func Parse(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if rest, ok := strings.CutPrefix(expr, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil || d < time.Second {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidExpression, expr)
+		}
+		return every{interval: d}, nil
+	}
+	if spec, ok := descriptors[expr]; ok {
+		expr = spec
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: %q: expected 5 fields", ErrInvalidExpression, expr)
+	}
+
+	s := &spec{}
+	var err error
+	if s.minute, err = parseField(fields[0], minutes); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[1], hours); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseField(fields[2], days); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[3], months); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseField(fields[4], weekdays); err != nil {
+		return nil, err
+	}
+	// 7 - тоже воскресенье
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domStar = fields[2] == "*" || strings.HasPrefix(fields[2], "*/")
+	s.dowStar = fields[4] == "*" || strings.HasPrefix(fields[4], "*/")
+
+	return s, nil
+}
+
+// MustParse - Parse для выражений, заданных в коде
+func MustParse(expr string) Schedule {
+	s, err := Parse(expr)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func parseField(expr string, f field) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expr, ",") {
+		b, err := parseRange(part, f)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidExpression, expr)
+		}
+		bits |= b
+	}
+
+	return bits, nil
+}
+
+func parseRange(expr string, f field) (uint64, error) {
+	rangePart, stepPart, hasStep := strings.Cut(expr, "/")
+	step := 1
+	if hasStep {
+		var err error
+		if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+			return 0, ErrInvalidExpression
+		}
+	}
+
+	lo, hi := f.min, f.max
+	if rangePart != "*" {
+		from, to, isRange := strings.Cut(rangePart, "-")
+		var err error
+		if lo, err = f.value(from); err != nil {
+			return 0, err
+		}
+		hi = lo
+		if isRange {
+			if hi, err = f.value(to); err != nil {
+				return 0, err
+			}
+		} else if hasStep {
+			hi = f.max
+		}
+	}
+	if lo > hi {
+		return 0, ErrInvalidExpression
+	}
+
+	var bits uint64
+	for v := lo; v <= hi; v += step {
+		bits |= 1 << v
+	}
+
+	return bits, nil
+}
+
+func (f field) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, ErrInvalidExpression
+	}
+
+	return v, nil
+}
+
+type spec struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+// за пять лет любое корректное выражение срабатывает хотя бы раз (29 февраля - раз в четыре года)
+const searchLimit = 5
+
+func (s *spec) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + searchLimit
+
+	for t.Year() <= limit {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (s *spec) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
+
+// every срабатывает с фиксированным интервалом, выровненным по началу эпохи,
+// чтобы все реплики получали одинаковые моменты срабатывания
+type every struct {
+	interval time.Duration
+}
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Truncate(e.interval).Add(e.interval)
+}
@@ -0,0 +1,49 @@
+package cron
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	base := time.Date(2024, time.December, 1, 10, 17, 30, 0, time.UTC) // воскресенье
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 12, 1, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 12, 1, 10, 30, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2024, 12, 1, 11, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 12, 1, 11, 0, 0, 0, time.UTC)},
+		{"30 3 * * *", time.Date(2024, 12, 2, 3, 30, 0, 0, time.UTC)},
+		{"0 9-17/4 * * mon-fri", time.Date(2024, 12, 2, 9, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC)},
+		{"5,10 10 * * *", time.Date(2024, 12, 2, 10, 5, 0, 0, time.UTC)},
+		// день месяца или день недели
+		{"0 0 15 * fri", time.Date(2024, 12, 6, 0, 0, 0, 0, time.UTC)},
+		{"@every 10m", time.Date(2024, 12, 1, 10, 20, 0, 0, time.UTC)},
+	}
+
+	for _, c := range cases {
+		s, err := Parse(c.expr)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", c.expr, err)
+			continue
+		}
+		if got := s.Next(base); !got.Equal(c.want) {
+			t.Errorf("%q: next = %v, want %v", c.expr, got, c.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *",
+		"* * * foo *", "@every 10ms", "@every soon"} {
+		if _, err := Parse(expr); !errors.Is(err, ErrInvalidExpression) {
+			t.Errorf("%q: expected ErrInvalidExpression, got %v", expr, err)
+		}
+	}
+}
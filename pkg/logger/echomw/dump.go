@@ -34,6 +34,11 @@ func (w *bodyWriter) Write(b []byte) (int, error) {
 	return w.ResponseWriter.Write(b)
 }
 
+// Unwrap returns the original http.ResponseWriter (used by http.ResponseController)
+func (w *bodyWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // implements http.Flusher
 func (w *bodyWriter) Flush() {
 	if f, ok := w.ResponseWriter.(http.Flusher); ok {
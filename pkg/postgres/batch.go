@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Batch - набор запросов, отправляемых за один round-trip
+type Batch struct {
+	Name string
+
+	batch pgx.Batch
+	items []batchItem
+}
+
+type batchItem struct {
+	name string
+	scan func(row pgx.Row) error
+}
+
+// BatchResult - результат запроса батча, индекс совпадает с порядком Queue
+type BatchResult struct {
+	Name string
+	Tag  pgconn.CommandTag
+	Err  error
+}
+
+func NewBatch(name string) *Batch {
+	return &Batch{Name: name}
+}
+
+// Queue добавляет запрос без результата
+func (b *Batch) Queue(q Query, args ...any) {
+	b.batch.Queue(q.Raw, args...)
+	b.items = append(b.items, batchItem{name: q.Name})
+}
+
+// QueueRow добавляет запрос, строка результата которого передается в scan
+func (b *Batch) QueueRow(q Query, scan func(row pgx.Row) error, args ...any) {
+	b.batch.Queue(q.Raw, args...)
+	b.items = append(b.items, batchItem{name: q.Name, scan: scan})
+}
+
+func (b *Batch) Len() int {
+	return len(b.items)
+}
+
+// Copy - описание COPY FROM STDIN
+type Copy struct {
+	Name    string
+	Table   pgx.Identifier
+	Columns []string
+}
+
+// CopyRows - типизированный источник строк для CopyFrom
+func CopyRows[T any](rows []T, values func(row T) ([]any, error)) pgx.CopyFromSource {
+	return pgx.CopyFromSlice(len(rows), func(i int) ([]any, error) {
+		return values(rows[i])
+	})
+}
+
+type bulkWriter interface {
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, table pgx.Identifier, columns []string, src pgx.CopyFromSource) (int64, error)
+}
+
+// writer - транзакция из контекста либо primary пул
+func (p *Postgres) writer(ctx context.Context) bulkWriter {
+	markWrite(ctx)
+
+	if tx, ok := ctx.Value(TxKey).(pgx.Tx); ok {
+		return tx
+	}
+
+	return p.Pool
+}
+
+// SendBatchContext выполняет батч и возвращает результат по каждому запросу.
+// Ошибка запроса попадает в его BatchResult, общая ошибка - ошибка закрытия батча
+func (p *Postgres) SendBatchContext(ctx context.Context, b *Batch) ([]BatchResult, error) {
+	if b.Len() == 0 {
+		return nil, nil
+	}
+	ctx = WithQueryName(ctx, b.Name)
+
+	br := p.writer(ctx).SendBatch(ctx, &b.batch)
+	results := make([]BatchResult, len(b.items))
+	for i, item := range b.items {
+		results[i].Name = item.name
+		if item.scan != nil {
+			results[i].Err = item.scan(br.QueryRow())
+			continue
+		}
+		results[i].Tag, results[i].Err = br.Exec()
+	}
+
+	if err := br.Close(); err != nil {
+		return results, err
+	}
+
+	return results, nil
+}
+
+func (p *Postgres) SendRawBatchContext(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return p.writer(ctx).SendBatch(ctx, b)
+}
+
+func (p *Postgres) CopyFromContext(ctx context.Context, c Copy, src pgx.CopyFromSource) (int64, error) {
+	ctx = WithQueryName(ctx, c.Name)
+
+	return p.writer(ctx).CopyFrom(ctx, c.Table, c.Columns, src)
+}
+
+// BatchErr возвращает первую ошибку из результатов батча
+func BatchErr(results []BatchResult) error {
+	for _, r := range results {
+		if r.Err != nil {
+			return r.Err
+		}
+	}
+
+	return nil
+}
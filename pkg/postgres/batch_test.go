@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type fakeBatchResults struct {
+	pgx.BatchResults
+
+	execs []error
+	n     int
+}
+
+func (r *fakeBatchResults) Exec() (pgconn.CommandTag, error) {
+	err := r.execs[r.n]
+	r.n++
+	return pgconn.NewCommandTag("INSERT 0 1"), err
+}
+
+func (r *fakeBatchResults) Close() error {
+	return nil
+}
+
+type fakeBatchTx struct {
+	pgx.Tx
+
+	results *fakeBatchResults
+	sent    int
+}
+
+func (t *fakeBatchTx) SendBatch(_ context.Context, b *pgx.Batch) pgx.BatchResults {
+	t.sent = b.Len()
+	return t.results
+}
+
+func TestSendBatchUsesTxAndMapsResults(t *testing.T) {
+	errDup := errors.New("duplicate")
+	tx := &fakeBatchTx{results: &fakeBatchResults{execs: []error{nil, errDup, nil}}}
+	ctx := context.WithValue(context.Background(), TxKey, pgx.Tx(tx))
+
+	b := NewBatch("links.bulk_insert")
+	for _, name := range []string{"a", "b", "c"} {
+		b.Queue(Query{Name: "links.insert_" + name, Raw: "INSERT INTO links(key) VALUES ($1)"}, name)
+	}
+
+	results, err := (&Postgres{}).SendBatchContext(ctx, b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tx.sent != 3 || len(results) != 3 {
+		t.Fatalf("expected 3 statements sent through tx, got %d sent and %d results", tx.sent, len(results))
+	}
+	if results[1].Name != "links.insert_b" || !errors.Is(results[1].Err, errDup) {
+		t.Errorf("second result should carry its error, got %+v", results[1])
+	}
+	if results[0].Err != nil || results[0].Tag.RowsAffected() != 1 {
+		t.Errorf("first result should succeed, got %+v", results[0])
+	}
+	if !errors.Is(BatchErr(results), errDup) {
+		t.Error("BatchErr should return the first statement error")
+	}
+}
+
+func TestCopyRows(t *testing.T) {
+	type row struct {
+		key string
+		url string
+	}
+	src := CopyRows([]row{{"a", "http://a"}, {"b", "http://b"}}, func(r row) ([]any, error) {
+		return []any{r.key, r.url}, nil
+	})
+
+	var keys []any
+	for src.Next() {
+		values, err := src.Values()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		keys = append(keys, values[0])
+	}
+	if len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Errorf("unexpected rows %v", keys)
+	}
+}
@@ -3,26 +3,72 @@ package postgres
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"net"
+	"strconv"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/sshlykov/shortener/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
-	_defaultMaxPoolSize  = 1
 	_defaultConnAttempts = 10
 	_defaultConnTimeout  = time.Second
 )
 
+// PoolConfig - настройки пула соединений, нулевые значения оставляют значения по умолчанию pgxpool
+// (либо заданные в dsn через pool_max_conns и т.п.)
+type PoolConfig struct {
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+	// StatementTimeout выставляется в statement_timeout каждого соединения
+	StatementTimeout time.Duration
+	ApplicationName  string
+	// Replicas - host или host:port реплик, остальные параметры подключения берутся из dsn
+	Replicas []string
+}
+
+// Option - дополнительная настройка клиента
+type Option func(*options)
+
+type options struct {
+	metrics        *Metrics
+	tracerProvider trace.TracerProvider
+	slowQuery      SlowQueryLog
+}
+
+// WithMetrics включает метрики запросов и пула
+func WithMetrics(metrics *Metrics) Option {
+	return func(o *options) {
+		o.metrics = metrics
+	}
+}
+
+// WithTracerProvider задает провайдер спанов, по умолчанию глобальный из otel
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracerProvider = tp
+	}
+}
+
 type pgClient struct {
-	maxPoolSize  int
 	connAttempts int
 	connTimeout  time.Duration
 
+	tracer  *queryTracer
+	metrics *Metrics
+
+	replicaConfigs []*pgxpool.Config
+
 	db DB
 }
 
@@ -30,39 +76,94 @@ type pgClient struct {
 // ctx - контекст; dsn - строка подключения к базе данных;
 // maxPoolSize - максимальное количество соединений в пуле.
 func NewPool(ctx context.Context, dsn string, maxPoolSize int) (Client, error) {
+	return NewClient(ctx, dsn, PoolConfig{MaxConns: int32(maxPoolSize)})
+}
+
+// NewClient создает клиента с пулом соединений, настроенным по cfg
+func NewClient(ctx context.Context, dsn string, cfg PoolConfig, opts ...Option) (Client, error) {
+	o := options{tracerProvider: otel.GetTracerProvider()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	client := &pgClient{
-		maxPoolSize:  maxPoolSize,
 		connAttempts: _defaultConnAttempts,
 		connTimeout:  _defaultConnTimeout,
+		tracer:       newQueryTracer(o),
+		metrics:      o.metrics,
 	}
 
 	poolConfig, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
-		slog.Error("Cant parse dsn", slog.String("dsn", dsn))
+		slog.Error("Cant parse dsn", slog.String("error", err.Error()))
 		return nil, err
 	}
 
-	poolConfig.MaxConns = int32(_defaultMaxPoolSize)
+	if err = applyPoolConfig(poolConfig, cfg); err != nil {
+		return nil, err
+	}
+	poolConfig.ConnConfig.Tracer = client.tracer
+
+	client.replicaConfigs, err = replicaConfigs(poolConfig, cfg.Replicas)
+	if err != nil {
+		return nil, err
+	}
 
 	return client.Connect(ctx, poolConfig)
 }
 
-func NewClient(ctx context.Context, dsn string) (Client, error) {
-	client := &pgClient{
-		maxPoolSize:  _defaultMaxPoolSize,
-		connAttempts: _defaultConnAttempts,
-		connTimeout:  _defaultConnTimeout,
+func replicaConfigs(primary *pgxpool.Config, hosts []string) ([]*pgxpool.Config, error) {
+	configs := make([]*pgxpool.Config, 0, len(hosts))
+	for _, hostport := range hosts {
+		host, port := hostport, primary.ConnConfig.Port
+		if h, p, err := net.SplitHostPort(hostport); err == nil {
+			parsed, err := strconv.ParseUint(p, 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("%w: replica %q: %w", ErrInvalidPoolConfig, hostport, err)
+			}
+			host, port = h, uint16(parsed)
+		}
+
+		cfg := primary.Copy()
+		cfg.ConnConfig.Host = host
+		cfg.ConnConfig.Port = port
+		cfg.ConnConfig.Fallbacks = nil
+		configs = append(configs, cfg)
 	}
 
-	poolConfig, err := pgxpool.ParseConfig(dsn)
-	if err != nil {
-		slog.Error("Cant parse dsn", slog.String("dsn", dsn))
-		return nil, err
+	return configs, nil
+}
+
+func applyPoolConfig(poolConfig *pgxpool.Config, cfg PoolConfig) error {
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = cfg.MaxConns
+	}
+	if cfg.MinConns > 0 {
+		poolConfig.MinConns = cfg.MinConns
+	}
+	if poolConfig.MinConns > poolConfig.MaxConns {
+		return fmt.Errorf("%w: min conns %d > max conns %d", ErrInvalidPoolConfig, poolConfig.MinConns,
+			poolConfig.MaxConns)
+	}
+	if cfg.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if cfg.HealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = cfg.HealthCheckPeriod
 	}
 
-	poolConfig.MaxConns = int32(_defaultMaxPoolSize)
+	params := poolConfig.ConnConfig.RuntimeParams
+	if cfg.StatementTimeout > 0 {
+		params["statement_timeout"] = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
+	}
+	if cfg.ApplicationName != "" {
+		params["application_name"] = cfg.ApplicationName
+	}
 
-	return client.Connect(ctx, poolConfig)
+	return nil
 }
 
 func (c *pgClient) Connect(ctx context.Context, poolConfig *pgxpool.Config) (Client, error) {
@@ -70,15 +171,16 @@ func (c *pgClient) Connect(ctx context.Context, poolConfig *pgxpool.Config) (Cli
 		ctx,
 		"connecting to db",
 		slog.Int("attempts", c.connAttempts),
-		slog.String("dsn", poolConfig.ConnString()),
-		slog.Int("maxPoolSize", _defaultMaxPoolSize),
+		slog.String("host", poolConfig.ConnConfig.Host),
+		slog.String("database", poolConfig.ConnConfig.Database),
+		slog.Int("maxPoolSize", int(poolConfig.MaxConns)),
+		slog.Int("minPoolSize", int(poolConfig.MinConns)),
 	)
 	for c.connAttempts > 0 {
 		var pool *pgxpool.Pool
 		pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 		if err == nil {
-			c.db = NewDB(pool)
-			return c, nil
+			return c.connectReplicas(ctx, pool)
 		}
 
 		time.Sleep(c.connTimeout)
@@ -89,21 +191,52 @@ func (c *pgClient) Connect(ctx context.Context, poolConfig *pgxpool.Config) (Cli
 	return nil, errors.New("failed to connect to db")
 }
 
+// connectReplicas создает пулы реплик, пулы ленивые, поэтому недоступная реплика не мешает старту
+func (c *pgClient) connectReplicas(ctx context.Context, primary *pgxpool.Pool) (Client, error) {
+	c.metrics.registerPool("primary", primary, c.tracer)
+
+	replicas := make([]*pgxpool.Pool, 0, len(c.replicaConfigs))
+	for _, cfg := range c.replicaConfigs {
+		pool, err := pgxpool.NewWithConfig(ctx, cfg)
+		if err != nil {
+			primary.Close()
+			for _, r := range replicas {
+				r.Close()
+			}
+			return nil, fmt.Errorf("failed to create replica pool %s: %w", cfg.ConnConfig.Host, err)
+		}
+		c.metrics.registerPool("replica_"+strconv.Itoa(len(replicas)), pool, c.tracer)
+		replicas = append(replicas, pool)
+	}
+
+	c.db = NewDB(primary, replicas...)
+	return c, nil
+}
+
 func (c *pgClient) Exec(ctx context.Context, query string, attrs ...interface{}) (pgconn.CommandTag, error) {
-	q := Query{Name: "exec", Raw: query}
+	q := Query{Name: queryName(query), Raw: query}
 	return c.db.ExecContext(ctx, q, attrs...)
 }
 
 func (c *pgClient) Query(ctx context.Context, query string, attrs ...interface{}) (pgx.Rows, error) {
-	q := Query{Name: "exec", Raw: query}
+	q := Query{Name: queryName(query), Raw: query}
 	return c.db.QueryRawContextMulti(ctx, q, attrs...)
 }
 
 func (c *pgClient) QueryRow(ctx context.Context, query string, attrs ...interface{}) pgx.Row {
-	q := Query{Name: "exec", Raw: query}
+	q := Query{Name: queryName(query), Raw: query}
 	return c.db.QueryRowContext(ctx, q, attrs...)
 }
 
+func (c *pgClient) SendBatch(ctx context.Context, batch *pgx.Batch) pgx.BatchResults {
+	return c.db.SendRawBatchContext(ctx, batch)
+}
+
+func (c *pgClient) CopyFrom(ctx context.Context, table pgx.Identifier, columns []string,
+	src pgx.CopyFromSource) (int64, error) {
+	return c.db.CopyFromContext(ctx, Copy{Table: table, Columns: columns}, src)
+}
+
 func (c *pgClient) DB() DB {
 	return c.db
 }
@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	CodeUniqueViolation      = "23505"
+	CodeSerializationFailure = "40001"
+	CodeDeadlockDetected     = "40P01"
+)
+
+var (
+	ErrInvalidPoolConfig   = errors.New("invalid pool config")
+	ErrUnknownArgsPolicy   = errors.New("unknown args policy")
+	ErrTxIsolationMismatch = errors.New("nested transaction requires stricter isolation level than outer one")
+)
+
+// ErrorCode возвращает SQLSTATE ошибки postgres или пустую строку
+func ErrorCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+
+	return ""
+}
+
+func IsUniqueViolation(err error) bool {
+	return ErrorCode(err) == CodeUniqueViolation
+}
+
+// IsRetryable - транзакцию с такой ошибкой можно безопасно повторить целиком
+func IsRetryable(err error) bool {
+	code := ErrorCode(err)
+	return code == CodeSerializationFailure || code == CodeDeadlockDetected
+}
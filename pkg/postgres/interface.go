@@ -19,22 +19,29 @@ type DB interface {
 	SQLScanner
 	Transactor
 	PingRunner
+	ReplicaRouter
 	Close()
 }
 
 type TxManager interface {
 	ReadCommitted(ctx context.Context, handler Handler) error
+	RepeatableRead(ctx context.Context, handler Handler) error
+	Serializable(ctx context.Context, handler Handler) error
 }
 
 type SQLCDB interface {
 	Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error)
 	Query(context.Context, string, ...interface{}) (pgx.Rows, error)
 	QueryRow(context.Context, string, ...interface{}) pgx.Row
+	SendBatch(context.Context, *pgx.Batch) pgx.BatchResults
+	CopyFrom(context.Context, pgx.Identifier, []string, pgx.CopyFromSource) (int64, error)
 }
 
 type Query struct {
 	Name string
 	Raw  string
+	// ReadOnly - запрос только читает и вне транзакции может уйти на реплику
+	ReadOnly bool
 }
 
 type Transactor interface {
@@ -56,8 +63,16 @@ type QueryScanner interface {
 	QueryContext(ctx context.Context, query Query, args ...interface{}) (pgx.Rows, error)
 	QueryRowContext(ctx context.Context, query Query, args ...interface{}) pgx.Row
 	QueryRawContextMulti(ctx context.Context, query Query, args ...interface{}) (pgx.Rows, error)
+	SendBatchContext(ctx context.Context, batch *Batch) ([]BatchResult, error)
+	SendRawBatchContext(ctx context.Context, batch *pgx.Batch) pgx.BatchResults
+	CopyFromContext(ctx context.Context, c Copy, src pgx.CopyFromSource) (int64, error)
 }
 
 type PingRunner interface {
 	Ping(ctx context.Context) error
 }
+
+type ReplicaRouter interface {
+	CheckReplicas(ctx context.Context)
+	Replicas() []ReplicaStatus
+}
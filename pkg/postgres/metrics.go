@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics - метрики запросов и пула соединений, nil отключает сбор
+type Metrics struct {
+	queryDuration *prometheus.HistogramVec
+	queryErrors   *prometheus.CounterVec
+	reg           prometheus.Registerer
+}
+
+// NewMetrics регистрирует метрики запросов в reg
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		queryDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "db_query_duration_seconds",
+				Help:    "Database query duration in seconds by query name",
+				Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+			},
+			[]string{"query"},
+		),
+		queryErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "db_query_errors_total",
+				Help: "Total number of failed database queries by query name",
+			},
+			[]string{"query"},
+		),
+		reg: reg,
+	}
+
+	reg.MustRegister(m.queryDuration, m.queryErrors)
+
+	return m
+}
+
+func (m *Metrics) observeQuery(name string, d time.Duration, err error) {
+	if m == nil {
+		return
+	}
+
+	m.queryDuration.WithLabelValues(name).Observe(d.Seconds())
+	if err != nil {
+		m.queryErrors.WithLabelValues(name).Inc()
+	}
+}
+
+func (m *Metrics) registerPool(name string, pool *pgxpool.Pool, tracer *queryTracer) {
+	if m == nil {
+		return
+	}
+
+	m.reg.MustRegister(newPoolCollector(name, pool, tracer))
+}
+
+// poolCollector снимает pgxpool.Stat в момент scrape
+type poolCollector struct {
+	pool   *pgxpool.Pool
+	tracer *queryTracer
+
+	acquired *prometheus.Desc
+	idle     *prometheus.Desc
+	total    *prometheus.Desc
+	waiting  *prometheus.Desc
+}
+
+func newPoolCollector(name string, pool *pgxpool.Pool, tracer *queryTracer) *poolCollector {
+	labels := prometheus.Labels{"pool": name}
+
+	return &poolCollector{
+		pool:   pool,
+		tracer: tracer,
+		acquired: prometheus.NewDesc("db_pool_acquired_conns",
+			"Number of currently acquired connections in the pool", nil, labels),
+		idle: prometheus.NewDesc("db_pool_idle_conns",
+			"Number of currently idle connections in the pool", nil, labels),
+		total: prometheus.NewDesc("db_pool_total_conns",
+			"Total number of connections in the pool", nil, labels),
+		waiting: prometheus.NewDesc("db_pool_waiting",
+			"Number of acquires currently waiting for a connection", nil, labels),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquired
+	ch <- c.idle
+	ch <- c.total
+	ch <- c.waiting
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(c.acquired, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.waiting, prometheus.GaugeValue, float64(c.tracer.waitingFor(c.pool).Load()))
+}
@@ -2,13 +2,12 @@ package postgres
 
 import (
 	"context"
-	"log/slog"
+	"sync/atomic"
 
 	"github.com/georgysavva/scany/v2/pgxscan"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/sshlykov/shortener/pkg/logger"
 )
 
 type key string
@@ -19,15 +18,22 @@ const (
 
 type Postgres struct {
 	Pool *pgxpool.Pool
+
+	replicas []*replica
+	next     atomic.Uint64
 }
 
-func NewDB(dbc *pgxpool.Pool) DB {
-	return &Postgres{Pool: dbc}
+// NewDB создает DB поверх primary пула, ReadOnly запросы распределяются по replicas
+func NewDB(dbc *pgxpool.Pool, replicas ...*pgxpool.Pool) DB {
+	p := &Postgres{Pool: dbc}
+	for _, pool := range replicas {
+		p.replicas = append(p.replicas, newReplica(pool))
+	}
+
+	return p
 }
 
 func (p *Postgres) ScanSingleContext(ctx context.Context, q Query, dest interface{}, args ...interface{}) error { //nolint:gofmt
-	logQuery(ctx, q, args...)
-
 	row, err := p.QueryContext(ctx, q, args...)
 	if err != nil {
 		return err
@@ -37,8 +43,6 @@ func (p *Postgres) ScanSingleContext(ctx context.Context, q Query, dest interfac
 }
 
 func (p *Postgres) ScanAllContext(ctx context.Context, q Query, dest interface{}, args ...interface{}) error { //nolint:gofmt
-	logQuery(ctx, q, args...)
-
 	rows, err := p.QueryContext(ctx, q, args...)
 	if err != nil {
 		return err
@@ -48,7 +52,9 @@ func (p *Postgres) ScanAllContext(ctx context.Context, q Query, dest interface{}
 }
 
 func (p *Postgres) ExecContext(ctx context.Context, q Query, args ...interface{}) (pgconn.CommandTag, error) { //nolint:gofmt
-	logQuery(ctx, q, args...)
+	ctx = WithQueryName(ctx, q.Name)
+
+	markWrite(ctx)
 
 	tx, ok := ctx.Value(TxKey).(pgx.Tx)
 	if ok {
@@ -59,38 +65,50 @@ func (p *Postgres) ExecContext(ctx context.Context, q Query, args ...interface{}
 }
 
 func (p *Postgres) QueryContext(ctx context.Context, q Query, args ...interface{}) (pgx.Rows, error) {
-	logQuery(ctx, q, args...)
+	ctx = WithQueryName(ctx, q.Name)
 
 	tx, ok := ctx.Value(TxKey).(pgx.Tx)
 	if ok {
 		return tx.Query(ctx, q.Raw, args...)
 	}
 
-	return p.Pool.Query(ctx, q.Raw, args...)
+	return p.readPool(ctx, q).Query(ctx, q.Raw, args...)
 }
 
 func (p *Postgres) QueryRowContext(ctx context.Context, q Query, args ...interface{}) pgx.Row {
-	logQuery(ctx, q, args...)
+	ctx = WithQueryName(ctx, q.Name)
+
 	tx, ok := ctx.Value(TxKey).(pgx.Tx)
 	if ok {
 		return tx.QueryRow(ctx, q.Raw, args...)
 	}
 
-	res := p.Pool.QueryRow(ctx, q.Raw, args...)
+	res := p.readPool(ctx, q).QueryRow(ctx, q.Raw, args...)
 
 	return res
 }
 
 func (p *Postgres) QueryRawContextMulti(ctx context.Context, q Query, args ...interface{}) (pgx.Rows, error) {
+	ctx = WithQueryName(ctx, q.Name)
+
 	tx, ok := ctx.Value(TxKey).(pgx.Tx)
 	if ok {
 		return tx.Query(ctx, q.Raw, args...)
 	}
 
-	return p.Pool.Query(ctx, q.Raw, args...)
+	return p.readPool(ctx, q).Query(ctx, q.Raw, args...)
 }
 
+// BeginTx открывает транзакцию в primary, read only транзакция уходит на реплику, если она есть
 func (p *Postgres) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	if txOptions.AccessMode == pgx.ReadOnly {
+		if pool := p.replicaPool(ctx); pool != nil {
+			return pool.BeginTx(ctx, txOptions)
+		}
+		return p.Pool.BeginTx(ctx, txOptions)
+	}
+
+	markWrite(ctx)
 	return p.Pool.BeginTx(ctx, txOptions)
 }
 
@@ -102,14 +120,7 @@ func (p *Postgres) Close() {
 	if p.Pool != nil {
 		p.Pool.Close()
 	}
-}
-
-func logQuery(ctx context.Context, q Query, args ...interface{}) {
-	attrs := []any{
-		slog.String("sql", q.Name),
-		slog.String("query", q.Raw),
-		slog.Any("args", args),
+	for _, r := range p.replicas {
+		r.pool.Close()
 	}
-
-	logger.Debug(ctx, "executing query", attrs...)
 }
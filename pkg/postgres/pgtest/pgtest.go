@@ -0,0 +1,17 @@
+// Package pgtest - заглушки pkg/postgres для тестов сервисов
+package pgtest
+
+import (
+	"context"
+
+	"github.com/sshlykov/shortener/pkg/postgres"
+)
+
+// PassTx - TxManager без базы: обработчик вызывается сразу с тем же контекстом
+type PassTx struct{}
+
+var _ postgres.TxManager = PassTx{}
+
+func (PassTx) ReadCommitted(ctx context.Context, h postgres.Handler) error  { return h(ctx) }
+func (PassTx) RepeatableRead(ctx context.Context, h postgres.Handler) error { return h(ctx) }
+func (PassTx) Serializable(ctx context.Context, h postgres.Handler) error   { return h(ctx) }
@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sshlykov/shortener/pkg/logger"
+)
+
+const _replicaPingTimeout = 2 * time.Second
+
+type primaryPinKey struct{}
+
+type primaryPin struct {
+	pinned atomic.Bool
+}
+
+// WithReadYourWrites - после первой записи через этот контекст все чтения идут в primary
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryPinKey{}, &primaryPin{})
+}
+
+// PinPrimary - все чтения через контекст сразу идут в primary
+func PinPrimary(ctx context.Context) context.Context {
+	pin := &primaryPin{}
+	pin.pinned.Store(true)
+	return context.WithValue(ctx, primaryPinKey{}, pin)
+}
+
+type readOnlyTxKey struct{}
+
+// ReadOnlyTx - транзакции, открытые TxManager через этот контекст, только читают и уходят на реплику,
+// если она есть. Долгие транзакции на реплике может прервать конфликт с восстановлением (max_standby_streaming_delay)
+func ReadOnlyTx(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readOnlyTxKey{}, true)
+}
+
+func readOnlyTx(ctx context.Context) bool {
+	readOnly, _ := ctx.Value(readOnlyTxKey{}).(bool)
+	return readOnly
+}
+
+func markWrite(ctx context.Context) {
+	if pin, ok := ctx.Value(primaryPinKey{}).(*primaryPin); ok {
+		pin.pinned.Store(true)
+	}
+}
+
+func pinnedToPrimary(ctx context.Context) bool {
+	pin, ok := ctx.Value(primaryPinKey{}).(*primaryPin)
+	return ok && pin.pinned.Load()
+}
+
+// ReplicaStatus - состояние реплики для /status
+type ReplicaStatus struct {
+	Name      string `json:"name"`
+	Healthy   bool   `json:"healthy"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+type replica struct {
+	name    string
+	pool    *pgxpool.Pool
+	healthy atomic.Bool
+	lastErr atomic.Pointer[string]
+}
+
+func newReplica(pool *pgxpool.Pool) *replica {
+	cfg := pool.Config().ConnConfig
+	r := &replica{
+		name: net.JoinHostPort(cfg.Host, strconv.Itoa(int(cfg.Port))),
+		pool: pool,
+	}
+	r.healthy.Store(true)
+
+	return r
+}
+
+// replicaPool выбирает реплику для чтения, при отсутствии живых реплик возвращает nil
+func (p *Postgres) replicaPool(ctx context.Context) *pgxpool.Pool {
+	if len(p.replicas) == 0 || pinnedToPrimary(ctx) {
+		return nil
+	}
+
+	start := p.next.Add(1)
+	for i := range uint64(len(p.replicas)) {
+		r := p.replicas[(start+i)%uint64(len(p.replicas))]
+		if r.healthy.Load() {
+			return r.pool
+		}
+	}
+
+	return nil
+}
+
+// readPool - пул для запроса вне транзакции: ReadOnly запросы уходят на реплики, остальные в primary
+func (p *Postgres) readPool(ctx context.Context, q Query) *pgxpool.Pool {
+	if q.ReadOnly {
+		if pool := p.replicaPool(ctx); pool != nil {
+			return pool
+		}
+		return p.Pool
+	}
+
+	if operation(q.Raw) != "SELECT" {
+		markWrite(ctx)
+	}
+
+	return p.Pool
+}
+
+// CheckReplicas пингует реплики и выводит недоступные из ротации до следующей успешной проверки
+func (p *Postgres) CheckReplicas(ctx context.Context) {
+	for _, r := range p.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, _replicaPingTimeout)
+		err := r.pool.Ping(pingCtx)
+		cancel()
+
+		if err != nil {
+			msg := err.Error()
+			r.lastErr.Store(&msg)
+			if r.healthy.Swap(false) {
+				logger.Warn(ctx, "replica removed from rotation", logger.Any("replica", r.name), logger.Err(err))
+			}
+			continue
+		}
+
+		r.lastErr.Store(nil)
+		if !r.healthy.Swap(true) {
+			logger.Info(ctx, "replica returned to rotation", logger.Any("replica", r.name))
+		}
+	}
+}
+
+func (p *Postgres) Replicas() []ReplicaStatus {
+	statuses := make([]ReplicaStatus, 0, len(p.replicas))
+	for _, r := range p.replicas {
+		status := ReplicaStatus{Name: r.name, Healthy: r.healthy.Load()}
+		if msg := r.lastErr.Load(); msg != nil {
+			status.LastError = *msg
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func newLazyPool(t *testing.T, host string) *pgxpool.Pool {
+	t.Helper()
+
+	cfg, err := pgxpool.ParseConfig("host=" + host + " user=test dbname=test")
+	if err != nil {
+		t.Fatalf("parse config: %v", err)
+	}
+	pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("new pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	return pool
+}
+
+func TestReadPoolRouting(t *testing.T) {
+	primary, r1, r2 := newLazyPool(t, "primary"), newLazyPool(t, "r1"), newLazyPool(t, "r2")
+	db := NewDB(primary, r1, r2).(*Postgres)
+	read := Query{Raw: "SELECT 1", ReadOnly: true}
+
+	seen := map[*pgxpool.Pool]int{}
+	for range 4 {
+		seen[db.readPool(context.Background(), read)]++
+	}
+	if seen[r1] != 2 || seen[r2] != 2 {
+		t.Fatalf("expected round robin over replicas, got %v", seen)
+	}
+
+	if db.readPool(context.Background(), Query{Raw: "SELECT 1"}) != primary {
+		t.Error("non read-only query should go to primary")
+	}
+
+	db.replicas[0].healthy.Store(false)
+	for range 3 {
+		if db.readPool(context.Background(), read) != r2 {
+			t.Fatal("unhealthy replica should be out of rotation")
+		}
+	}
+	db.replicas[1].healthy.Store(false)
+	if db.readPool(context.Background(), read) != primary {
+		t.Error("without healthy replicas reads should fall back to primary")
+	}
+}
+
+func TestReadYourWrites(t *testing.T) {
+	primary, r1 := newLazyPool(t, "primary"), newLazyPool(t, "r1")
+	db := NewDB(primary, r1).(*Postgres)
+	read := Query{Raw: "SELECT 1", ReadOnly: true}
+
+	ctx := WithReadYourWrites(context.Background())
+	if db.readPool(ctx, read) != r1 {
+		t.Fatal("reads before a write should go to replica")
+	}
+	db.readPool(ctx, Query{Raw: "INSERT INTO links(key) VALUES ($1) RETURNING id"})
+	if db.readPool(ctx, read) != primary {
+		t.Error("reads after a write should be pinned to primary")
+	}
+
+	if db.readPool(PinPrimary(context.Background()), read) != primary {
+		t.Error("pinned context should read from primary")
+	}
+}
@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+	"unicode/utf8"
+)
+
+// ArgsPolicy - как аргументы медленного запроса попадают в лог
+type ArgsPolicy string
+
+const (
+	// ArgsRedact оставляет только типы аргументов
+	ArgsRedact ArgsPolicy = "redact"
+	// ArgsTruncate пишет значения, обрезая их до MaxArgLen
+	ArgsTruncate ArgsPolicy = "truncate"
+	// ArgsOmit не пишет аргументы совсем
+	ArgsOmit ArgsPolicy = "omit"
+)
+
+const _defaultMaxArgLen = 64
+
+func ParseArgsPolicy(s string) (ArgsPolicy, error) {
+	switch p := ArgsPolicy(s); p {
+	case ArgsRedact, ArgsTruncate, ArgsOmit:
+		return p, nil
+	case "":
+		return ArgsRedact, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownArgsPolicy, s)
+	}
+}
+
+// SlowQueryLog - настройки лога медленных запросов, нулевой Threshold выключает лог
+type SlowQueryLog struct {
+	Threshold time.Duration
+	Args      ArgsPolicy
+	MaxArgLen int
+}
+
+// WithSlowQueryLog включает warn-лог запросов дольше cfg.Threshold
+func WithSlowQueryLog(cfg SlowQueryLog) Option {
+	return func(o *options) {
+		o.slowQuery = cfg
+	}
+}
+
+func (s SlowQueryLog) enabled() bool {
+	return s.Threshold > 0
+}
+
+func (s SlowQueryLog) args(args []any) slog.Attr {
+	out := make([]string, len(args))
+	for i, arg := range args {
+		out[i] = s.arg(arg)
+	}
+
+	return slog.Any("args", out)
+}
+
+func (s SlowQueryLog) arg(arg any) string {
+	if arg == nil {
+		return "<nil>"
+	}
+	if s.Args != ArgsTruncate {
+		return fmt.Sprintf("<%T>", arg)
+	}
+
+	var v string
+	switch a := arg.(type) {
+	case []byte:
+		return fmt.Sprintf("<%d bytes>", len(a))
+	case string:
+		v = a
+	default:
+		v = fmt.Sprint(a)
+	}
+
+	return truncate(v, s.maxArgLen())
+}
+
+func (s SlowQueryLog) maxArgLen() int {
+	if s.MaxArgLen > 0 {
+		return s.MaxArgLen
+	}
+	return _defaultMaxArgLen
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+
+	runes := []rune(s)
+	return string(runes[:n]) + "…"
+}
@@ -0,0 +1,27 @@
+package postgres
+
+import "testing"
+
+func TestSlowQueryArgs(t *testing.T) {
+	args := []any{"secret-token-value", 42, nil, []byte("raw")}
+
+	redacted := SlowQueryLog{Args: ArgsRedact}.args(args).Value.Any().([]string)
+	want := []string{"<string>", "<int>", "<nil>", "<[]uint8>"}
+	for i := range want {
+		if redacted[i] != want[i] {
+			t.Errorf("redact arg %d = %q, want %q", i, redacted[i], want[i])
+		}
+	}
+
+	truncated := SlowQueryLog{Args: ArgsTruncate, MaxArgLen: 6}.args(args).Value.Any().([]string)
+	want = []string{"secret…", "42", "<nil>", "<3 bytes>"}
+	for i := range want {
+		if truncated[i] != want[i] {
+			t.Errorf("truncate arg %d = %q, want %q", i, truncated[i], want[i])
+		}
+	}
+
+	if _, err := ParseArgsPolicy("full"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
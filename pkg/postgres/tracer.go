@@ -0,0 +1,221 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sshlykov/shortener/pkg/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	_tracerName = "github.com/sshlykov/shortener/pkg/postgres"
+	_sqlcPrefix = "-- name:"
+)
+
+type queryNameKey struct{}
+
+type queryTraceKey struct{}
+
+type queryTrace struct {
+	name      string
+	operation string
+	sql       string
+	args      []any
+	start     time.Time
+}
+
+// WithQueryName кладет в контекст имя запроса, под которым он попадет в метрики и трейсы
+func WithQueryName(ctx context.Context, name string) context.Context {
+	if name == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, queryNameKey{}, name)
+}
+
+// queryTracer - хук pgx, снимающий метрики, спаны и лог медленных запросов со всех запросов пула,
+// включая запросы внутри транзакций
+type queryTracer struct {
+	tracer    trace.Tracer
+	metrics   *Metrics
+	slowQuery SlowQueryLog
+	// waiting - *atomic.Int64 по каждому пулу
+	waiting sync.Map
+}
+
+func newQueryTracer(o options) *queryTracer {
+	return &queryTracer{
+		tracer:    o.tracerProvider.Tracer(_tracerName),
+		metrics:   o.metrics,
+		slowQuery: o.slowQuery,
+	}
+}
+
+func (t *queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return t.start(ctx, queryName(data.SQL), operation(data.SQL), data.SQL, data.Args)
+}
+
+func (t *queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	t.end(ctx, data.CommandTag, data.Err)
+}
+
+// TraceBatchStart открывает один спан на весь батч, запросы батча пишутся в него событиями
+func (t *queryTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	sql := fmt.Sprintf("BATCH %d", data.Batch.Len())
+	return t.start(ctx, "batch", "BATCH", sql, nil)
+}
+
+func (t *queryTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	attrs := []attribute.KeyValue{
+		attribute.String("db.operation", operation(data.SQL)),
+		attribute.String("db.statement", data.SQL),
+	}
+	if data.Err != nil {
+		attrs = append(attrs, attribute.String("error", data.Err.Error()))
+	}
+	trace.SpanFromContext(ctx).AddEvent("batch.query", trace.WithAttributes(attrs...))
+}
+
+func (t *queryTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	t.end(ctx, pgconn.CommandTag{}, data.Err)
+}
+
+func (t *queryTracer) TraceCopyFromStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	table := data.TableName.Sanitize()
+	sql := fmt.Sprintf("COPY %s (%s) FROM STDIN", table, strings.Join(data.ColumnNames, ", "))
+	return t.start(ctx, "copy."+strings.Join(data.TableName, "."), "COPY", sql, nil)
+}
+
+func (t *queryTracer) TraceCopyFromEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	t.end(ctx, data.CommandTag, data.Err)
+}
+
+// start открывает спан и запоминает время начала, имя из контекста важнее fallback
+func (t *queryTracer) start(ctx context.Context, fallback, op, sql string, args []any) context.Context {
+	name, _ := ctx.Value(queryNameKey{}).(string)
+	if name == "" {
+		name = fallback
+	}
+
+	ctx, _ = t.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", op),
+			attribute.String("db.statement", sql),
+		),
+	)
+
+	qt := queryTrace{name: name, operation: op, start: time.Now()}
+	if t.slowQuery.enabled() {
+		qt.sql, qt.args = sql, args
+	}
+
+	return context.WithValue(ctx, queryTraceKey{}, qt)
+}
+
+func (t *queryTracer) end(ctx context.Context, tag pgconn.CommandTag, err error) {
+	span := trace.SpanFromContext(ctx)
+	defer span.End()
+
+	qt, ok := ctx.Value(queryTraceKey{}).(queryTrace)
+	if !ok {
+		return
+	}
+
+	elapsed := time.Since(qt.start)
+	t.metrics.observeQuery(qt.name, elapsed, err)
+	t.logSlow(ctx, qt, elapsed, err)
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+	span.SetAttributes(attribute.Int64("db.rows_affected", tag.RowsAffected()))
+}
+
+func (t *queryTracer) logSlow(ctx context.Context, qt queryTrace, elapsed time.Duration, err error) {
+	if !t.slowQuery.enabled() || elapsed < t.slowQuery.Threshold {
+		return
+	}
+
+	attrs := []any{
+		slog.String("sql", qt.name),
+		slog.String("query", qt.sql),
+		slog.Duration("duration", elapsed),
+	}
+	if t.slowQuery.Args != ArgsOmit {
+		attrs = append(attrs, t.slowQuery.args(qt.args))
+	}
+	if err != nil {
+		attrs = append(attrs, logger.Err(err))
+	}
+
+	logger.Warn(ctx, "slow query", attrs...)
+}
+
+// TraceAcquireStart и TraceAcquireEnd считают запросы, ожидающие соединения из пула
+func (t *queryTracer) TraceAcquireStart(ctx context.Context, pool *pgxpool.Pool, _ pgxpool.TraceAcquireStartData) context.Context {
+	t.waitingFor(pool).Add(1)
+	return ctx
+}
+
+func (t *queryTracer) TraceAcquireEnd(_ context.Context, pool *pgxpool.Pool, _ pgxpool.TraceAcquireEndData) {
+	t.waitingFor(pool).Add(-1)
+}
+
+func (t *queryTracer) waitingFor(pool *pgxpool.Pool) *atomic.Int64 {
+	if v, ok := t.waiting.Load(pool); ok {
+		return v.(*atomic.Int64)
+	}
+	v, _ := t.waiting.LoadOrStore(pool, new(atomic.Int64))
+	return v.(*atomic.Int64)
+}
+
+// queryName достает имя из комментария sqlc, иначе называет запрос по операции
+func queryName(sql string) string {
+	sql = strings.TrimSpace(sql)
+	if rest, ok := strings.CutPrefix(sql, _sqlcPrefix); ok {
+		line, _, _ := strings.Cut(rest, "\n")
+		if fields := strings.Fields(line); len(fields) > 0 {
+			return fields[0]
+		}
+	}
+
+	return strings.ToLower(operation(sql))
+}
+
+// operation возвращает первое ключевое слово запроса, пропуская комментарии
+func operation(sql string) string {
+	for {
+		sql = strings.TrimSpace(sql)
+		if !strings.HasPrefix(sql, "--") {
+			break
+		}
+		_, rest, ok := strings.Cut(sql, "\n")
+		if !ok {
+			return ""
+		}
+		sql = rest
+	}
+
+	end := strings.IndexFunc(sql, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '(' || r == ';'
+	})
+	if end >= 0 {
+		sql = sql[:end]
+	}
+
+	return strings.ToUpper(sql)
+}
@@ -0,0 +1,30 @@
+package postgres
+
+import "testing"
+
+func TestQueryName(t *testing.T) {
+	cases := map[string]string{
+		"-- name: SelectNow :one\nSELECT NOW() as now\n": "SelectNow",
+		"  select 1": "select",
+		"-- comment\n-- another\ninsert into links(key) values ($1)": "insert",
+		"WITH due AS (SELECT 1) UPDATE links SET x = 1":              "with",
+		"begin isolation level serializable":                         "begin",
+		"savepoint sp_1;":                                            "savepoint",
+		"-- only comment":                                            "",
+	}
+
+	for sql, want := range cases {
+		if got := queryName(sql); got != want {
+			t.Errorf("queryName(%q) = %q, want %q", sql, got, want)
+		}
+	}
+}
+
+func TestOperation(t *testing.T) {
+	if got := operation("delete from links where key = $1"); got != "DELETE" {
+		t.Errorf("unexpected operation %q", got)
+	}
+	if got := operation("select(1)"); got != "SELECT" {
+		t.Errorf("unexpected operation %q", got)
+	}
+}
@@ -0,0 +1,170 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sshlykov/shortener/pkg/backoff"
+	"github.com/sshlykov/shortener/pkg/logger"
+)
+
+const (
+	txIsoLevelKey key = "tx_iso_level"
+
+	_defaultTxMaxRetries    = 3
+	_defaultTxRetryInterval = 50 * time.Millisecond
+)
+
+var isoLevelRank = map[pgx.TxIsoLevel]int{
+	pgx.ReadCommitted:  1,
+	pgx.RepeatableRead: 2,
+	pgx.Serializable:   3,
+}
+
+type txManager struct {
+	db Transactor
+
+	maxRetries    uint64
+	retryInterval time.Duration
+}
+
+type TxManagerOption func(*txManager)
+
+// WithTxMaxRetries - сколько раз повторять транзакцию после serialization failure/deadlock
+func WithTxMaxRetries(n uint64) TxManagerOption {
+	return func(m *txManager) {
+		m.maxRetries = n
+	}
+}
+
+// WithTxRetryInterval - начальная задержка перед повтором транзакции
+func WithTxRetryInterval(d time.Duration) TxManagerOption {
+	return func(m *txManager) {
+		m.retryInterval = d
+	}
+}
+
+// NewTxManager создает менеджер транзакций. Транзакция кладется в контекст под TxKey,
+// поэтому все запросы через DB внутри handler выполняются в ней.
+//
+// Вложенный вызов не открывает новую транзакцию, а создает savepoint в текущей: ошибка
+// вложенного handler откатывает только его изменения. Вложенная транзакция не может
+// требовать уровень изоляции строже внешней.
+//
+// Внешняя транзакция, завершившаяся serialization failure (40001) или deadlock (40P01),
+// повторяется целиком с экспоненциальной задержкой, поэтому handler может быть вызван
+// несколько раз и не должен иметь побочных эффектов вне базы.
+func NewTxManager(db Transactor, opts ...TxManagerOption) TxManager {
+	m := &txManager{
+		db:            db,
+		maxRetries:    _defaultTxMaxRetries,
+		retryInterval: _defaultTxRetryInterval,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+func (m *txManager) ReadCommitted(ctx context.Context, handler Handler) error {
+	return m.transaction(ctx, pgx.ReadCommitted, handler)
+}
+
+func (m *txManager) RepeatableRead(ctx context.Context, handler Handler) error {
+	return m.transaction(ctx, pgx.RepeatableRead, handler)
+}
+
+func (m *txManager) Serializable(ctx context.Context, handler Handler) error {
+	return m.transaction(ctx, pgx.Serializable, handler)
+}
+
+func (m *txManager) transaction(ctx context.Context, level pgx.TxIsoLevel, handler Handler) error {
+	if tx, ok := ctx.Value(TxKey).(pgx.Tx); ok {
+		return m.nested(ctx, tx, level, handler)
+	}
+
+	b := backoff.NewExponentialBackOff(
+		backoff.WithInitialInterval(m.retryInterval),
+		backoff.WithMaxElapsedTime(0),
+	)
+
+	return backoff.RetryNotify(
+		func() error {
+			err := m.run(ctx, level, handler)
+			if err != nil && !IsRetryable(err) {
+				return backoff.Permanent(err)
+			}
+			return err
+		},
+		backoff.WithContext(backoff.WithMaxRetries(b, m.maxRetries), ctx),
+		func(err error, next time.Duration) {
+			logger.Warn(ctx, "retrying transaction", logger.Err(err), logger.Any("next", next.String()))
+		},
+	)
+}
+
+func (m *txManager) run(ctx context.Context, level pgx.TxIsoLevel, handler Handler) (err error) {
+	options := pgx.TxOptions{IsoLevel: level}
+	if readOnlyTx(ctx) {
+		options.AccessMode = pgx.ReadOnly
+	}
+	tx, err := m.db.BeginTx(ctx, options)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = finish(ctx, tx, err, recover())
+	}()
+
+	ctx = context.WithValue(ctx, TxKey, tx)
+	ctx = context.WithValue(ctx, txIsoLevelKey, level)
+
+	return handler(ctx)
+}
+
+func (m *txManager) nested(ctx context.Context, tx pgx.Tx, level pgx.TxIsoLevel, handler Handler) (err error) {
+	// транзакции, открытые не через менеджер, считаем read committed (уровень по умолчанию в postgres)
+	outer, ok := ctx.Value(txIsoLevelKey).(pgx.TxIsoLevel)
+	if !ok {
+		outer = pgx.ReadCommitted
+	}
+	if isoLevelRank[level] > isoLevelRank[outer] {
+		return ErrTxIsolationMismatch
+	}
+
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = finish(ctx, savepoint, err, recover())
+	}()
+
+	return handler(context.WithValue(ctx, TxKey, savepoint))
+}
+
+// finish коммитит транзакцию (или отпускает savepoint) при успехе и откатывает при ошибке или панике
+func finish(ctx context.Context, tx pgx.Tx, err error, panicked any) error {
+	if panicked != nil {
+		_ = tx.Rollback(ctx)
+		panic(panicked)
+	}
+
+	if err != nil {
+		if rbErr := tx.Rollback(ctx); rbErr != nil && !errors.Is(rbErr, pgx.ErrTxClosed) {
+			err = errors.Join(err, rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// WithoutTx возвращает контекст, запросы в котором выполняются вне транзакции из родительского контекста
+func WithoutTx(ctx context.Context) context.Context {
+	return context.WithValue(ctx, TxKey, nil)
+}
@@ -0,0 +1,139 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type fakeTx struct {
+	pgx.Tx
+
+	level      pgx.TxIsoLevel
+	savepoints int
+	committed  bool
+	rolledBack bool
+	commitErr  error
+}
+
+func (t *fakeTx) Begin(context.Context) (pgx.Tx, error) {
+	t.savepoints++
+	return &fakeTx{level: t.level}, nil
+}
+
+func (t *fakeTx) Commit(context.Context) error {
+	t.committed = true
+	return t.commitErr
+}
+
+func (t *fakeTx) Rollback(context.Context) error {
+	t.rolledBack = true
+	return nil
+}
+
+type fakeTransactor struct {
+	txs []*fakeTx
+}
+
+func (f *fakeTransactor) BeginTx(_ context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	tx := &fakeTx{level: opts.IsoLevel}
+	f.txs = append(f.txs, tx)
+	return tx, nil
+}
+
+func newTestManager(db Transactor) TxManager {
+	return NewTxManager(db, WithTxMaxRetries(2), WithTxRetryInterval(time.Millisecond))
+}
+
+func TestTxManagerCommitAndRollback(t *testing.T) {
+	db := &fakeTransactor{}
+	m := newTestManager(db)
+
+	err := m.Serializable(context.Background(), func(ctx context.Context) error {
+		if _, ok := ctx.Value(TxKey).(pgx.Tx); !ok {
+			t.Error("tx should be in context")
+		}
+		return nil
+	})
+	if err != nil || !db.txs[0].committed || db.txs[0].level != pgx.Serializable {
+		t.Fatalf("expected committed serializable tx, got %v %+v", err, db.txs[0])
+	}
+
+	boom := errors.New("boom")
+	if err = m.ReadCommitted(context.Background(), func(context.Context) error { return boom }); !errors.Is(err, boom) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(db.txs) != 2 || !db.txs[1].rolledBack || db.txs[1].committed {
+		t.Fatalf("non retryable error should roll back once, got %d txs", len(db.txs))
+	}
+}
+
+func TestTxManagerRetry(t *testing.T) {
+	db := &fakeTransactor{}
+	m := newTestManager(db)
+
+	calls := 0
+	err := m.RepeatableRead(context.Background(), func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return &pgconn.PgError{Code: CodeSerializationFailure}
+		}
+		return nil
+	})
+	if err != nil || calls != 3 || !db.txs[2].committed {
+		t.Fatalf("expected success on third attempt, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = m.ReadCommitted(context.Background(), func(context.Context) error {
+		calls++
+		return &pgconn.PgError{Code: CodeDeadlockDetected}
+	})
+	if !IsRetryable(err) || calls != 3 {
+		t.Fatalf("expected deadlock after 3 attempts, got %v after %d calls", err, calls)
+	}
+}
+
+func TestTxManagerNested(t *testing.T) {
+	db := &fakeTransactor{}
+	m := newTestManager(db)
+	boom := errors.New("boom")
+
+	err := m.RepeatableRead(context.Background(), func(ctx context.Context) error {
+		outer := ctx.Value(TxKey).(pgx.Tx)
+
+		if err := m.ReadCommitted(ctx, func(context.Context) error { return boom }); !errors.Is(err, boom) {
+			t.Errorf("nested error should be returned, got %v", err)
+		}
+		if err := m.Serializable(ctx, func(context.Context) error { return nil }); !errors.Is(err, ErrTxIsolationMismatch) {
+			t.Errorf("expected isolation mismatch, got %v", err)
+		}
+		if outer.(*fakeTx).savepoints != 1 {
+			t.Errorf("expected one savepoint, got %d", outer.(*fakeTx).savepoints)
+		}
+		return nil
+	})
+	if err != nil || len(db.txs) != 1 || !db.txs[0].committed {
+		t.Fatalf("outer tx should be committed, got %v", err)
+	}
+}
+
+func TestTxManagerPanic(t *testing.T) {
+	db := &fakeTransactor{}
+	m := newTestManager(db)
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("panic should be propagated")
+		}
+		if !db.txs[0].rolledBack {
+			t.Fatal("tx should be rolled back on panic")
+		}
+	}()
+
+	_ = m.ReadCommitted(context.Background(), func(context.Context) error { panic("boom") })
+}
@@ -5,7 +5,7 @@ endif
 
 .PHONY: run
 run:
-	go run cmd/shortener/main.go
+	go run ./cmd/shortener
 
 .PHONY: lint
 lint:
@@ -16,6 +16,30 @@ migrate:
 	docker-compose up -d --force-recreate --build --remove-orphans && \
 	go run cmd/migrator/main.go up
 
+.PHONY: migrate-status
+migrate-status:
+	go run cmd/migrator/main.go status
+
+.PHONY: migrate-plan
+migrate-plan:
+	go run cmd/migrator/main.go plan
+
+.PHONY: seed
+seed:
+	go run ./cmd/shortener seed migrations/entity.csv
+
+.PHONY: backup
+backup:
+	go run ./cmd/shortener backup -o backup.tar.gz
+
+.PHONY: restore-check
+restore-check:
+	go run ./cmd/shortener restore backup.tar.gz
+
+.PHONY: snapshot
+snapshot:
+	go run ./cmd/shortener snapshot
+
 .PHONY: .sqlc
 .sqlc:
 	sqlc generate -f ./sqlc/sqlc.json
\ No newline at end of file